@@ -0,0 +1,125 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bgzf implements reading and writing of BGZF (blocked gzip) files,
+// as used by BAM, VCF and other bioinformatics formats.
+//
+// A BGZF file is a series of gzip members, each holding at most 64KB of compressed data,
+// with the compressed size stored in a "BC" extra subfield.
+// The file ends with an empty member acting as EOF marker.
+// Since BGZF files are valid gzip files, they can be read by any gzip reader.
+//
+// Positions in BGZF files are addressed by virtual offsets,
+// which combine the offset of a block in the file with an offset inside the uncompressed block.
+//
+// The format is specified in the SAM/BAM format specification, section 4.1:
+// https://samtools.github.io/hts-specs/SAMv1.pdf
+package bgzf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+)
+
+// These constants are copied from the flate package.
+const (
+	NoCompression      = flate.NoCompression
+	BestSpeed          = flate.BestSpeed
+	BestCompression    = flate.BestCompression
+	DefaultCompression = flate.DefaultCompression
+	HuffmanOnly        = flate.HuffmanOnly
+)
+
+const (
+	// MaxBlockSize is the maximum size of a BGZF block, including header and trailer.
+	MaxBlockSize = 64 << 10
+
+	// BlockDataSize is the maximum uncompressed size of a block written.
+	// It is chosen so incompressible data will fit in a block.
+	BlockDataSize = 0xff00
+
+	// headerSize is the size of the block header written, including the BC subfield.
+	headerSize = 18
+	// trailerSize is the size of the CRC and size following the compressed data.
+	trailerSize = 8
+	// fixedHeaderSize is the size of the gzip header up to and including XLEN.
+	fixedHeaderSize = 12
+)
+
+// EOFMarker is the empty block that marks the end of a BGZF file.
+var EOFMarker = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00,
+	0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+var (
+	// ErrHeader is returned when reading a block that is not a valid BGZF block.
+	ErrHeader = gzip.ErrHeader
+	// ErrChecksum is returned when a block has an invalid checksum or size.
+	ErrChecksum = gzip.ErrChecksum
+	// ErrNoEOFMarker is returned by CheckEOF if the file does not end with an EOF marker.
+	ErrNoEOFMarker = errors.New("bgzf: missing EOF marker")
+)
+
+// VirtualOffset is a BGZF virtual file offset.
+// The upper 48 bits contain the compressed offset of a block in the file,
+// and the lower 16 bits the offset in the uncompressed block.
+type VirtualOffset uint64
+
+// MakeVirtualOffset returns the virtual offset of the uncompressed offset
+// in the block starting at the compressed offset.
+func MakeVirtualOffset(compressed int64, uncompressed int) VirtualOffset {
+	return VirtualOffset(uint64(compressed)<<16 | uint64(uncompressed&0xffff))
+}
+
+// Compressed returns the offset of the block in the compressed file.
+func (v VirtualOffset) Compressed() int64 {
+	return int64(v >> 16)
+}
+
+// Uncompressed returns the offset in the uncompressed block.
+func (v VirtualOffset) Uncompressed() int {
+	return int(v & 0xffff)
+}
+
+// String returns the offsets as "compressed:uncompressed".
+func (v VirtualOffset) String() string {
+	return fmt.Sprintf("%d:%d", v.Compressed(), v.Uncompressed())
+}
+
+// appendHeader appends a block header for a block with the given total size.
+func appendHeader(dst []byte, blockSize int) []byte {
+	dst = append(dst, EOFMarker[:16]...)
+	return binary.LittleEndian.AppendUint16(dst, uint16(blockSize-1))
+}
+
+// parseBlockSize parses the gzip header up to and including XLEN in hdr,
+// followed by the extra field in extra, and returns the total block size.
+func parseBlockSize(hdr, extra []byte) (int, error) {
+	if hdr[0] != 0x1f || hdr[1] != 0x8b || hdr[2] != 8 || hdr[3] != 4 {
+		return 0, ErrHeader
+	}
+	minSize := fixedHeaderSize + len(extra) + trailerSize
+	for len(extra) >= 4 {
+		sLen := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+sLen {
+			break
+		}
+		if extra[0] == 'B' && extra[1] == 'C' && sLen == 2 {
+			size := int(binary.LittleEndian.Uint16(extra[4:])) + 1
+			if size < minSize {
+				return 0, ErrHeader
+			}
+			return size, nil
+		}
+		extra = extra[4+sLen:]
+	}
+	return 0, ErrHeader
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bgzf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+func testInput(t testing.TB, size int) []byte {
+	dat, err := os.ReadFile("../gzip/testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(int64(size)))
+	in := make([]byte, 0, size)
+	for len(in) < size {
+		in = append(in, dat[:min(len(dat), size-len(in))]...)
+		rnd := make([]byte, min(rng.Intn(100000), size-len(in)))
+		rng.Read(rnd)
+		in = append(in, rnd...)
+	}
+	return in
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if w.queue != nil {
+		t.Fatal("output goroutine started before a block was compressed")
+	}
+	w.Reset(&buf)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), EOFMarker) {
+		t.Fatalf("got %x, want EOF marker", buf.Bytes())
+	}
+	if err := CheckEOF(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+	// The EOF marker must be a valid gzip stream.
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(gr); err != nil || len(b) != 0 {
+		t.Fatalf("got %d bytes, %v", len(b), err)
+	}
+}
+
+func TestRoundtrip(t *testing.T) {
+	for _, size := range []int{1, BlockDataSize, BlockDataSize + 1, 1 << 20} {
+		in := testInput(t, size)
+		for _, level := range []int{NoCompression, BestSpeed, DefaultCompression, HuffmanOnly} {
+			for _, n := range []int{1, 3} {
+				t.Run(fmt.Sprintf("size-%d-level-%d-n-%d", size, level, n), func(t *testing.T) {
+					var buf bytes.Buffer
+					w, err := NewWriterLevel(&buf, level, n)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if _, err := w.Write(in); err != nil {
+						t.Fatal(err)
+					}
+					if err := w.Close(); err != nil {
+						t.Fatal(err)
+					}
+					compressed := buf.Bytes()
+					if err := CheckEOF(bytes.NewReader(compressed), int64(len(compressed))); err != nil {
+						t.Fatal(err)
+					}
+
+					// Must be readable as gzip.
+					gr, err := gzip.NewReader(bytes.NewReader(compressed))
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := io.ReadAll(gr)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, in) {
+						t.Fatal("gzip: content mismatch")
+					}
+
+					r, err := NewReader(bytes.NewReader(compressed))
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err = io.ReadAll(r)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, in) {
+						t.Fatal("bgzf: content mismatch")
+					}
+
+					// Index from the writer must match the index built from the file.
+					idx, err := BuildIndex(bytes.NewReader(compressed))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if wIdx := w.Index(); !reflect.DeepEqual(idx.Entries, wIdx.Entries) {
+						t.Fatalf("index mismatch:\n%v\n%v", idx.Entries, wIdx.Entries)
+					}
+					if want := (size - 1) / BlockDataSize; len(idx.Entries) != want {
+						t.Fatalf("got %d index entries, want %d", len(idx.Entries), want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestWriterFlushReset(t *testing.T) {
+	in := testInput(t, 200000)
+	var buf, buf2 bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(in[:1000])
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	flushed := buf.Len()
+	if flushed == 0 {
+		t.Fatal("no output after flush")
+	}
+	w.Write(in[1000:])
+	w.Reset(&buf2)
+	w.Write(in)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(in); err == nil {
+		t.Fatal("expected error writing after close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()[:flushed]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, in[:1000]) {
+		t.Fatalf("flushed content mismatch: %v", err)
+	}
+	r.Reset(&buf2)
+	got, err = io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, in) {
+		t.Fatalf("content mismatch after Reset: %v", err)
+	}
+}
+
+func TestReaderSeek(t *testing.T) {
+	in := testInput(t, 1<<20)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	// Write in pieces, with flushes, so blocks have different sizes.
+	rng := rand.New(rand.NewSource(0))
+	for rem := in; len(rem) > 0; {
+		n := min(len(rem), rng.Intn(200000))
+		w.Write(rem[:n])
+		rem = rem[n:]
+		if rng.Intn(2) == 0 {
+			w.Flush()
+		}
+	}
+	w.Close()
+	compressed := buf.Bytes()
+
+	// Record virtual offsets while reading.
+	r, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type mark struct {
+		off VirtualOffset
+		pos int
+	}
+	var marks []mark
+	pos := 0
+	for {
+		marks = append(marks, mark{off: r.Offset(), pos: pos})
+		n, err := r.Read(make([]byte, rng.Intn(100000)+1))
+		pos += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pos != len(in) {
+		t.Fatalf("read %d bytes, want %d", pos, len(in))
+	}
+	for _, i := range rng.Perm(len(marks)) {
+		m := marks[i]
+		if err := r.SeekVirtual(m.off); err != nil {
+			t.Fatalf("seek to %v: %v", m.off, err)
+		}
+		got := make([]byte, 1000)
+		n, _ := io.ReadFull(r, got)
+		if !bytes.Equal(got[:n], in[m.pos:min(len(in), m.pos+1000)]) {
+			t.Fatalf("content mismatch after seek to %v (pos %d)", m.off, m.pos)
+		}
+	}
+
+	// Seek using a .gzi index.
+	idx, err := BuildIndex(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzi bytes.Buffer
+	if _, err := idx.WriteTo(&gzi); err != nil {
+		t.Fatal(err)
+	}
+	if gzi.Len() != 8+16*len(idx.Entries) {
+		t.Fatalf("gzi size %d", gzi.Len())
+	}
+	idx, err = ReadIndex(&gzi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		off := rng.Intn(len(in) + 1)
+		if err := r.SeekUncompressed(int64(off), idx); err != nil {
+			t.Fatalf("seek to %d: %v", off, err)
+		}
+		got := make([]byte, 1000)
+		n, _ := io.ReadFull(r, got)
+		if !bytes.Equal(got[:n], in[off:min(len(in), off+1000)]) {
+			t.Fatalf("content mismatch after seek to %d", off)
+		}
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	in := testInput(t, 100000)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(in)
+	w.Close()
+	compressed := buf.Bytes()
+
+	// Checksum of the first block.
+	c := bytes.Clone(compressed)
+	idx, _ := BuildIndex(bytes.NewReader(c))
+	c[idx.Entries[0].Compressed-8]++
+	r, err := NewReader(bytes.NewReader(c))
+	if err != ErrChecksum {
+		t.Fatalf("got %v, want %v", err, ErrChecksum)
+	}
+
+	// Missing BC subfield.
+	c = bytes.Clone(compressed)
+	c[12] = 'X'
+	if _, err = NewReader(bytes.NewReader(c)); err != ErrHeader {
+		t.Fatalf("got %v, want %v", err, ErrHeader)
+	}
+
+	// Truncated.
+	c = compressed[:len(compressed)-len(EOFMarker)-1]
+	if err := CheckEOF(bytes.NewReader(c), int64(len(c))); err != ErrNoEOFMarker {
+		t.Fatalf("got %v, want %v", err, ErrNoEOFMarker)
+	}
+	r, err = NewReader(bytes.NewReader(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	in := testInput(b, 8<<20)
+	w := NewWriter(io.Discard)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	for b.Loop() {
+		w.Reset(io.Discard)
+		w.Write(in)
+		w.Close()
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	in := testInput(b, 8<<20)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(in)
+	w.Close()
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	for b.Loop() {
+		r.Reset(bytes.NewReader(buf.Bytes()))
+		io.Copy(io.Discard, r)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bgzf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+var errIndexCorrupt = errors.New("bgzf: corrupt index")
+
+// IndexEntry is the start of a block in the compressed and uncompressed stream.
+type IndexEntry struct {
+	Compressed   int64
+	Uncompressed int64
+}
+
+// Index contains the offsets of blocks in a BGZF file.
+// The index can be stored in the .gzi format used by bgzip and samtools,
+// which allows seeking to uncompressed offsets.
+//
+// The first block, starting at offset 0 in both streams, is not included.
+type Index struct {
+	Entries []IndexEntry
+}
+
+// ReadIndex reads an index in the .gzi format.
+// The format is the number of entries, followed by the compressed
+// and uncompressed offset of each entry, all stored as 64 bit little endian values.
+func ReadIndex(r io.Reader) (*Index, error) {
+	var tmp [16]byte
+	if _, err := io.ReadFull(r, tmp[:8]); err != nil {
+		return nil, noEOF(err)
+	}
+	n := binary.LittleEndian.Uint64(tmp[:])
+	if n > 1<<40 {
+		return nil, errIndexCorrupt
+	}
+	// Don't trust the count for the allocation.
+	idx := &Index{Entries: make([]IndexEntry, 0, min(n, 1<<16))}
+	var prev IndexEntry
+	for range n {
+		if _, err := io.ReadFull(r, tmp[:]); err != nil {
+			return nil, noEOF(err)
+		}
+		e := IndexEntry{
+			Compressed:   int64(binary.LittleEndian.Uint64(tmp[:])),
+			Uncompressed: int64(binary.LittleEndian.Uint64(tmp[8:])),
+		}
+		if e.Compressed <= prev.Compressed || e.Uncompressed < prev.Uncompressed {
+			return nil, errIndexCorrupt
+		}
+		idx.Entries = append(idx.Entries, e)
+		prev = e
+	}
+	return idx, nil
+}
+
+// WriteTo writes the index in the .gzi format.
+func (i *Index) WriteTo(w io.Writer) (int64, error) {
+	b := make([]byte, 0, 8+16*len(i.Entries))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(i.Entries)))
+	for _, e := range i.Entries {
+		b = binary.LittleEndian.AppendUint64(b, uint64(e.Compressed))
+		b = binary.LittleEndian.AppendUint64(b, uint64(e.Uncompressed))
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// VirtualOffset returns the virtual offset of the uncompressed offset.
+// Offsets after the last block in the index are only found
+// if they are within 64KB of the start of the last block.
+func (i *Index) VirtualOffset(offset int64) (VirtualOffset, error) {
+	if offset < 0 {
+		return 0, errors.New("bgzf: negative offset")
+	}
+	n := sort.Search(len(i.Entries), func(n int) bool {
+		return i.Entries[n].Uncompressed > offset
+	})
+	var e IndexEntry
+	if n > 0 {
+		e = i.Entries[n-1]
+	}
+	if offset-e.Uncompressed > 0xffff {
+		return 0, errors.New("bgzf: offset not covered by index")
+	}
+	return MakeVirtualOffset(e.Compressed, int(offset-e.Uncompressed)), nil
+}
+
+// BuildIndex reads a BGZF file and returns the index of its blocks.
+// Blocks are not decompressed, so the content and checksums are not validated.
+func BuildIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	var idx Index
+	var hdr [fixedHeaderSize]byte
+	var extra []byte
+	var compressed, uncompressed int64
+	for {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if err == io.EOF {
+				return &idx, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				err = ErrHeader
+			}
+			return nil, err
+		}
+		xLen := int(binary.LittleEndian.Uint16(hdr[10:]))
+		if cap(extra) < xLen {
+			extra = make([]byte, xLen)
+		}
+		extra = extra[:xLen]
+		if _, err := io.ReadFull(br, extra); err != nil {
+			return nil, noEOF(err)
+		}
+		size, err := parseBlockSize(hdr[:], extra)
+		if err != nil {
+			return nil, err
+		}
+		skip := size - fixedHeaderSize - xLen - trailerSize
+		if _, err := br.Discard(skip); err != nil {
+			return nil, noEOF(err)
+		}
+		var trailer [trailerSize]byte
+		if _, err := io.ReadFull(br, trailer[:]); err != nil {
+			return nil, noEOF(err)
+		}
+		n := int64(binary.LittleEndian.Uint32(trailer[4:]))
+		if n > MaxBlockSize {
+			return nil, ErrHeader
+		}
+		if compressed > 0 && n > 0 {
+			idx.Entries = append(idx.Entries, IndexEntry{Compressed: compressed, Uncompressed: uncompressed})
+		}
+		compressed += int64(size)
+		uncompressed += n
+	}
+}
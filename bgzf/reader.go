@@ -0,0 +1,246 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bgzf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/klauspost/compress/flate"
+)
+
+var errNotSeeker = errors.New("bgzf: underlying reader is not an io.Seeker")
+
+// A Reader is an io.Reader that can be read to retrieve
+// uncompressed data from a BGZF file.
+//
+// Compressed offsets are relative to the position of the input
+// when the Reader was created or Reset.
+// When seeking, the input is expected to start at the beginning of the file.
+type Reader struct {
+	r   io.Reader
+	br  *bufio.Reader
+	fr  io.ReadCloser
+	src bytes.Reader
+	err error
+
+	block      []byte // Uncompressed content of the current block.
+	pos        int    // Read position in block.
+	blockStart int64  // Compressed offset of the current block.
+	next       int64  // Compressed offset of the next block.
+
+	cbuf []byte
+	hdr  [fixedHeaderSize]byte
+}
+
+// NewReader creates a new Reader reading the given reader.
+// The first block is read and validated.
+// If the input is empty, io.EOF is returned.
+func NewReader(r io.Reader) (*Reader, error) {
+	z := new(Reader)
+	if err := z.Reset(r); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// Reset discards the Reader z's state and makes it equivalent to the
+// result of its original state from NewReader, but reading from r instead.
+func (z *Reader) Reset(r io.Reader) error {
+	z.r = r
+	if z.br == nil {
+		z.br = bufio.NewReader(r)
+	} else {
+		z.br.Reset(r)
+	}
+	z.err = nil
+	z.block = z.block[:0]
+	z.pos, z.blockStart, z.next = 0, 0, 0
+	z.err = z.readBlock()
+	return z.err
+}
+
+// readBlock reads and decompresses the next block.
+// If no more blocks are available io.EOF is returned.
+func (z *Reader) readBlock() error {
+	if _, err := io.ReadFull(z.br, z.hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrHeader
+		}
+		return err
+	}
+	xLen := int(binary.LittleEndian.Uint16(z.hdr[10:]))
+	if cap(z.cbuf) < MaxBlockSize {
+		z.cbuf = make([]byte, MaxBlockSize)
+	}
+	extra := z.cbuf[:xLen]
+	if _, err := io.ReadFull(z.br, extra); err != nil {
+		return noEOF(err)
+	}
+	size, err := parseBlockSize(z.hdr[:], extra)
+	if err != nil {
+		return err
+	}
+	rest := z.cbuf[xLen : size-fixedHeaderSize]
+	if _, err := io.ReadFull(z.br, rest); err != nil {
+		return noEOF(err)
+	}
+	cdata, trailer := rest[:len(rest)-trailerSize], rest[len(rest)-trailerSize:]
+	crc := binary.LittleEndian.Uint32(trailer)
+	n := binary.LittleEndian.Uint32(trailer[4:])
+	if n > MaxBlockSize {
+		return ErrHeader
+	}
+	if cap(z.block) < MaxBlockSize {
+		z.block = make([]byte, 0, MaxBlockSize)
+	}
+
+	z.src.Reset(cdata)
+	if z.fr == nil {
+		z.fr = flate.NewReader(&z.src)
+	} else if err := z.fr.(flate.Resetter).Reset(&z.src, nil); err != nil {
+		return err
+	}
+	z.block = z.block[:n]
+	if _, err := io.ReadFull(z.fr, z.block); err != nil {
+		if err == io.EOF {
+			err = ErrChecksum
+		}
+		return err
+	}
+	// The deflate stream must end here.
+	var tmp [1]byte
+	if m, err := z.fr.Read(tmp[:]); m != 0 || err != io.EOF {
+		if err == nil || err == io.EOF {
+			err = ErrChecksum
+		}
+		return err
+	}
+	if crc32.ChecksumIEEE(z.block) != crc {
+		return ErrChecksum
+	}
+	z.pos = 0
+	z.blockStart = z.next
+	z.next += int64(size)
+	return nil
+}
+
+// Read implements io.Reader, reading uncompressed bytes from its underlying Reader.
+func (z *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, z.err
+	}
+	for z.pos == len(z.block) {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.readBlock()
+	}
+	n := copy(p, z.block[z.pos:])
+	z.pos += n
+	return n, nil
+}
+
+// WriteTo writes the uncompressed data to w.
+func (z *Reader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for {
+		if z.pos < len(z.block) {
+			n, err := w.Write(z.block[z.pos:])
+			total += int64(n)
+			if err == nil && n != len(z.block)-z.pos {
+				err = io.ErrShortWrite
+			}
+			z.pos += n
+			if err != nil {
+				return total, err
+			}
+		}
+		if z.err != nil {
+			if z.err == io.EOF {
+				return total, nil
+			}
+			return total, z.err
+		}
+		z.err = z.readBlock()
+	}
+}
+
+// Offset returns the virtual offset of the next byte to be read.
+// At the end of a block, the offset may point to the end of the block
+// instead of the start of the next block.
+func (z *Reader) Offset() VirtualOffset {
+	return MakeVirtualOffset(z.blockStart, z.pos)
+}
+
+// SeekVirtual moves the reader to the virtual offset.
+// The underlying reader must implement io.Seeker.
+func (z *Reader) SeekVirtual(off VirtualOffset) error {
+	rs, ok := z.r.(io.Seeker)
+	if !ok {
+		return errNotSeeker
+	}
+	if _, err := rs.Seek(off.Compressed(), io.SeekStart); err != nil {
+		return err
+	}
+	z.br.Reset(z.r)
+	z.block = z.block[:0]
+	z.pos = 0
+	z.blockStart, z.next = off.Compressed(), off.Compressed()
+	z.err = z.readBlock()
+	if z.err == io.EOF && off.Uncompressed() == 0 {
+		// Seeking to the end of the file is allowed.
+		return nil
+	}
+	if z.err != nil {
+		return z.err
+	}
+	if off.Uncompressed() > len(z.block) {
+		z.err = errors.New("bgzf: offset outside block")
+		return z.err
+	}
+	z.pos = off.Uncompressed()
+	return nil
+}
+
+// SeekUncompressed moves the reader to the uncompressed offset,
+// using the index to locate the block.
+// The underlying reader must implement io.Seeker.
+func (z *Reader) SeekUncompressed(offset int64, idx *Index) error {
+	off, err := idx.VirtualOffset(offset)
+	if err != nil {
+		return err
+	}
+	return z.SeekVirtual(off)
+}
+
+// CheckEOF checks if the file of the given size ends with an EOF marker.
+// Files without the EOF marker may have been truncated.
+// ErrNoEOFMarker is returned if the marker is not found.
+func CheckEOF(r io.ReaderAt, size int64) error {
+	if size < int64(len(EOFMarker)) {
+		return ErrNoEOFMarker
+	}
+	buf := make([]byte, len(EOFMarker))
+	if n, err := r.ReadAt(buf, size-int64(len(buf))); n != len(buf) {
+		return noEOF(err)
+	}
+	if !bytes.Equal(buf, EOFMarker) {
+		return ErrNoEOFMarker
+	}
+	return nil
+}
+
+// noEOF converts io.EOF to io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bgzf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+)
+
+var errClosed = errors.New("bgzf: writer closed")
+
+// A Writer is an io.WriteCloser.
+// Writes to a Writer are compressed in blocks of BlockDataSize bytes
+// and written to w.
+// Blocks are compressed concurrently.
+type Writer struct {
+	w     io.Writer
+	level int
+	n     int
+
+	buf    []byte
+	closed bool
+
+	// queue contains blocks in output order.
+	queue    chan chan block
+	writerWg sync.WaitGroup
+
+	// mu protects the fields below, which are updated by the output goroutine.
+	mu           sync.Mutex
+	err          error
+	compressed   int64
+	uncompressed int64
+	index        Index
+
+	bufs sync.Pool
+	outs sync.Pool
+	fws  sync.Pool
+}
+
+type block struct {
+	out *bytes.Buffer
+	in  []byte // Input buffer to return to the pool.
+}
+
+// NewWriter returns a new Writer using the default compression level,
+// compressing up to GOMAXPROCS blocks concurrently.
+//
+// It is the caller's responsibility to call Close on the Writer when done,
+// which will write the EOF marker and stop the background goroutine
+// started when the first block is compressed.
+func NewWriter(w io.Writer) *Writer {
+	z, _ := NewWriterLevel(w, DefaultCompression, 0)
+	return z
+}
+
+// NewWriterLevel is like NewWriter but specifies the compression level
+// and the number of blocks compressed concurrently.
+// If concurrency is <= 0, GOMAXPROCS is used.
+// The compression level can be DefaultCompression, NoCompression, HuffmanOnly
+// or any integer value between BestSpeed and BestCompression inclusive.
+// The error returned will be nil if the level is valid.
+func NewWriterLevel(w io.Writer, level, concurrency int) (*Writer, error) {
+	if level < HuffmanOnly || level > BestCompression {
+		return nil, fmt.Errorf("bgzf: invalid compression level: %d", level)
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	z := &Writer{level: level, n: concurrency}
+	z.Reset(w)
+	return z, nil
+}
+
+// Reset discards the Writer z's state and makes it equivalent to the
+// result of its original state from NewWriter or NewWriterLevel, but
+// writing to w instead.
+// Buffered data that has not been flushed is discarded.
+func (z *Writer) Reset(w io.Writer) {
+	z.stop()
+	z.w = w
+	z.buf = nil
+	z.closed = false
+	z.err = nil
+	z.compressed, z.uncompressed = 0, 0
+	z.index = Index{}
+}
+
+// start the output goroutine, if not running.
+// The goroutine is started when the first block is queued,
+// so a Writer that is never written to doesn't have to be closed.
+func (z *Writer) start() {
+	if z.queue != nil {
+		return
+	}
+	queue := make(chan chan block, z.n)
+	z.queue = queue
+	z.writerWg.Add(1)
+	go func() {
+		defer z.writerWg.Done()
+		for ch := range queue {
+			b := <-ch
+			if b.out != nil {
+				z.writeBlock(b)
+			}
+			// Signal that the block has been written.
+			close(ch)
+		}
+	}()
+}
+
+// stop the output goroutine, if running.
+// Queued blocks are not written.
+func (z *Writer) stop() {
+	if z.queue == nil {
+		return
+	}
+	z.setErr(errClosed)
+	close(z.queue)
+	z.writerWg.Wait()
+	z.queue = nil
+}
+
+// setErr returns the first error set.
+func (z *Writer) setErr(err error) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.err == nil {
+		z.err = err
+	}
+	return z.err
+}
+
+// writeBlock writes a compressed block to the output.
+func (z *Writer) writeBlock(b block) {
+	defer func() {
+		z.bufs.Put(b.in)
+		z.outs.Put(b.out)
+	}()
+	if z.setErr(nil) != nil {
+		return
+	}
+	n, err := z.w.Write(b.out.Bytes())
+	if err == nil && n != b.out.Len() {
+		err = io.ErrShortWrite
+	}
+	if z.setErr(err) != nil {
+		return
+	}
+	z.mu.Lock()
+	if z.compressed > 0 && len(b.in) > 0 {
+		z.index.Entries = append(z.index.Entries, IndexEntry{Compressed: z.compressed, Uncompressed: z.uncompressed})
+	}
+	z.compressed += int64(n)
+	z.uncompressed += int64(len(b.in))
+	z.mu.Unlock()
+}
+
+// Write writes a compressed form of p to the underlying io.Writer.
+// Full blocks are compressed in the background.
+// Errors may be returned on a later call to Write, Flush or Close.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errClosed
+	}
+	if err := z.setErr(nil); err != nil {
+		return 0, err
+	}
+	written := 0
+	for len(p) > 0 {
+		if z.buf == nil {
+			z.buf = z.getBuf()
+		}
+		n := min(len(p), BlockDataSize-len(z.buf))
+		z.buf = append(z.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(z.buf) == BlockDataSize {
+			z.compress()
+		}
+	}
+	return written, z.setErr(nil)
+}
+
+func (z *Writer) getBuf() []byte {
+	if b, ok := z.bufs.Get().([]byte); ok {
+		return b[:0]
+	}
+	return make([]byte, 0, BlockDataSize)
+}
+
+// compress the buffered input as a block and queue it for output.
+func (z *Writer) compress() {
+	in := z.buf
+	z.buf = nil
+	ch := make(chan block)
+	z.start()
+	z.queue <- ch
+	go func() {
+		out, ok := z.outs.Get().(*bytes.Buffer)
+		if !ok {
+			out = bytes.NewBuffer(make([]byte, 0, MaxBlockSize))
+		}
+		out.Reset()
+		out.Write(make([]byte, headerSize))
+		fw, ok := z.fws.Get().(*flate.Writer)
+		if ok {
+			fw.Reset(out)
+		} else {
+			fw, _ = flate.NewWriter(out, z.level)
+		}
+		fw.Write(in)
+		fw.Close()
+		z.fws.Put(fw)
+		if out.Len()+trailerSize > MaxBlockSize {
+			// Store the block.
+			out.Truncate(headerSize)
+			var hdr [5]byte
+			hdr[0] = 1 // Final, stored.
+			binary.LittleEndian.PutUint16(hdr[1:], uint16(len(in)))
+			binary.LittleEndian.PutUint16(hdr[3:], ^uint16(len(in)))
+			out.Write(hdr[:])
+			out.Write(in)
+		}
+		var trailer [trailerSize]byte
+		binary.LittleEndian.PutUint32(trailer[:], crc32.ChecksumIEEE(in))
+		binary.LittleEndian.PutUint32(trailer[4:], uint32(len(in)))
+		out.Write(trailer[:])
+		appendHeader(out.Bytes()[:0], out.Len())
+		ch <- block{out: out, in: in}
+	}()
+}
+
+// wait until all queued blocks have been written.
+func (z *Writer) wait() error {
+	if z.queue == nil {
+		return z.setErr(nil)
+	}
+	ch := make(chan block)
+	z.queue <- ch
+	ch <- block{}
+	<-ch
+	return z.setErr(nil)
+}
+
+// Flush compresses any buffered data as a block
+// and waits until all blocks have been written to the underlying writer.
+// Flush ends the current block, so frequent flushes will reduce compression.
+func (z *Writer) Flush() error {
+	if z.closed {
+		return errClosed
+	}
+	if err := z.setErr(nil); err != nil {
+		return err
+	}
+	if len(z.buf) > 0 {
+		z.compress()
+	}
+	return z.wait()
+}
+
+// Close flushes buffered data and writes the EOF marker.
+// It does not close the underlying io.Writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.setErr(nil)
+	}
+	err := z.Flush()
+	z.closed = true
+	if err == nil {
+		var n int
+		n, err = z.w.Write(EOFMarker)
+		if err == nil && n != len(EOFMarker) {
+			err = io.ErrShortWrite
+		}
+		z.compressed += int64(n)
+		err = z.setErr(err)
+	}
+	z.stop()
+	// Don't return the error set by stop.
+	if err == nil {
+		z.err = nil
+	}
+	return err
+}
+
+// Index returns the .gzi index of the blocks written.
+// All blocks are included after Flush or Close has returned.
+func (z *Writer) Index() *Index {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return &Index{Entries: append([]IndexEntry(nil), z.index.Entries...)}
+}
@@ -20,6 +20,13 @@ const (
 	BestCompression    = 9
 	DefaultCompression = -1
 
+	// BestCompressionZopfli gives the best compression.
+	// Levels 10 to 12 use iterative optimal parsing and block splitting,
+	// similar to Zopfli, and are more than 100 times slower than BestCompression.
+	// They are intended for data that is compressed once and decompressed many times,
+	// like static web assets.
+	BestCompressionZopfli = 12
+
 	// HuffmanOnly disables Lempel-Ziv match searching and only performs Huffman
 	// entropy encoding. This mode is useful in compressing data that has
 	// already been compressed with an LZ style algorithm (e.g. Snappy or LZ4)
@@ -123,6 +130,7 @@ type compressor struct {
 	tokens tokens
 	fast   fastEnc
 	state  *advancedState
+	zopfli *zopfliState
 
 	sync          bool // requesting flush
 	byteAvailable bool // if true, still need to process window[index-1].
@@ -177,7 +185,7 @@ func (d *compressor) writeBlock(tok *tokens, index int, eof bool) error {
 		}
 		d.blockStart = index
 		//d.w.writeBlock(tok, eof, window)
-		d.w.writeBlockDynamic(tok, eof, window, d.sync)
+		d.w.writeBlockSplit(tok, eof, window, d.sync)
 		return d.w.err
 	}
 	return nil
@@ -207,6 +215,23 @@ func (d *compressor) writeBlockSkip(tok *tokens, index int, eof bool) error {
 	return nil
 }
 
+// history returns up to maxMatchOffset bytes of data that has been
+// compressed and can be referenced by subsequent data.
+// All pending data must have been written.
+func (d *compressor) history() []byte {
+	var b []byte
+	switch {
+	case d.fast != nil:
+		b = d.fast.history()
+	case d.zopfli != nil, d.state != nil:
+		b = d.window[:d.windowEnd]
+	}
+	if len(b) > maxMatchOffset {
+		b = b[len(b)-maxMatchOffset:]
+	}
+	return b
+}
+
 // fillWindow will fill the current window with the supplied
 // dictionary and calculate all hashes.
 // This is much faster than doing a full encode.
@@ -225,6 +250,14 @@ func (d *compressor) fillWindow(b []byte) {
 		d.tokens.Reset()
 		return
 	}
+	if d.zopfli != nil {
+		if len(b) > maxMatchOffset {
+			b = b[len(b)-maxMatchOffset:]
+		}
+		d.windowEnd = copy(d.window, b)
+		d.zopfli.start = d.windowEnd
+		return
+	}
 	s := d.state
 	// If we are given too much, cut it.
 	if len(b) > windowSize {
@@ -388,8 +421,16 @@ func bulkHash4(b []byte, dst []uint32) {
 	}
 }
 
+// allocWindow returns a window of size n, reusing the current window if possible.
+func (d *compressor) allocWindow(n int) []byte {
+	if cap(d.window) >= n {
+		return d.window[:n]
+	}
+	return make([]byte, n)
+}
+
 func (d *compressor) initDeflate() {
-	d.window = make([]byte, 2*windowSize)
+	d.window = d.allocWindow(2 * windowSize)
 	d.byteAvailable = false
 	d.err = nil
 	if d.state == nil {
@@ -785,16 +826,19 @@ func (d *compressor) syncFlush() error {
 }
 
 func (d *compressor) init(w io.Writer, level int) (err error) {
-	d.w = newHuffmanBitWriter(w)
+	if d.w == nil {
+		d.w = newHuffmanBitWriter(w)
+	}
+	d.w.logNewTablePenalty = 0
 
 	switch {
 	case level == NoCompression:
-		d.window = make([]byte, maxStoreBlockSize)
+		d.window = d.allocWindow(maxStoreBlockSize)
 		d.fill = (*compressor).fillBlock
 		d.step = (*compressor).store
 	case level == ConstantCompression:
 		d.w.logNewTablePenalty = 10
-		d.window = make([]byte, 32<<10)
+		d.window = d.allocWindow(32 << 10)
 		d.fill = (*compressor).fillBlock
 		d.step = (*compressor).storeHuff
 	case level == DefaultCompression:
@@ -803,24 +847,33 @@ func (d *compressor) init(w io.Writer, level int) (err error) {
 	case level >= 1 && level <= 6:
 		d.w.logNewTablePenalty = 7
 		d.fast = newFastEnc(level)
-		d.window = make([]byte, maxStoreBlockSize)
+		d.window = d.allocWindow(maxStoreBlockSize)
 		d.fill = (*compressor).fillBlock
 		d.step = (*compressor).storeFast
 	case 7 <= level && level <= 9:
 		d.w.logNewTablePenalty = 8
-		d.state = &advancedState{}
+		if d.state == nil {
+			d.state = &advancedState{}
+		} else {
+			*d.state = advancedState{}
+		}
 		d.compressionLevel = levels[level]
 		d.initDeflate()
 		d.fill = (*compressor).fillDeflate
 		d.step = (*compressor).deflateLazy
+	case 10 <= level && level <= BestCompressionZopfli:
+		d.zopfli = newZopfliState(level)
+		d.window = d.allocWindow(maxMatchOffset + zopfliChunkSize)
+		d.fill = (*compressor).fillBlock
+		d.step = (*compressor).storeZopfli
 	case -level >= MinCustomWindowSize && -level <= MaxCustomWindowSize:
 		d.w.logNewTablePenalty = 7
 		d.fast = &fastEncL5Window{maxOffset: int32(-level), cur: maxStoreBlockSize}
-		d.window = make([]byte, maxStoreBlockSize)
+		d.window = d.allocWindow(maxStoreBlockSize)
 		d.fill = (*compressor).fillBlock
 		d.step = (*compressor).storeFast
 	default:
-		return fmt.Errorf("flate: invalid compression level %d: want value in range [-2, 9]", level)
+		return fmt.Errorf("flate: invalid compression level %d: want value in range [-2, 12]", level)
 	}
 	d.level = level
 	return nil
@@ -829,6 +882,11 @@ func (d *compressor) init(w io.Writer, level int) (err error) {
 // reset the state of the compressor.
 func (d *compressor) reset(w io.Writer) {
 	d.w.reset(w)
+	d.resetState()
+}
+
+// resetState resets the compression state, but not the output.
+func (d *compressor) resetState() {
 	d.sync = false
 	d.err = nil
 	// We only need to reset a few things for Snappy.
@@ -838,6 +896,10 @@ func (d *compressor) reset(w io.Writer) {
 		d.tokens.Reset()
 		return
 	}
+	if d.zopfli != nil {
+		d.zopfli.start, d.windowEnd = 0, 0
+		return
+	}
 	switch d.compressionLevel.chain {
 	case 0:
 		// level was NoCompression or ConstantCompression.
@@ -888,8 +950,10 @@ func (d *compressor) close() error {
 // Level -2 (ConstantCompression) will use Huffman compression only, giving
 // a very fast compression for all types of input, but sacrificing considerable
 // compression efficiency.
+// Levels 10 to 12 (BestCompressionZopfli) are very slow, but compress
+// better than BestCompression.
 //
-// If level is in the range [-2, 9] then the error returned will be nil.
+// If level is in the range [-2, 12] then the error returned will be nil.
 // Otherwise the error returned will be non-nil.
 func NewWriter(w io.Writer, level int) (*Writer, error) {
 	var dw Writer
@@ -940,13 +1004,17 @@ func NewWriterWindow(w io.Writer, windowSize int) (*Writer, error) {
 // A Writer takes data written to it and writes the compressed
 // form of that data to an underlying writer (see NewWriter).
 type Writer struct {
-	d    compressor
-	dict []byte
+	d     compressor
+	dict  []byte
+	rsync *rsyncState
 }
 
 // Write writes data to w, which will eventually write the
 // compressed form of data to its underlying writer.
 func (w *Writer) Write(data []byte) (n int, err error) {
+	if w.rsync != nil {
+		return w.writeRsyncable(data)
+	}
 	return w.d.write(data)
 }
 
@@ -974,6 +1042,9 @@ func (w *Writer) Close() error {
 // the result of NewWriter or NewWriterDict called with dst
 // and w's level and dictionary.
 func (w *Writer) Reset(dst io.Writer) {
+	if w.rsync != nil {
+		w.rsync.reset()
+	}
 	if len(w.dict) > 0 {
 		// w was created with NewWriterDict
 		w.d.reset(dst)
@@ -990,7 +1061,86 @@ func (w *Writer) Reset(dst io.Writer) {
 // the result of NewWriter or NewWriterDict called with dst
 // and w's level, but sets a specific dictionary.
 func (w *Writer) ResetDict(dst io.Writer, dict []byte) {
+	if w.rsync != nil {
+		w.rsync.reset()
+	}
 	w.dict = dict
 	w.d.reset(dst)
 	w.d.fillWindow(w.dict)
 }
+
+// SetLevel changes the compression level of subsequent writes.
+// Data already written is compressed with the previous level and ends
+// the current block, but no sync marker is written and the output is not flushed.
+// The output remains a single deflate stream, and unless the previous
+// level was NoCompression or HuffmanOnly, subsequent data can reference
+// data written before the change.
+// The new level is kept if the Writer is Reset.
+// If the level is unchanged, SetLevel does nothing.
+//
+// This is similar to deflateParams in zlib.
+// If level is in the range [-2, 12] then the error returned will be nil.
+// Otherwise the error returned will be non-nil.
+func (w *Writer) SetLevel(level int) error {
+	if level < HuffmanOnly || level > BestCompressionZopfli {
+		return fmt.Errorf("flate: invalid compression level %d: want value in range [-2, 12]", level)
+	}
+	return w.restart(level, nil)
+}
+
+// SetDictionary sets a dictionary for subsequent writes.
+// Data already written is compressed and ends the current block,
+// after which data is compressed as if dict had been written, without
+// producing any compressed output.
+// A reader must append the same dictionary to its history at
+// this point of the stream to decompress the output.
+// Typically the Writer is flushed before calling SetDictionary,
+// so the reader can switch dictionary at a byte boundary.
+// The dictionary used by Reset is not changed.
+//
+// This is similar to deflateSetDictionary in zlib.
+func (w *Writer) SetDictionary(dict []byte) error {
+	return w.restart(w.d.level, dict)
+}
+
+// restart ends the current block and restarts compression with level,
+// keeping history and the position in the output stream.
+// dict is added to the history.
+func (w *Writer) restart(level int, dict []byte) error {
+	d := &w.d
+	if d.err != nil {
+		return d.err
+	}
+	if level == DefaultCompression {
+		level = 5
+	}
+	if level == d.level && len(dict) == 0 {
+		return nil
+	}
+	// Write all pending data with the current level.
+	d.sync = true
+	d.step(d)
+	d.sync = false
+	if d.err != nil {
+		return d.err
+	}
+	hist := append(append([]byte{}, d.history()...), dict...)
+	if level == d.level {
+		d.resetState()
+		d.fillWindow(hist)
+		return nil
+	}
+
+	// Keep the bit writer, since it contains pending bits.
+	// Reuse the window and hash tables, if the new level can use them.
+	nd := compressor{w: d.w, window: d.window}
+	if 7 <= level && level <= 9 {
+		nd.state = d.state
+	}
+	if err := nd.init(nil, level); err != nil {
+		return err
+	}
+	*d = nd
+	d.fillWindow(hist)
+	return nil
+}
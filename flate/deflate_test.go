@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"reflect"
 	"strings"
@@ -581,6 +582,75 @@ func TestWriterReset(t *testing.T) {
 	})
 }
 
+func TestWriterZopfli(t *testing.T) {
+	if _, err := NewWriter(io.Discard, BestCompressionZopfli+1); err == nil {
+		t.Fatal("expected error on invalid level")
+	}
+	in, err := os.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in = in[:100000]
+	// Add a long repeat and some incompressible data.
+	in = append(in, bytes.Repeat([]byte("abcdefg"), 20000)...)
+	rng := rand.New(rand.NewSource(0))
+	for range 10000 {
+		in = append(in, byte(rng.Intn(256)))
+	}
+	dict := in[len(in)/2 : len(in)/2+1000]
+
+	var ref bytes.Buffer
+	w, _ := NewWriter(&ref, BestCompression)
+	w.Write(in)
+	w.Close()
+	for level := 10; level <= BestCompressionZopfli; level++ {
+		testResetOutput(t, fmt.Sprint("level-", level), func(w io.Writer) (*Writer, error) { return NewWriter(w, level) })
+		testResetOutput(t, fmt.Sprint("dict-level-", level), func(w io.Writer) (*Writer, error) { return NewWriterDict(w, level, dict) })
+
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(in)
+		w.Close()
+		t.Logf("level %d: %d -> %d bytes, level 9: %d bytes", level, len(in), buf.Len(), ref.Len())
+		if buf.Len() >= ref.Len() {
+			t.Errorf("level %d: output (%d) not smaller than level 9 (%d)", level, buf.Len(), ref.Len())
+		}
+		got, err := io.ReadAll(NewReader(&buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, in) {
+			t.Fatalf("level %d: content mismatch", level)
+		}
+
+		// Flush and dictionary.
+		buf.Reset()
+		w, err = NewWriterDict(&buf, level, dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rem := in; len(rem) > 0; {
+			n := min(len(rem), rng.Intn(50000))
+			w.Write(rem[:n])
+			rem = rem[n:]
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+		got, err = io.ReadAll(NewReaderDict(&buf, dict))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, in) {
+			t.Fatalf("level %d: content mismatch with dictionary", level)
+		}
+	}
+}
+
 func testResetOutput(t *testing.T, name string, newWriter func(w io.Writer) (*Writer, error)) {
 	t.Run(name, func(t *testing.T) {
 		buf := new(bytes.Buffer)
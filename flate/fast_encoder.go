@@ -14,6 +14,7 @@ import (
 type fastEnc interface {
 	Encode(dst *tokens, src []byte)
 	Reset()
+	history() []byte
 }
 
 func newFastEnc(level int) fastEnc {
@@ -175,6 +176,11 @@ func (e *fastGen) matchlenLong(s, t int, src []byte) int32 {
 	return int32(matchLen(src[s:], src[t:]))
 }
 
+// history returns the previously encoded data.
+func (e *fastGen) history() []byte {
+	return e.hist
+}
+
 // Reset the encoding table.
 func (e *fastGen) Reset() {
 	if cap(e.hist) < allocHistory {
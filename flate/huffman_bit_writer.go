@@ -122,6 +122,9 @@ type huffmanBitWriter struct {
 
 	// codegen must have an extra space for the final symbol.
 	codegen [literalCount + offsetCodeCount + 1]uint8
+
+	// split is allocated on first use by writeBlockSplit.
+	split *blockSplitter
 }
 
 // Huffman reuse.
@@ -612,6 +615,30 @@ func (w *huffmanBitWriter) writeBlock(tokens *tokens, eof bool, input []byte) {
 	w.writeTokens(tokens.Slice(), literalEncoding.codes, offsetEncoding.codes)
 }
 
+// blockSize returns the size in bits of the tokens, when written
+// by writeBlock with the same input.
+// The tokens must not be empty or contain an EOB, and are not modified.
+// The literal and offset encodings of w are overwritten,
+// so it should not be called while a block is pending.
+func (w *huffmanBitWriter) blockSize(tokens *tokens, input []byte) int {
+	numLiterals, numOffsets := w.indexTokens(tokens, true)
+	w.generate()
+	extraBits := w.extraBitSize()
+
+	size := math.MaxInt32
+	if tokens.n+1 < maxPredefinedTokens {
+		size = w.fixedSize(extraBits)
+	}
+	w.generateCodegen(numLiterals, numOffsets, w.literalEncoding, w.offsetEncoding)
+	w.codegenEncoding.generate(w.codegenFreq[:], 7)
+	dynamicSize, _ := w.dynamicSize(w.literalEncoding, w.offsetEncoding, extraBits)
+	size = min(size, dynamicSize)
+	if storedSize, storable := w.storedSize(input); storable {
+		size = min(size, storedSize)
+	}
+	return size
+}
+
 // writeBlockDynamic encodes a block using a dynamic Huffman table.
 // This should be used if the symbols used have a disproportionate
 // histogram distribution.
@@ -764,6 +791,174 @@ func (w *huffmanBitWriter) writeBlockDynamic(tokens *tokens, eof bool, input []b
 	w.writeTokens(tokens.Slice(), w.literalEncoding.codes, w.offsetEncoding.codes)
 }
 
+// Block splitting.
+//
+// writeBlockSplit divides the tokens into segments of splitSegmentTokens and
+// estimates the size of a block from the entropy of its symbol histogram.
+// Blocks are split recursively where two blocks with separate tables are
+// estimated to be smaller than a single block, including the cost of the
+// additional header.
+const (
+	splitSegmentTokens = 1024
+	splitMaxSegments   = (maxStoreBlockSize + splitSegmentTokens) / splitSegmentTokens
+	splitSymbols       = lengthCodesStart + 32 + 32
+	// Estimated cost of a dynamic header in bits, excluding code lengths.
+	splitHeaderBits = 5 + 5 + 4 + 19*3
+	// Estimated cost in bits of each used symbol in a dynamic header.
+	splitSymbolBits = 4
+	// Blocks are only split if the estimated size is reduced by 1/splitMinGain.
+	splitMinGain = 128
+)
+
+type blockSplitter struct {
+	tok    tokens                                     // Tokens of the current piece.
+	hist   [splitMaxSegments + 1][splitSymbols]uint32 // Cumulative histograms at segment starts.
+	extra  [splitMaxSegments + 1]int                  // Cumulative extra bits at segment starts.
+	pos    [splitMaxSegments + 1]int                  // Input offsets of segment starts.
+	points []int                                      // Split points, as segment indexes.
+}
+
+// writeBlockSplit writes the tokens as writeBlockDynamic,
+// but splits them into several blocks if the symbol statistics change.
+func (w *huffmanBitWriter) writeBlockSplit(tokens *tokens, eof bool, input []byte, sync bool) {
+	if w.err != nil {
+		return
+	}
+	if tokens.n < 2*splitSegmentTokens {
+		w.writeBlockDynamic(tokens, eof, input, sync)
+		return
+	}
+	if w.split == nil {
+		w.split = &blockSplitter{}
+	}
+	s := w.split
+	all := tokens.Slice()
+	segments := s.index(all)
+	s.points = s.points[:0]
+	s.splitRange(0, segments)
+	if len(s.points) == 0 {
+		w.writeBlockDynamic(tokens, eof, input, sync)
+		return
+	}
+	s.points = append(s.points, segments)
+
+	start := 0
+	for _, end := range s.points {
+		s.piece(all, start, end)
+		var in []byte
+		if input != nil {
+			in = input[s.pos[start]:s.pos[end]]
+		}
+		last := end == segments
+		w.writeBlockDynamic(&s.tok, eof && last, in, sync && last)
+		start = end
+	}
+}
+
+// index generates the cumulative histograms of the segments of tokens
+// and returns the number of segments.
+func (s *blockSplitter) index(tokens []token) int {
+	extra, pos := 0, 0
+	s.hist[0] = [splitSymbols]uint32{}
+	s.extra[0], s.pos[0] = 0, 0
+	i := 0
+	for len(tokens) > 0 {
+		seg := tokens[:min(len(tokens), splitSegmentTokens)]
+		tokens = tokens[len(seg):]
+		cur := &s.hist[i+1]
+		*cur = s.hist[i]
+		for _, t := range seg {
+			if t < matchType {
+				cur[uint8(t)]++
+				continue
+			}
+			length := t.length()
+			lCode := uint32(lengthCodes1[length])
+			oCode := (uint32(t) >> 16) & 31
+			cur[lengthCodesStart+lCode]++
+			cur[lengthCodesStart+32+oCode]++
+			extra += int(lengthExtraBits[(lCode-1)&31]) + int(offsetExtraBits[oCode])
+			pos += int(length) + baseMatchLength - 1
+		}
+		pos += len(seg)
+		i++
+		s.extra[i], s.pos[i] = extra, pos
+	}
+	return i
+}
+
+// piece sets s.tok to the tokens of segments [a, b).
+func (s *blockSplitter) piece(tokens []token, a, b int) {
+	t := &s.tok
+	ha, hb := &s.hist[a], &s.hist[b]
+	for i := range t.litHist {
+		t.litHist[i] = uint16(hb[i] - ha[i])
+	}
+	for i := range t.extraHist {
+		t.extraHist[i] = uint16(hb[lengthCodesStart+i] - ha[lengthCodesStart+i])
+	}
+	for i := range t.offHist {
+		t.offHist[i] = uint16(hb[lengthCodesStart+32+i] - ha[lengthCodesStart+32+i])
+	}
+	t.nFilled = 0
+	t.n = uint16(copy(t.tokens[:], tokens[a*splitSegmentTokens:min(b*splitSegmentTokens, len(tokens))]))
+}
+
+// cost returns the estimated size in bits of segments [a, b) as one block.
+// Like tokens.EstimatedBits, code lengths are estimated from the entropy.
+func (s *blockSplitter) cost(a, b int) float32 {
+	ha, hb := &s.hist[a], &s.hist[b]
+	bits := float32(s.extra[b]-s.extra[a]) + splitHeaderBits
+	// Literals and lengths, including EOB.
+	total := uint32(1)
+	for i := range lengthCodesStart + 32 {
+		total += hb[i] - ha[i]
+	}
+	invTotal := 1.0 / float32(total)
+	for i := range lengthCodesStart + 32 {
+		if v := hb[i] - ha[i]; v > 0 {
+			n := float32(v)
+			bits += atLeastOne(-mFastLog2(n*invTotal))*n + splitSymbolBits
+		}
+	}
+	total = 0
+	for i := lengthCodesStart + 32; i < splitSymbols; i++ {
+		total += hb[i] - ha[i]
+	}
+	if total == 0 {
+		return bits
+	}
+	invTotal = 1.0 / float32(total)
+	for i := lengthCodesStart + 32; i < splitSymbols; i++ {
+		if v := hb[i] - ha[i]; v > 0 {
+			n := float32(v)
+			bits += atLeastOne(-mFastLog2(n*invTotal))*n + splitSymbolBits
+		}
+	}
+	return bits
+}
+
+// splitRange adds the split points of segments [a, b) to s.points in order.
+func (s *blockSplitter) splitRange(a, b int) {
+	if b-a < 2 {
+		return
+	}
+	best, bestCost := 0, s.cost(a, b)
+	// Only split if it is estimated to save a reasonable amount.
+	bestCost -= bestCost / splitMinGain
+	for i := a + 1; i < b; i++ {
+		if c := s.cost(a, i) + s.cost(i, b); c < bestCost {
+			best, bestCost = i, c
+		}
+	}
+	if best == 0 {
+		return
+	}
+	s.splitRange(a, best)
+	s.points = append(s.points, best)
+	s.splitRange(best, b)
+}
+
 func (w *huffmanBitWriter) fillTokens() {
 	for i, v := range w.literalFreq[:literalCount] {
 		if v == 0 {
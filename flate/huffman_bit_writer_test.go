@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -331,6 +332,45 @@ func writeToType(t *testing.T, ttype string, bw *huffmanBitWriter, tok tokens, i
 	}
 }
 
+func TestWriteBlockSplit(t *testing.T) {
+	text, err := os.ReadFile("../testdata/html.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := os.ReadFile("../testdata/pngdata.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Text followed by binary data should be split.
+	in := append(text[:maxStatelessBlock/2:maxStatelessBlock/2], bin[:maxStatelessBlock/2]...)
+	var tok tokens
+	statelessEnc(&tok, in, 0)
+
+	var dyn, split bytes.Buffer
+	bw := newHuffmanBitWriter(&dyn)
+	bw.writeBlockDynamic(&tok, true, in, true)
+	bw.flush()
+	tok.Reset()
+	statelessEnc(&tok, in, 0)
+	bw = newHuffmanBitWriter(&split)
+	bw.writeBlockSplit(&tok, true, in, true)
+	bw.flush()
+	if bw.err != nil {
+		t.Fatal(bw.err)
+	}
+	t.Logf("dynamic: %d bytes, split: %d bytes", dyn.Len(), split.Len())
+	if split.Len() >= dyn.Len() {
+		t.Errorf("split block not smaller: %d >= %d", split.Len(), dyn.Len())
+	}
+	got, err := io.ReadAll(NewReader(&split))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Fatal("output mismatch")
+	}
+}
+
 // testWriterEOF tests if the written block contains an EOF marker.
 func testWriterEOF(t *testing.T, ttype string, test huffTest, useInput bool) {
 	if useInput && test.input == "" {
@@ -296,6 +296,7 @@ const (
 	huffmanBufioReader
 	huffmanStringsReader
 	huffmanGenericReader
+	huffmanBlock64
 )
 
 // flushMode tells decompressor when to return data
@@ -316,7 +317,7 @@ type decompressor struct {
 	h1, h2 huffmanDecoder
 
 	// Length arrays used to define Huffman codes.
-	bits     *[maxNumLit + maxNumDist]int
+	bits     *[maxNumLit + maxNumDist64]int
 	codebits *[numCodes]int
 
 	// Output history, buffer.
@@ -341,6 +342,7 @@ type decompressor struct {
 	nb    uint
 	final bool
 
+	deflate64 bool // Decode Deflate64 (enhanced deflate) streams.
 	flushMode flushMode
 	cb        func(InflateCheckpoint)
 	cp        InflateCheckpoint
@@ -370,7 +372,11 @@ func (f *decompressor) nextBlock() {
 		// compressed, fixed Huffman tables
 		f.hl = &fixedHuffmanDecoder
 		f.hd = nil
-		f.huffmanBlockDecoder()
+		if f.deflate64 {
+			f.huffmanBlock64()
+		} else {
+			f.huffmanBlockDecoder()
+		}
 		if debugDecode {
 			fmt.Println("predefinied huffman block")
 		}
@@ -381,7 +387,11 @@ func (f *decompressor) nextBlock() {
 		}
 		f.hl = &f.h1
 		f.hd = &f.h2
-		f.huffmanBlockDecoder()
+		if f.deflate64 {
+			f.huffmanBlock64()
+		} else {
+			f.huffmanBlockDecoder()
+		}
 		if debugDecode {
 			fmt.Println("dynamic huffman block")
 		}
@@ -477,7 +487,7 @@ func (f *decompressor) readHuffman() error {
 	}
 	f.b >>= 5
 	ndist := int(f.b&0x1F) + 1
-	if ndist > maxNumDist {
+	if ndist > maxNumDist && !f.deflate64 {
 		if debugDecode {
 			fmt.Println("ndist > maxNumDist", ndist)
 		}
@@ -712,6 +722,8 @@ func (f *decompressor) doStep() {
 		f.huffmanStringsReader()
 	case huffmanGenericReader:
 		f.huffmanGenericReader()
+	case huffmanBlock64:
+		f.huffmanBlock64()
 	default:
 		panic("BUG: unexpected step state")
 	}
@@ -811,15 +823,16 @@ func fixedHuffmanDecoderInit() {
 
 func (f *decompressor) Reset(r io.Reader, dict []byte) error {
 	*f = decompressor{
-		r:        makeReader(r),
-		bits:     f.bits,
-		codebits: f.codebits,
-		h1:       f.h1,
-		h2:       f.h2,
-		dict:     f.dict,
-		step:     nextBlock,
-	}
-	f.dict.init(maxMatchOffset, dict)
+		r:         makeReader(r),
+		bits:      f.bits,
+		codebits:  f.codebits,
+		h1:        f.h1,
+		h2:        f.h2,
+		dict:      f.dict,
+		step:      nextBlock,
+		deflate64: f.deflate64,
+	}
+	f.dict.init(f.windowSize(), dict)
 	return nil
 }
 
@@ -827,14 +840,15 @@ func (f *decompressor) Reset(r io.Reader, dict []byte) error {
 // It is assumed the input stream is forwarded to cp.CompressedOffset.
 func (f *decompressor) ResetCP(r io.Reader, cp InflateCheckpoint) error {
 	*f = decompressor{
-		r:        makeReader(r),
-		bits:     f.bits,
-		codebits: f.codebits,
-		h1:       f.h1,
-		h2:       f.h2,
-		dict:     f.dict,
-		step:     nextBlock,
-		cpBuf:    f.cpBuf,
+		r:         makeReader(r),
+		bits:      f.bits,
+		codebits:  f.codebits,
+		h1:        f.h1,
+		h2:        f.h2,
+		dict:      f.dict,
+		step:      nextBlock,
+		cpBuf:     f.cpBuf,
+		deflate64: f.deflate64,
 	}
 	return f.applyCP(cp)
 }
@@ -844,7 +858,7 @@ func (f *decompressor) ResetCP(r io.Reader, cp InflateCheckpoint) error {
 // offsets, and skips cp.BitOffset bits into the first input byte so
 // the next decode aligns with the start of a deflate block.
 func (f *decompressor) applyCP(cp InflateCheckpoint) error {
-	f.dict.init(maxMatchOffset, cp.Window)
+	f.dict.init(f.windowSize(), cp.Window)
 	f.roffset = cp.CompressedOffset
 	f.uncOffset = cp.UncompressedOffset
 	f.final = cp.Final
@@ -875,7 +889,7 @@ func WithPartialBlock() ReaderOpt {
 // WithDict initializes the reader with a preset dictionary
 func WithDict(dict []byte) ReaderOpt {
 	return func(f *decompressor) {
-		f.dict.init(maxMatchOffset, dict)
+		f.dict.init(f.windowSize(), dict)
 	}
 }
 
@@ -915,7 +929,7 @@ func NewReaderOpts(r io.Reader, opts ...ReaderOpt) io.ReadCloser {
 
 	var f decompressor
 	f.r = makeReader(r)
-	f.bits = new([maxNumLit + maxNumDist]int)
+	f.bits = new([maxNumLit + maxNumDist64]int)
 	f.codebits = new([numCodes]int)
 	f.step = nextBlock
 	f.dict.init(maxMatchOffset, nil)
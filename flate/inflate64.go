@@ -0,0 +1,194 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flate
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Deflate64 ("enhanced deflate") is a proprietary extension of deflate
+// used by PKZIP and Windows Explorer (zip method 9). It differs from
+// deflate in three ways:
+//
+//   - The history window is 64KB instead of 32KB.
+//   - Length code 285 has 16 extra bits and a base of 3, instead of
+//     representing a fixed length of 258.
+//   - Distance codes 30 and 31 are valid, with 14 extra bits each.
+const (
+	maxNumDist64     = 32      // Number of distance codes in Deflate64.
+	maxMatchOffset64 = 1 << 16 // Deflate64 history window size.
+)
+
+// windowSize returns the history size used by the decompressor.
+func (f *decompressor) windowSize() int {
+	if f.deflate64 {
+		return maxMatchOffset64
+	}
+	return maxMatchOffset
+}
+
+// NewReader64 returns a new ReadCloser that can be used
+// to read the uncompressed version of a Deflate64 stream r.
+// Since length code 285 has a different meaning in Deflate64,
+// regular deflate streams cannot in general be read with this reader.
+// If r does not also implement io.ByteReader,
+// the decompressor may read more data than necessary from r.
+// It is the caller's responsibility to call Close on the ReadCloser
+// when finished reading.
+//
+// The ReadCloser returned by NewReader64 also implements Resetter.
+// Reset will keep decoding Deflate64.
+func NewReader64(r io.Reader) io.ReadCloser {
+	return NewReaderOpts(r, func(f *decompressor) {
+		f.deflate64 = true
+		f.dict.init(maxMatchOffset64, nil)
+	})
+}
+
+// huffmanBlock64 decodes a single Huffman block using Deflate64 symbol semantics.
+// Deflate64 is rarely used, so this uses the generic bit reader
+// instead of the specialized decoders in inflate_gen.go.
+func (f *decompressor) huffmanBlock64() {
+	const (
+		stateInit = iota // Zero value must be stateInit
+		stateDict
+	)
+
+	switch f.stepState {
+	case stateInit:
+		goto readLiteral
+	case stateDict:
+		goto copyHistory
+	}
+
+readLiteral:
+	// Read literal and/or (length, distance) according to RFC section 3.2.3.
+	{
+		v, err := f.huffSym(f.hl)
+		if err != nil {
+			f.err = err
+			return
+		}
+		var length int
+		switch {
+		case v < 256:
+			f.dict.writeByte(byte(v))
+			if f.dict.availWrite() == 0 {
+				f.toRead = f.dict.readFlush()
+				f.step = huffmanBlock64
+				f.stepState = stateInit
+				return
+			}
+			goto readLiteral
+		case v == 256:
+			f.finishBlock()
+			return
+		// otherwise, reference to older data
+		case v < 265:
+			length = v - (257 - 3)
+		case v < maxNumLit:
+			val := decCodeToLen[v-257]
+			length = int(val.length) + 3
+			n := uint(val.extra)
+			if v == maxNumLit-1 {
+				// Code 285 is base 3 with 16 extra bits.
+				length, n = 3, 16
+			}
+			for f.nb < n {
+				if err = f.moreBits(); err != nil {
+					f.err = err
+					return
+				}
+			}
+			length += int(f.b & bitMask32[n])
+			f.b >>= n & regSizeMaskUint32
+			f.nb -= n
+		default:
+			if debugDecode {
+				fmt.Println(v, ">= maxNumLit")
+			}
+			f.err = CorruptInputError(f.roffset)
+			return
+		}
+
+		var dist uint32
+		if f.hd == nil {
+			for f.nb < 5 {
+				if err = f.moreBits(); err != nil {
+					f.err = err
+					return
+				}
+			}
+			dist = uint32(bits.Reverse8(uint8(f.b & 0x1F << 3)))
+			f.b >>= 5
+			f.nb -= 5
+		} else {
+			sym, err := f.huffSym(f.hd)
+			if err != nil {
+				f.err = err
+				return
+			}
+			dist = uint32(sym)
+		}
+
+		switch {
+		case dist < 4:
+			dist++
+		case dist < maxNumDist64:
+			nb := uint(dist-2) >> 1
+			// have 1 bit in bottom of dist, need nb more.
+			extra := (dist & 1) << (nb & regSizeMaskUint32)
+			for f.nb < nb {
+				if err = f.moreBits(); err != nil {
+					f.err = err
+					return
+				}
+			}
+			extra |= f.b & bitMask32[nb]
+			f.b >>= nb & regSizeMaskUint32
+			f.nb -= nb
+			dist = 1<<((nb+1)&regSizeMaskUint32) + 1 + extra
+		default:
+			if debugDecode {
+				fmt.Println("dist too big:", dist, maxNumDist64)
+			}
+			f.err = CorruptInputError(f.roffset)
+			return
+		}
+
+		// No check on length; encoding can be prescient.
+		if dist > uint32(f.dict.histSize()) {
+			if debugDecode {
+				fmt.Println("dist > dict.histSize():", dist, f.dict.histSize())
+			}
+			f.err = CorruptInputError(f.roffset)
+			return
+		}
+
+		f.copyLen, f.copyDist = length, int(dist)
+		goto copyHistory
+	}
+
+copyHistory:
+	// Perform a backwards copy according to RFC section 3.2.3.
+	{
+		cnt := f.dict.tryWriteCopy(f.copyDist, f.copyLen)
+		if cnt == 0 {
+			cnt = f.dict.writeCopy(f.copyDist, f.copyLen)
+		}
+		f.copyLen -= cnt
+
+		if f.dict.availWrite() == 0 || f.copyLen > 0 {
+			f.toRead = f.dict.readFlush()
+			f.step = huffmanBlock64 // We need to continue this work
+			f.stepState = stateDict
+			return
+		}
+		goto readLiteral
+	}
+	// Not reached
+}
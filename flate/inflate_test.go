@@ -8,10 +8,13 @@ import (
 	"bytes"
 	"crypto/rand"
 	"io"
+	"math/bits"
+	mrand "math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestReset(t *testing.T) {
@@ -448,3 +451,207 @@ func TestReaderPartialBlock(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", expected, actual)
 	}
 }
+
+// deflate64Encoder is a minimal Deflate64 encoder used to produce test streams.
+type deflate64Encoder struct {
+	out               []byte
+	b                 uint64
+	nb                uint
+	litLens, distLens []int
+	litCode, distCode []uint16
+	want              []byte
+}
+
+func (e *deflate64Encoder) writeBits(v uint64, n uint) {
+	e.b |= v << e.nb
+	e.nb += n
+	for e.nb >= 8 {
+		e.out = append(e.out, byte(e.b))
+		e.b >>= 8
+		e.nb -= 8
+	}
+}
+
+// writeCode writes a Huffman code, most significant bit first.
+func (e *deflate64Encoder) writeCode(code uint16, n int) {
+	e.writeBits(uint64(bits.Reverse16(code)>>(16-n)), uint(n))
+}
+
+// canonicalCodes returns the canonical Huffman codes for the lengths.
+func canonicalCodes(lens []int) []uint16 {
+	var count [16]int
+	for _, l := range lens {
+		count[l]++
+	}
+	count[0] = 0
+	var next [16]uint16
+	code := uint16(0)
+	for i := 1; i < 16; i++ {
+		code = (code + uint16(count[i-1])) << 1
+		next[i] = code
+	}
+	codes := make([]uint16, len(lens))
+	for i, l := range lens {
+		if l > 0 {
+			codes[i] = next[l]
+			next[l]++
+		}
+	}
+	return codes
+}
+
+// block starts a new block. Fixed tables are used unless dynamic is set,
+// in which case all 32 distance codes are sent.
+func (e *deflate64Encoder) block(final, dynamic bool) {
+	e.writeBits(uint64(b2i(final)), 1)
+	if !dynamic {
+		e.writeBits(1, 2)
+		e.litLens = make([]int, 288)
+		for i := range e.litLens {
+			switch {
+			case i < 144:
+				e.litLens[i] = 8
+			case i < 256:
+				e.litLens[i] = 9
+			case i < 280:
+				e.litLens[i] = 7
+			default:
+				e.litLens[i] = 8
+			}
+		}
+	} else {
+		e.writeBits(2, 2)
+		// 226 codes of 8 bits and 60 codes of 9 bits form a complete tree.
+		e.litLens = make([]int, maxNumLit)
+		for i := range e.litLens {
+			e.litLens[i] = 8 + b2i(i >= 226)
+		}
+	}
+	e.distLens = make([]int, maxNumDist64)
+	for i := range e.distLens {
+		e.distLens[i] = 5
+	}
+	e.litCode, e.distCode = canonicalCodes(e.litLens), canonicalCodes(e.distLens)
+	if !dynamic {
+		return
+	}
+	e.writeBits(maxNumLit-257, 5)
+	e.writeBits(maxNumDist64-1, 5)
+	// Code length codes: 5 -> 1 bit, 8 and 9 -> 2 bits.
+	clLens := make([]int, numCodes)
+	clLens[5], clLens[8], clLens[9] = 1, 2, 2
+	const nclen = 10 // codeOrder[9] == 5
+	e.writeBits(nclen-4, 4)
+	for _, c := range codeOrder[:nclen] {
+		e.writeBits(uint64(clLens[c]), 3)
+	}
+	clCodes := canonicalCodes(clLens)
+	for _, l := range append(append([]int{}, e.litLens...), e.distLens...) {
+		e.writeCode(clCodes[l], clLens[l])
+	}
+}
+
+func (e *deflate64Encoder) sym(v int) {
+	e.writeCode(e.litCode[v], e.litLens[v])
+}
+
+func (e *deflate64Encoder) literals(b []byte) {
+	for _, c := range b {
+		e.sym(int(c))
+	}
+	e.want = append(e.want, b...)
+}
+
+func (e *deflate64Encoder) match(length, dist int) {
+	if length > 258 {
+		e.sym(maxNumLit - 1)
+		e.writeBits(uint64(length-3), 16)
+	} else {
+		code := 0
+		for i, v := range decCodeToLen[:maxNumLit-257-1] {
+			if int(v.length)+3 <= length {
+				code = i
+			}
+		}
+		v := decCodeToLen[code]
+		e.sym(code + 257)
+		e.writeBits(uint64(length-3-int(v.length)), uint(v.extra))
+	}
+	for code := maxNumDist64 - 1; code >= 0; code-- {
+		base, nb := code+1, uint(0)
+		if code >= 4 {
+			nb = uint(code-2) >> 1
+			base = 1<<(nb+1) + 1 + (code&1)<<nb
+		}
+		if base <= dist {
+			e.writeCode(e.distCode[code], e.distLens[code])
+			e.writeBits(uint64(dist-base), nb)
+			break
+		}
+	}
+	for range length {
+		e.want = append(e.want, e.want[len(e.want)-dist])
+	}
+}
+
+func (e *deflate64Encoder) close() {
+	e.sym(endBlockMarker)
+	e.writeBits(0, 7)
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestReader64(t *testing.T) {
+	var e deflate64Encoder
+	rng := mrand.New(mrand.NewSource(1))
+	lits := make([]byte, 1000)
+	for _, dynamic := range []bool{false, true} {
+		e.block(dynamic, dynamic)
+		rng.Read(lits)
+		e.literals(lits)
+		e.match(40000, 1)
+		e.match(1000, 41000)   // Distance code 30.
+		e.match(10000, 1)      // Length code 285.
+		e.match(500, 52000)    // Distance code 31.
+		e.match(65538, 1)      // Longest match.
+		e.match(300, 65536)    // Longest distance.
+		e.match(258, 7)        // Length code 284 with all extra bits set.
+		e.match(20, 32768+100) // Distance beyond deflate window.
+		e.literals([]byte("hello"))
+		if !dynamic {
+			e.sym(endBlockMarker)
+		}
+	}
+	e.close()
+
+	r := NewReader64(bytes.NewReader(e.out))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, e.want) {
+		t.Fatalf("output mismatch, got %d bytes, want %d", len(got), len(e.want))
+	}
+
+	// Reset must keep decoding Deflate64.
+	if err := r.(Resetter).Reset(bytes.NewReader(e.out), nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err = io.ReadAll(iotest.OneByteReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, e.want) {
+		t.Fatal("output mismatch after Reset")
+	}
+
+	// Regular deflate must reject the stream.
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(e.out))); err == nil {
+		t.Fatal("deflate reader accepted Deflate64 stream")
+	}
+}
@@ -636,6 +636,11 @@ func (e *fastEncL5Window) Reset() {
 	e.hist = e.hist[:0]
 }
 
+// history returns the previously encoded data.
+func (e *fastEncL5Window) history() []byte {
+	return e.hist
+}
+
 func (e *fastEncL5Window) addBlock(src []byte) int32 {
 	// check if we have space already
 	maxMatchOffset := e.maxOffset
@@ -0,0 +1,97 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flate
+
+import "io"
+
+const (
+	// rsyncWindow is the size of the rolling sum window.
+	rsyncWindow = 4096
+
+	// rsyncModulus is the value the rolling sum must be a multiple of at a boundary.
+	rsyncModulus = 2 * rsyncWindow
+
+	// rsyncMinChunk is the minimum distance between boundaries.
+	// This prevents a boundary at every byte in long runs of zeros.
+	rsyncMinChunk = 2 * rsyncWindow
+)
+
+// rsyncState contains the rolling sum of the last rsyncWindow bytes.
+type rsyncState struct {
+	sum    uint32
+	n      int // Bytes since the last boundary.
+	pos    int // Total bytes, modulo rsyncWindow.
+	full   bool
+	window [rsyncWindow]byte
+}
+
+func (r *rsyncState) reset() {
+	r.sum, r.n, r.pos, r.full = 0, 0, 0, false
+}
+
+// next returns the number of bytes of b up to and including the next boundary.
+// If no boundary is found len(b) and false are returned.
+func (r *rsyncState) next(b []byte) (int, bool) {
+	for i, c := range b {
+		old := r.window[r.pos]
+		r.window[r.pos] = c
+		r.sum += uint32(c) - uint32(old)
+		r.pos++
+		if r.pos == rsyncWindow {
+			r.pos = 0
+			r.full = true
+		}
+		r.n++
+		if r.full && r.n >= rsyncMinChunk && r.sum%rsyncModulus == 0 {
+			r.n = 0
+			return i + 1, true
+		}
+	}
+	return len(b), false
+}
+
+// NewWriterRsyncable returns a new Writer compressing data at the given level,
+// which produces "rsyncable" output, similar to gzip --rsyncable.
+//
+// The input is split at content-defined boundaries chosen by a rolling sum
+// of the last 4KB of input.
+// At each boundary the compressor is flushed and the compression state is reset,
+// so compressed output following a boundary only depends on input after the boundary.
+// This means that a local change in the input only changes the compressed output
+// until the following boundary, which allows tools like rsync to transfer only changed parts.
+//
+// Boundaries are at least 8KB apart, and typically occur every 16KB.
+// Since matches cannot cross boundaries, compression will be a bit worse than NewWriter.
+// The output is a standard deflate stream.
+func NewWriterRsyncable(w io.Writer, level int) (*Writer, error) {
+	zw, err := NewWriter(w, level)
+	if err != nil {
+		return nil, err
+	}
+	zw.rsync = &rsyncState{}
+	return zw, nil
+}
+
+// writeRsyncable writes data, flushing and resetting the compressor at boundaries.
+func (w *Writer) writeRsyncable(data []byte) (n int, err error) {
+	for len(data) > 0 {
+		i, boundary := w.rsync.next(data)
+		m, err := w.d.write(data[:i])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		data = data[i:]
+		if boundary {
+			// Flushing aligns the output to a byte boundary,
+			// so the compressor can be reset without ending the stream.
+			if err := w.d.syncFlush(); err != nil {
+				return n, err
+			}
+			w.d.reset(w.d.w.writer)
+		}
+	}
+	return n, nil
+}
@@ -13,6 +13,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -221,6 +222,48 @@ func BenchmarkEncodeTwain1024Win1e4(b *testing.B) { benchmarkEncoder(b, twain, o
 func BenchmarkEncodeTwain1024Win1e5(b *testing.B) { benchmarkEncoder(b, twain, oneK, 1e5) }
 func BenchmarkEncodeTwain1024Win1e6(b *testing.B) { benchmarkEncoder(b, twain, oneK, 1e6) }
 
+// BenchmarkEncodeCorpus compresses the testdata corpus and reports the compression ratio.
+func BenchmarkEncodeCorpus(b *testing.B) {
+	files := []string{"Mark.Twain-Tom.Sawyer.txt", "e.txt", "html.txt", "pngdata.bin", "sharnd.out"}
+	var mixed []byte
+	for _, name := range files {
+		in, err := os.ReadFile(filepath.Join("..", "testdata", name))
+		if err != nil {
+			b.Fatal(err)
+		}
+		mixed = append(mixed, in[:min(len(in), 100<<10)]...)
+	}
+	for _, name := range append(files, "mixed") {
+		in := mixed
+		if name != "mixed" {
+			in, _ = os.ReadFile(filepath.Join("..", "testdata", name))
+		}
+		for _, level := range []int{5, 6, 7, 8, 9, -100} {
+			lname := strconv.Itoa(level)
+			if level == -100 {
+				lname = "SL"
+			}
+			b.Run(name+"/"+lname, func(b *testing.B) {
+				var buf bytes.Buffer
+				w, _ := NewWriter(&buf, max(level, 0))
+				b.SetBytes(int64(len(in)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					buf.Reset()
+					if level == -100 {
+						StatelessDeflate(&buf, in, true, nil)
+						continue
+					}
+					w.Reset(&buf)
+					w.Write(in)
+					w.Close()
+				}
+				b.ReportMetric(float64(len(in))/float64(buf.Len()), "ratio")
+			})
+		}
+	}
+}
+
 func benchmarkStatelessEncoder(b *testing.B, testfile, n int) {
 	b.SetBytes(int64(n))
 	buf0, err := os.ReadFile(testfiles[testfile])
@@ -542,3 +585,195 @@ func BenchmarkCompressAllocationsSingle(b *testing.B) {
 		}
 	})
 }
+
+func TestWriterRsyncable(t *testing.T) {
+	in, err := os.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in = append(in, make([]byte, 100000)...)
+	// Change a single byte in the middle.
+	changed := bytes.Clone(in)
+	changed[len(in)/2] ^= 0xff
+
+	for _, level := range []int{HuffmanOnly, NoCompression, BestSpeed, DefaultCompression, BestCompression} {
+		t.Run(fmt.Sprint(level), func(t *testing.T) {
+			compress := func(b []byte) []byte {
+				var buf bytes.Buffer
+				w, err := NewWriterRsyncable(&buf, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Write in pieces, to check the state is kept between writes.
+				for len(b) > 0 {
+					n := min(len(b), 1000)
+					if _, err := w.Write(b[:n]); err != nil {
+						t.Fatal(err)
+					}
+					b = b[n:]
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			}
+			a, b := compress(in), compress(changed)
+			for i, c := range [][]byte{a, b} {
+				got, err := io.ReadAll(NewReader(bytes.NewReader(c)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := [][]byte{in, changed}[i]; !bytes.Equal(got, want) {
+					t.Fatal("content mismatch")
+				}
+			}
+			// The output should only differ around the change.
+			prefix := 0
+			for prefix < min(len(a), len(b)) && a[prefix] == b[prefix] {
+				prefix++
+			}
+			suffix := 0
+			for suffix < min(len(a), len(b))-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+				suffix++
+			}
+			diff := len(a) - prefix - suffix
+			t.Logf("size %d, %d bytes differ", len(a), diff)
+			if diff > 32<<10 {
+				t.Errorf("%d bytes differ", diff)
+			}
+
+			// Compare to regular output.
+			var buf bytes.Buffer
+			w, _ := NewWriter(&buf, level)
+			w.Write(in)
+			w.Close()
+			t.Logf("regular size %d", buf.Len())
+		})
+	}
+}
+
+func TestWriterSetLevel(t *testing.T) {
+	in, err := os.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in = in[:200<<10]
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Change level between all writes.
+	const chunk = 10000
+	levels := []int{-2, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, -1}
+	for i := 0; i < len(in); i += chunk {
+		if err := w.SetLevel(levels[(i/chunk)%len(levels)]); err != nil {
+			t.Fatal(err)
+		}
+		w.Write(in[i:min(i+chunk, len(in))])
+		if i%(7*chunk) == 0 {
+			w.Flush()
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Fatal("output mismatch")
+	}
+	if err := w.SetLevel(BestCompressionZopfli + 1); err == nil {
+		t.Fatal("expected error on invalid level")
+	}
+
+	// Changing level should reuse the window and tables.
+	w, err = NewWriter(io.Discard, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(in[:50000])
+	if n := testing.AllocsPerRun(10, func() { w.SetLevel(7) }); n != 0 {
+		t.Errorf("unchanged level: %v allocations", n)
+	}
+	if n := testing.AllocsPerRun(10, func() { w.SetLevel(8); w.SetLevel(9) }); n > 4 {
+		t.Errorf("changing level: %v allocations", n)
+	}
+
+	// History must be kept when changing level.
+	data := in[:20000]
+	for _, from := range []int{1, 5, 7, 10, -100} {
+		for _, to := range []int{-2, 1, 6, 9, 10} {
+			buf.Reset()
+			if from == -100 {
+				w, err = NewWriterWindow(&buf, 1<<15)
+			} else {
+				w, err = NewWriter(&buf, from)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(data)
+			w.Flush()
+			n := buf.Len()
+			if err := w.SetLevel(to); err != nil {
+				t.Fatal(err)
+			}
+			w.Write(data)
+			w.Close()
+			if to != HuffmanOnly && buf.Len()-n > 400 {
+				t.Errorf("%d->%d: history not used, repeated data was %d bytes", from, to, buf.Len()-n)
+			}
+			got, err := io.ReadAll(NewReader(&buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2*len(data) || !bytes.Equal(got[:len(data)], data) || !bytes.Equal(got[len(data):], data) {
+				t.Fatalf("%d->%d: output mismatch", from, to)
+			}
+		}
+	}
+}
+
+func TestWriterSetDictionary(t *testing.T) {
+	in, err := os.ReadFile("../testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, dict := in[:10000], in[100000:120000]
+	for _, level := range []int{0, 1, 5, 7, 9, 10} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(first)
+		w.Flush()
+		n := buf.Len()
+		if err := w.SetDictionary(dict); err != nil {
+			t.Fatal(err)
+		}
+		w.Write(dict)
+		w.Close()
+		if level != NoCompression && buf.Len()-n > 400 {
+			t.Errorf("level %d: dictionary not used, data was %d bytes", level, buf.Len()-n)
+		}
+
+		// The first part can be read as a truncated stream.
+		got, _ := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes()[:n])))
+		if !bytes.Equal(got, first) {
+			t.Fatalf("level %d: first part mismatch", level)
+		}
+		// The rest has the previous output and the dictionary as history.
+		hist := append(append([]byte{}, first...), dict...)
+		got, err = io.ReadAll(NewReaderDict(bytes.NewReader(buf.Bytes()[n:]), hist))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, dict) {
+			t.Fatalf("level %d: output mismatch", level)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flate
+
+import "math"
+
+// Levels 10 to 12 use iterative optimal parsing and block splitting,
+// using the same approach as Zopfli.
+//
+// Input is compressed in chunks of zopfliChunkSize bytes.
+// For each chunk all matches are found, and a greedy parse
+// is used to split the chunk into blocks.
+// Each block is then parsed by finding the cheapest path through the block,
+// using symbol costs from the previous iteration.
+// The smallest result is written.
+
+const (
+	// zopfliChunkSize is the number of bytes compressed at the time.
+	zopfliChunkSize = 256 << 10
+
+	zopfliHashBits = 15
+	zopfliHashSize = 1 << zopfliHashBits
+
+	// zopfliMaxBlocks is the maximum number of blocks a chunk is split into.
+	zopfliMaxBlocks = 15
+
+	// zopfliMaxBlockTokens is the maximum number of tokens in a block, excluding EOB.
+	zopfliMaxBlockTokens = maxStoreBlockSize - 1
+)
+
+type zopfliLevel struct {
+	chain      int // Maximum number of hash chain entries checked.
+	iterations int // Number of optimal parsing iterations.
+}
+
+var zopfliLevels = [...]zopfliLevel{
+	{chain: 1024, iterations: 5},  // 10
+	{chain: 4096, iterations: 15}, // 11
+	{chain: 8192, iterations: 40}, // 12
+}
+
+// lzSym is a literal or a match.
+type lzSym struct {
+	pos    int32  // Position in the window.
+	length uint16 // Match length, or 0 for a literal.
+	dist   uint16
+}
+
+// end returns the position after s.
+func (s lzSym) end() int {
+	return int(s.pos) + max(int(s.length), 1)
+}
+
+// zopfliStats contains symbol frequencies.
+type zopfliStats struct {
+	litLen [literalCount]float64
+	dist   [offsetCodeCount]float64
+}
+
+func (s *zopfliStats) add(window []byte, syms []lzSym) {
+	*s = zopfliStats{}
+	for _, sym := range syms {
+		if sym.length == 0 {
+			s.litLen[window[sym.pos]]++
+			continue
+		}
+		s.litLen[lengthCodesStart+int(lengthCodes[sym.length-baseMatchLength])]++
+		s.dist[offsetCode(uint32(sym.dist-baseMatchOffset))]++
+	}
+	s.litLen[endBlockMarker] = 1
+}
+
+// zopfliCosts contains the cost in bits of each symbol, including extra bits.
+type zopfliCosts struct {
+	lit    [256]float64
+	length [maxMatchLength + 1]float64
+	dist   [offsetCodeCount]float64
+}
+
+// entropy returns the cost of each symbol given the frequencies.
+// Unused symbols are given the cost of a symbol used once.
+func entropy(freq []float64, dst []float64) {
+	var sum float64
+	for _, v := range freq {
+		sum += v
+	}
+	log2sum := math.Log2(sum)
+	if sum == 0 {
+		log2sum = math.Log2(float64(len(freq)))
+	}
+	for i, v := range freq {
+		dst[i] = log2sum
+		if v > 0 {
+			dst[i] = max(log2sum-math.Log2(v), 0)
+		}
+	}
+}
+
+// fromStats sets the costs from symbol frequencies.
+func (c *zopfliCosts) fromStats(s *zopfliStats) {
+	var litLen [literalCount]float64
+	entropy(s.litLen[:], litLen[:])
+	entropy(s.dist[:], c.dist[:])
+	copy(c.lit[:], litLen[:256])
+	for l := baseMatchLength; l <= maxMatchLength; l++ {
+		code := lengthCodes[l-baseMatchLength]
+		c.length[l] = litLen[lengthCodesStart+int(code)] + float64(lengthExtraBits[code])
+	}
+	for i := range c.dist {
+		c.dist[i] += float64(offsetExtraBits[i])
+	}
+}
+
+// zopfliRand is the random number generator used by Zopfli.
+type zopfliRand struct {
+	w, z uint32
+}
+
+func (r *zopfliRand) next() uint32 {
+	r.z = 36969*(r.z&65535) + (r.z >> 16)
+	r.w = 18000*(r.w&65535) + (r.w >> 16)
+	return r.z<<16 + r.w
+}
+
+// randomize replaces random frequencies with other frequencies.
+func (r *zopfliRand) randomize(freq []float64) {
+	for i := range freq {
+		if (r.next()>>4)%3 == 0 {
+			freq[i] = freq[r.next()%uint32(len(freq))]
+		}
+	}
+}
+
+// zopfliState contains the state for levels 10-12.
+type zopfliState struct {
+	zopfliLevel
+
+	// window[:start] is history.
+	start int
+
+	hashHead [zopfliHashSize]int32
+	hashPrev []int32
+
+	// Matches at window position start+i are matches[matchIdx[i]:matchIdx[i+1]].
+	// Each match is stored as length<<16 | dist, with increasing lengths.
+	// The distance is the smallest distance for all lengths after the previous match.
+	matchIdx []int32
+	matches  []uint32
+
+	// repeat contains the number of positions from each position in the chunk,
+	// which have a maximum length match at the same distance.
+	repeat []uint16
+
+	// Shortest path state.
+	costs   []float64
+	lengths []uint16
+	dists   []uint16
+
+	greedy, cur, alt, best []lzSym
+	done                   []bool
+
+	// est is used to calculate block sizes.
+	est *huffmanBitWriter
+	tok tokens
+}
+
+func newZopfliState(level int) *zopfliState {
+	return &zopfliState{
+		zopfliLevel: zopfliLevels[level-10],
+		est:         newHuffmanBitWriter(nil),
+	}
+}
+
+// storeZopfli compresses the buffered input when the window is full
+// or a flush is requested.
+func (d *compressor) storeZopfli() {
+	z := d.zopfli
+	if d.windowEnd < len(d.window) && !d.sync || d.windowEnd == z.start {
+		return
+	}
+	z.compress(d, d.window[:d.windowEnd])
+	d.err = d.w.err
+
+	// Keep history for the next chunk.
+	keep := min(d.windowEnd, maxMatchOffset)
+	copy(d.window, d.window[d.windowEnd-keep:d.windowEnd])
+	z.start, d.windowEnd = keep, keep
+}
+
+// compress window[z.start:] and write the blocks.
+func (z *zopfliState) compress(d *compressor, window []byte) {
+	z.findMatches(window)
+	z.greedyParse(window)
+	splits := z.split(window, z.greedy)
+
+	prev := 0
+	for i := 0; i <= len(splits); i++ {
+		next := len(z.greedy)
+		if i < len(splits) {
+			next = splits[i]
+		}
+		greedy := z.greedy[prev:next]
+		prev = next
+		syms := z.optimal(window, greedy)
+		forPieces(syms, func(piece []lzSym) {
+			tokensFrom(&d.tokens, window, piece)
+			d.w.writeBlock(&d.tokens, false, window[piece[0].pos:piece[len(piece)-1].end()])
+		})
+		if d.w.err != nil {
+			return
+		}
+	}
+}
+
+// forPieces calls fn with syms split into pieces of at most zopfliMaxBlockTokens.
+func forPieces(syms []lzSym, fn func(piece []lzSym)) {
+	n := (len(syms) + zopfliMaxBlockTokens - 1) / zopfliMaxBlockTokens
+	for i := range n {
+		fn(syms[i*len(syms)/n : (i+1)*len(syms)/n])
+	}
+}
+
+// tokensFrom sets dst to the symbols.
+func tokensFrom(dst *tokens, window []byte, syms []lzSym) {
+	dst.Reset()
+	for _, s := range syms {
+		if s.length == 0 {
+			dst.AddLiteral(window[s.pos])
+		} else {
+			dst.AddMatch(uint32(s.length-baseMatchLength), uint32(s.dist-baseMatchOffset))
+		}
+	}
+}
+
+// size returns the encoded size of the symbols in bits.
+// If stored is set, storing the input is considered.
+func (z *zopfliState) size(window []byte, syms []lzSym, stored bool) int {
+	total := 0
+	forPieces(syms, func(piece []lzSym) {
+		tokensFrom(&z.tok, window, piece)
+		var input []byte
+		if stored {
+			input = window[piece[0].pos:piece[len(piece)-1].end()]
+		}
+		total += z.est.blockSize(&z.tok, input)
+	})
+	return total
+}
+
+// findMatches finds matches for all positions in window[z.start:].
+func (z *zopfliState) findMatches(window []byte) {
+	n := len(window) - z.start
+	if cap(z.hashPrev) < len(window) {
+		z.hashPrev = make([]int32, len(window), cap(window))
+		z.matchIdx = make([]int32, n+1, cap(window)+1)
+		z.repeat = make([]uint16, n, cap(window))
+	}
+	z.hashPrev = z.hashPrev[:len(window)]
+	z.matchIdx = z.matchIdx[:n+1]
+	z.repeat = z.repeat[:n]
+	z.matches = z.matches[:0]
+	for i := range z.hashHead {
+		z.hashHead[i] = -1
+	}
+
+	for pos := range window {
+		if pos >= z.start {
+			z.matchIdx[pos-z.start] = int32(len(z.matches))
+		}
+		if pos+baseMatchLength > len(window) {
+			continue
+		}
+		h := (uint32(window[pos]) | uint32(window[pos+1])<<8 | uint32(window[pos+2])<<16) * prime4bytes >> (32 - zopfliHashBits)
+		if pos >= z.start {
+			z.longestMatches(window, pos, z.hashHead[h])
+		}
+		z.hashPrev[pos] = z.hashHead[h]
+		z.hashHead[h] = int32(pos)
+	}
+	z.matchIdx[n] = int32(len(z.matches))
+
+	var next uint32
+	for i := n - 1; i >= 0; i-- {
+		z.repeat[i] = 0
+		m := z.matches[z.matchIdx[i]:z.matchIdx[i+1]]
+		if len(m) == 0 || m[len(m)-1]>>16 != maxMatchLength {
+			next = 0
+			continue
+		}
+		z.repeat[i] = 1
+		if m[len(m)-1] == next {
+			z.repeat[i] = min(z.repeat[i+1], math.MaxUint16-1) + 1
+		}
+		next = m[len(m)-1]
+	}
+}
+
+// longestMatches adds the longest match for each distance,
+// where it is longer than all matches with a smaller distance.
+func (z *zopfliState) longestMatches(window []byte, pos int, cand int32) {
+	maxLen := min(maxMatchLength, len(window)-pos)
+	best := baseMatchLength - 1
+	minCand := pos - maxMatchOffset
+	for tries := z.chain; cand >= 0 && int(cand) >= minCand && tries > 0; tries-- {
+		c := int(cand)
+		cand = z.hashPrev[c]
+		if window[c+best] != window[pos+best] {
+			continue
+		}
+		l := matchLen(window[pos:pos+maxLen], window[c:])
+		if l <= best {
+			continue
+		}
+		z.matches = append(z.matches, uint32(l)<<16|uint32(pos-c))
+		best = l
+		if l == maxLen {
+			break
+		}
+	}
+}
+
+// matchesAt returns the matches at window position pos.
+func (z *zopfliState) matchesAt(pos int) []uint32 {
+	i := pos - z.start
+	return z.matches[z.matchIdx[i]:z.matchIdx[i+1]]
+}
+
+// greedyParse parses window[z.start:] using the longest match at each position.
+func (z *zopfliState) greedyParse(window []byte) {
+	z.greedy = z.greedy[:0]
+	for pos := z.start; pos < len(window); {
+		if m := z.matchesAt(pos); len(m) > 0 {
+			l, d := m[len(m)-1]>>16, m[len(m)-1]&0xffff
+			// Short matches with long distances are rarely better than literals.
+			if l > baseMatchLength || d <= 4096 {
+				z.greedy = append(z.greedy, lzSym{pos: int32(pos), length: uint16(l), dist: uint16(d)})
+				pos += int(l)
+				continue
+			}
+		}
+		z.greedy = append(z.greedy, lzSym{pos: int32(pos)})
+		pos++
+	}
+}
+
+// split returns the indexes of syms where new blocks should start.
+// Blocks are split recursively, by splitting the largest block
+// at the point that gives the smallest size, until no split gives a smaller size.
+func (z *zopfliState) split(window []byte, syms []lzSym) []int {
+	var splits []int
+	if cap(z.done) < len(syms) {
+		z.done = make([]bool, len(syms))
+	}
+	done := z.done[:len(syms)]
+	clear(done)
+	cost := func(start, end int) int {
+		return z.size(window, syms[start:end], true)
+	}
+
+	start, end := 0, len(syms)
+	for len(splits)+1 < zopfliMaxBlocks && end-start >= 10 {
+		pos, splitCost := findMinimum(func(i int) int {
+			return cost(start, i) + cost(i, end)
+		}, start+1, end)
+		if splitCost >= cost(start, end) || pos == start+1 || pos == end {
+			done[start] = true
+		} else {
+			i := 0
+			for i < len(splits) && splits[i] < pos {
+				i++
+			}
+			splits = append(splits[:i], append([]int{pos}, splits[i:]...)...)
+		}
+
+		// Find the largest block that can still be split.
+		found := false
+		for i, best := 0, 0; i <= len(splits); i++ {
+			s, e := 0, len(syms)
+			if i > 0 {
+				s = splits[i-1]
+			}
+			if i < len(splits) {
+				e = splits[i]
+			}
+			if !done[s] && e-s > best {
+				start, end, best, found = s, e, e-s, true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return splits
+}
+
+// findMinimum returns the position in [start, end) where f is smallest, and the value.
+// For big ranges only a subset of positions is checked,
+// which may not find the global minimum.
+func findMinimum(f func(i int) int, start, end int) (int, int) {
+	if end-start < 1024 {
+		best, pos := math.MaxInt, start
+		for i := start; i < end; i++ {
+			if v := f(i); v < best {
+				best, pos = v, i
+			}
+		}
+		return pos, best
+	}
+	const points = 9
+	var p [points]int
+	var v [points]int
+	best, pos := math.MaxInt, start
+	for end-start > points {
+		bestI := 0
+		for i := range p {
+			p[i] = start + (i+1)*((end-start)/(points+1))
+			v[i] = f(p[i])
+			if v[i] < v[bestI] {
+				bestI = i
+			}
+		}
+		if v[bestI] > best {
+			break
+		}
+		if bestI > 0 {
+			start = p[bestI-1]
+		}
+		if bestI < points-1 {
+			end = p[bestI+1]
+		}
+		pos, best = p[bestI], v[bestI]
+	}
+	return pos, best
+}
+
+// optimal returns the smallest parse of the input covered by greedy found in a number of iterations.
+func (z *zopfliState) optimal(window []byte, greedy []lzSym) []lzSym {
+	start, end := int(greedy[0].pos), greedy[len(greedy)-1].end()
+
+	var stats, lastStats, bestStats zopfliStats
+	var costs zopfliCosts
+	rng := zopfliRand{w: 1, z: 2}
+	stats.add(window, greedy)
+	bestStats = stats
+	// Statistics with only literals, which can be a better start
+	// for data with few useful matches.
+	var lits zopfliStats
+	for _, b := range window[start:end] {
+		lits.litLen[b]++
+	}
+	lits.litLen[endBlockMarker] = 1
+	z.best = append(z.best[:0], greedy...)
+	bestSize := z.size(window, greedy, false)
+	lastSize, lastRandom := 0, -1
+
+	for i := range z.iterations {
+		costs.fromStats(&stats)
+		z.cur = z.shortestPath(window, start, end, &costs, z.cur[:0])
+		size := z.size(window, z.cur, false)
+		if i == 0 {
+			costs.fromStats(&lits)
+			z.alt = z.shortestPath(window, start, end, &costs, z.alt[:0])
+			if altSize := z.size(window, z.alt, false); altSize < size {
+				z.cur, z.alt = z.alt, z.cur
+				stats, size = lits, altSize
+			}
+		}
+		if size < bestSize {
+			z.best = append(z.best[:0], z.cur...)
+			bestStats = stats
+			bestSize = size
+		}
+		lastStats = stats
+		stats.add(window, z.cur)
+		if lastRandom >= 0 {
+			// Avoid oscillation by including the previous statistics.
+			for j := range stats.litLen {
+				stats.litLen[j] += lastStats.litLen[j] / 2
+			}
+			for j := range stats.dist {
+				stats.dist[j] += lastStats.dist[j] / 2
+			}
+			stats.litLen[endBlockMarker] = 1
+		}
+		if i > 5 && size == lastSize {
+			// Stuck in a local minimum.
+			stats = bestStats
+			rng.randomize(stats.litLen[:])
+			rng.randomize(stats.dist[:])
+			stats.litLen[endBlockMarker] = 1
+			lastRandom = i
+		}
+		lastSize = size
+	}
+	return z.best
+}
+
+// shortestPath appends the cheapest parse of window[start:end] using the costs to dst.
+func (z *zopfliState) shortestPath(window []byte, start, end int, c *zopfliCosts, dst []lzSym) []lzSym {
+	n := end - start
+	if cap(z.costs) < n+1 {
+		z.costs = make([]float64, n+1, cap(window)+1)
+		z.lengths = make([]uint16, n+1, cap(window)+1)
+		z.dists = make([]uint16, n+1, cap(window)+1)
+	}
+	costs, lengths, dists := z.costs[:n+1], z.lengths[:n+1], z.dists[:n+1]
+	for i := range costs {
+		costs[i] = math.MaxFloat64
+	}
+	costs[0] = 0
+	for i := 0; i < n; i++ {
+		pos := start + i
+		// In long repeats, all positions can be reached with maximum length matches.
+		// Skip ahead, instead of checking each position.
+		if z.repeat[pos-z.start] > maxMatchLength*2 && i > maxMatchLength+1 && i+maxMatchLength*2+1 < n &&
+			z.repeat[pos-z.start-maxMatchLength] > maxMatchLength {
+			m := z.matchesAt(pos)
+			d := uint16(m[len(m)-1])
+			cost := c.length[maxMatchLength] + c.dist[offsetCode(uint32(d-baseMatchOffset))]
+			for range maxMatchLength {
+				costs[i+maxMatchLength] = costs[i] + cost
+				lengths[i+maxMatchLength], dists[i+maxMatchLength] = maxMatchLength, d
+				i++
+			}
+			pos = start + i
+		}
+		cost := costs[i]
+		if v := cost + c.lit[window[pos]]; v < costs[i+1] {
+			costs[i+1], lengths[i+1] = v, 1
+		}
+		maxLen := n - i
+		l := baseMatchLength
+		for _, m := range z.matchesAt(pos) {
+			mLen, d := min(int(m>>16), maxLen), uint16(m)
+			dCost := cost + c.dist[offsetCode(uint32(d-baseMatchOffset))]
+			for ; l <= mLen; l++ {
+				if v := dCost + c.length[l]; v < costs[i+l] {
+					costs[i+l], lengths[i+l], dists[i+l] = v, uint16(l), d
+				}
+			}
+			if mLen == maxLen {
+				break
+			}
+		}
+	}
+
+	// Trace back the path.
+	count := 0
+	for i := n; i > 0; i -= int(lengths[i]) {
+		count++
+	}
+	dst = append(dst, make([]lzSym, count)...)
+	syms := dst[len(dst)-count:]
+	for i := n; i > 0; i -= int(lengths[i]) {
+		count--
+		if l := lengths[i]; l == 1 {
+			syms[count] = lzSym{pos: int32(start + i - 1)}
+		} else {
+			syms[count] = lzSym{pos: int32(start + i - int(l)), length: l, dist: dists[i]}
+		}
+	}
+	return dst
+}
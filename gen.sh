@@ -1,4 +1,4 @@
 #!/bin/sh
 
-cd s2/cmd/_s2sx/ || exit 1
+cd s2/cmd/s2sx/ || exit 1
 go generate .
@@ -0,0 +1,329 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+)
+
+const (
+	// concurrentDictSize is the size of the previous input
+	// used as dictionary for each block.
+	concurrentDictSize = 32 << 10
+
+	// DefaultConcurrentBlockSize is the default block size for NewWriterConcurrent.
+	DefaultConcurrentBlockSize = 1 << 20
+)
+
+var errWriterReset = errors.New("gzip: writer reset")
+
+// NewWriterConcurrent returns a new Writer, which compresses blocks of the input concurrently.
+// Each block is compressed using the previous 32KB of input as dictionary,
+// and blocks are joined with sync flushes, so the output is a single standard gzip member.
+// Compression will be slightly worse than NewWriterLevel,
+// and the output will be a few bytes bigger for each block.
+//
+// The blockSize is the size of the input blocks.
+// If blockSize is <= 0, DefaultConcurrentBlockSize is used.
+// Up to n blocks are compressed concurrently. If n <= 0, GOMAXPROCS is used.
+// Memory usage is approximately (n+1) * 2 * blockSize.
+//
+// Input is buffered until a full block is available.
+// Flush will compress the buffered input and wait for all output to be written.
+// Background goroutines are started on the first write, and stopped by Close or Reset.
+// StatelessCompression and custom window sizes are not supported.
+func NewWriterConcurrent(w io.Writer, level, blockSize, n int) (*Writer, error) {
+	if level < HuffmanOnly || level > BestCompressionZopfli {
+		return nil, fmt.Errorf("gzip: invalid compression level: %d", level)
+	}
+	if blockSize <= 0 {
+		blockSize = DefaultConcurrentBlockSize
+	}
+	if blockSize < 1<<10 {
+		return nil, fmt.Errorf("gzip: block size %d too small, must be at least 1024", blockSize)
+	}
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	z := &Writer{conc: &concurrentWriter{blockSize: blockSize, n: n}}
+	z.init(w, level)
+	return z, nil
+}
+
+// concurrentWriter contains the state of a concurrent Writer.
+type concurrentWriter struct {
+	blockSize int
+	n         int
+
+	// buf contains the dictionary, followed by buffered input.
+	buf     []byte
+	dictLen int
+
+	// queue contains blocks in output order.
+	queue    chan chan concurrentBlock
+	writerWg sync.WaitGroup
+	errMu    sync.Mutex
+	err      error
+
+	bufs       sync.Pool
+	outs       sync.Pool
+	tmpWriters sync.Pool
+}
+
+type concurrentBlock struct {
+	out *bytes.Buffer
+	buf []byte // Input buffer to return to the pool.
+	crc uint32
+	n   int
+}
+
+// setErr returns the first error set.
+func (c *concurrentWriter) setErr(err error) error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+// stop the output goroutine, if running.
+// Queued blocks are not written.
+func (c *concurrentWriter) stop() {
+	if c.queue == nil {
+		return
+	}
+	c.setErr(errWriterReset)
+	close(c.queue)
+	c.writerWg.Wait()
+	c.queue = nil
+}
+
+// reset the state.
+// Any previous output goroutine must be stopped.
+func (c *concurrentWriter) reset() {
+	c.err = nil
+	c.buf = nil
+	c.dictLen = 0
+}
+
+// start the output goroutine writing to z.w, if not running.
+// The goroutine is started on the first write,
+// so a Writer that is never written to doesn't have to be closed.
+func (c *concurrentWriter) start(z *Writer) {
+	if c.queue != nil {
+		return
+	}
+	queue := make(chan chan concurrentBlock, c.n)
+	c.queue = queue
+	c.writerWg.Add(1)
+	go func() {
+		defer c.writerWg.Done()
+		for block := range queue {
+			b := <-block
+			if b.out != nil {
+				if c.setErr(nil) == nil {
+					n, err := z.w.Write(b.out.Bytes())
+					if err == nil && n != b.out.Len() {
+						err = io.ErrShortWrite
+					}
+					c.setErr(err)
+				}
+				c.outs.Put(b.out)
+				c.bufs.Put(b.buf)
+			}
+			z.digest = crc32Combine(z.digest, b.crc, int64(b.n))
+			z.size += uint32(b.n)
+			// Signal that the block has been written.
+			close(block)
+		}
+	}()
+}
+
+// write buffers p and compresses full blocks.
+func (c *concurrentWriter) write(z *Writer, p []byte) (int, error) {
+	if err := c.setErr(nil); err != nil {
+		return 0, err
+	}
+	c.start(z)
+	written := 0
+	for len(p) > 0 {
+		if c.buf == nil {
+			c.buf = c.getBuf()
+		}
+		n := min(len(p), c.dictLen+c.blockSize-len(c.buf))
+		c.buf = append(c.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(c.buf) == c.dictLen+c.blockSize {
+			c.compress(z.level, false)
+		}
+	}
+	return written, c.setErr(nil)
+}
+
+func (c *concurrentWriter) getBuf() []byte {
+	if b, ok := c.bufs.Get().([]byte); ok {
+		return b[:0]
+	}
+	return make([]byte, 0, concurrentDictSize+c.blockSize)
+}
+
+// compress the buffered input, and queue it for output.
+// If final is set, the deflate stream is ended.
+func (c *concurrentWriter) compress(level int, final bool) {
+	buf := c.buf
+	if buf == nil {
+		buf = c.getBuf()
+	}
+	dict, data := buf[:c.dictLen], buf[c.dictLen:]
+
+	// Use the end of the input as dictionary for the next block.
+	if !final {
+		next := c.getBuf()
+		next = append(next, buf[max(0, len(buf)-concurrentDictSize):]...)
+		c.buf = next
+		c.dictLen = len(next)
+	} else {
+		c.buf = nil
+		c.dictLen = 0
+	}
+
+	block := make(chan concurrentBlock)
+	c.queue <- block
+	go func() {
+		out, ok := c.outs.Get().(*bytes.Buffer)
+		if !ok {
+			out = new(bytes.Buffer)
+		}
+		out.Reset()
+		fw, ok := c.tmpWriters.Get().(*flate.Writer)
+		if ok {
+			fw.ResetDict(out, dict)
+		} else {
+			var err error
+			fw, err = flate.NewWriterDict(out, level, dict)
+			if err != nil {
+				c.setErr(err)
+				block <- concurrentBlock{}
+				return
+			}
+		}
+		_, err := fw.Write(data)
+		if err == nil {
+			if final {
+				err = fw.Close()
+			} else {
+				err = fw.Flush()
+			}
+		}
+		c.setErr(err)
+		c.tmpWriters.Put(fw)
+		block <- concurrentBlock{out: out, buf: buf, crc: crc32.ChecksumIEEE(data), n: len(data)}
+	}()
+}
+
+// wait until all queued blocks have been written.
+func (c *concurrentWriter) wait() error {
+	block := make(chan concurrentBlock)
+	c.queue <- block
+	block <- concurrentBlock{}
+	<-block
+	return c.setErr(nil)
+}
+
+// flush compresses buffered input and waits for the output to be written.
+func (c *concurrentWriter) flush(z *Writer) error {
+	if err := c.setErr(nil); err != nil {
+		return err
+	}
+	c.start(z)
+	if len(c.buf) > c.dictLen {
+		c.compress(z.level, false)
+	}
+	return c.wait()
+}
+
+// close compresses the remaining input, ends the stream
+// and stops the output goroutine.
+func (c *concurrentWriter) close(z *Writer) error {
+	c.start(z)
+	if err := c.setErr(nil); err == nil {
+		c.compress(z.level, true)
+	}
+	err := c.wait()
+	c.stop()
+	return err
+}
+
+// crc32Combine returns the CRC-32 of two concatenated inputs,
+// where crc1 is the checksum of the first and crc2 is the checksum of the second input,
+// which has length len2.
+// This is the crc32_combine function from zlib.
+func crc32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	var even, odd [32]uint32
+
+	// Put operator for one zero bit in odd.
+	odd[0] = crc32.IEEE
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	// Put operator for two zero bits in even.
+	gf2MatrixSquare(&even, &odd)
+	// Put operator for four zero bits in odd.
+	gf2MatrixSquare(&odd, &even)
+
+	// Apply len2 zeros to crc1.
+	// The first square will put the operator for one zero byte, eight zero bits, in even.
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i++ {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range square {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
@@ -82,6 +82,8 @@ type Reader struct {
 	buf          [512]byte
 	err          error
 	multistream  bool
+	idx          *indexBuilder
+	conc         *concurrentReader
 }
 
 // NewReader creates a new Reader reading the given reader.
@@ -107,6 +109,8 @@ func (z *Reader) Reset(r io.Reader) error {
 		decompressor: z.decompressor,
 		multistream:  true,
 		br:           z.br,
+		idx:          z.idx,
+		conc:         z.conc,
 	}
 	if rr, ok := r.(flate.Reader); ok {
 		z.r = rr
@@ -119,6 +123,13 @@ func (z *Reader) Reset(r io.Reader) error {
 		}
 		z.r = z.br
 	}
+	if z.idx != nil {
+		z.idx.reset(z)
+	}
+	if z.conc != nil {
+		z.conc.start(r)
+		z.r = z.conc
+	}
 	z.Header, z.err = z.readHeader()
 	return z.err
 }
@@ -181,7 +192,14 @@ func (z *Reader) readString() (string, error) {
 // readHeader reads the GZIP header according to section 2.3.1.
 // This method does not set z.err.
 func (z *Reader) readHeader() (hdr Header, err error) {
+	var start int64
+	if z.idx != nil {
+		start = z.idx.cr.n
+	}
 	if _, err = io.ReadFull(z.r, z.buf[:10]); err != nil {
+		if err == io.EOF && z.idx != nil {
+			z.idx.complete = true
+		}
 		// RFC 1952, section 2.2, says the following:
 		//	A gzip file consists of a series of "members" (compressed data sets).
 		//
@@ -244,6 +262,12 @@ func (z *Reader) readHeader() (hdr Header, err error) {
 	}
 
 	z.digest = 0
+	if z.idx != nil {
+		z.idx.memberStart(start)
+		// The callback is not retained by Reset.
+		z.decompressor = z.idx.decompressor()
+		return hdr, nil
+	}
 	if z.decompressor == nil {
 		z.decompressor = flate.NewReader(z.r)
 	} else {
@@ -259,6 +283,16 @@ func (z *Reader) Read(p []byte) (n int, err error) {
 	}
 
 	for n == 0 {
+		if z.conc != nil && z.conc.boundary {
+			if len(p) == 0 {
+				return 0, nil
+			}
+			n, z.err = z.conc.read(z, p)
+			if z.err != nil {
+				return n, z.err
+			}
+			continue
+		}
 		n, z.err = z.decompressor.Read(p)
 		z.digest = crc32.Update(z.digest, crc32.IEEETable, p[:n])
 		z.size += uint32(n)
@@ -279,6 +313,9 @@ func (z *Reader) Read(p []byte) (n int, err error) {
 			return n, z.err
 		}
 		z.digest, z.size = 0, 0
+		if z.idx != nil {
+			z.idx.memberEnd(z.multistream)
+		}
 
 		// File is ok; check if there is another.
 		if !z.multistream {
@@ -286,6 +323,9 @@ func (z *Reader) Read(p []byte) (n int, err error) {
 		}
 		z.err = nil // Remove io.EOF
 
+		if z.conc != nil && z.conc.atBoundary() {
+			continue
+		}
 		if _, z.err = z.readHeader(); z.err != nil {
 			return n, z.err
 		}
@@ -330,6 +370,13 @@ func (z *Reader) WriteTo(w io.Writer) (int64, error) {
 			}
 			return total, z.err
 		}
+		if z.conc != nil && z.conc.boundary {
+			n, err := z.conc.writeTo(z, w)
+			total += n
+			z.err = err
+			crcWriter.Reset()
+			continue
+		}
 
 		// We write both to output and digest.
 		mw := io.MultiWriter(w, crcWriter)
@@ -357,6 +404,9 @@ func (z *Reader) WriteTo(w io.Writer) (int64, error) {
 			return total, z.err
 		}
 		z.digest, z.size = 0, 0
+		if z.idx != nil {
+			z.idx.memberEnd(z.multistream)
+		}
 
 		// File is ok; check if there is another.
 		if !z.multistream {
@@ -365,6 +415,9 @@ func (z *Reader) WriteTo(w io.Writer) (int64, error) {
 		crcWriter.Reset()
 		z.err = nil // Remove io.EOF
 
+		if z.conc != nil && z.conc.atBoundary() {
+			continue
+		}
 		if _, z.err = z.readHeader(); z.err != nil {
 			if z.err == io.EOF {
 				return total, nil
@@ -377,4 +430,9 @@ func (z *Reader) WriteTo(w io.Writer) (int64, error) {
 // Close closes the Reader. It does not close the underlying io.Reader.
 // In order for the GZIP checksum to be verified, the reader must be
 // fully consumed until the io.EOF.
-func (z *Reader) Close() error { return z.decompressor.Close() }
+func (z *Reader) Close() error {
+	if z.conc != nil {
+		z.conc.stop()
+	}
+	return z.decompressor.Close()
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+const (
+	// concurrentSegmentSize is the minimum size of compressed segments decoded concurrently.
+	concurrentSegmentSize = 1 << 20
+	// concurrentMaxSegment is the maximum size of a segment, relative to the segment size.
+	// If no member starts before this, the member is decoded sequentially.
+	concurrentMaxSegment = 4
+	// concurrentMaxOutput is the maximum decompressed size of a segment, relative to the segment size.
+	// Segments decompressing to more are decoded sequentially.
+	concurrentMaxOutput = 32
+)
+
+var errSegmentTooLarge = errors.New("gzip: segment output too large")
+
+// NewReaderConcurrent creates a new Reader reading the given reader,
+// which decompresses gzip members concurrently.
+// Up to n segments of the input are decompressed concurrently.
+// If n <= 0, GOMAXPROCS is used.
+//
+// The input is split into segments of about 1MB at the start of gzip members,
+// which are located by searching for gzip headers, or using the block size
+// of BGZF files. Segments are decoded speculatively and
+// the output is only used if all members in the segment decode without errors
+// and the segment ends exactly where the last member ends.
+// Other input is decoded sequentially.
+// This means that files with a single member will be decoded sequentially,
+// while files written by parallel compressors or BGZF writers
+// can be decoded in parallel.
+// Output is returned in order and is identical to the output of NewReader.
+//
+// WriteTo should be used to read the output, since it avoids copying.
+// Memory usage can be up to 40MB per segment for very compressible input.
+// The Reader must be closed to stop the background goroutines.
+func NewReaderConcurrent(r io.Reader, n int) (*Reader, error) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return newReaderConcurrent(r, n, concurrentSegmentSize)
+}
+
+func newReaderConcurrent(r io.Reader, n, segSize int) (*Reader, error) {
+	z := &Reader{conc: &concurrentReader{n: n, segSize: segSize}}
+	if err := z.Reset(r); err != nil {
+		z.conc.stop()
+		return nil, err
+	}
+	return z, nil
+}
+
+// concurrentReader contains the state of a concurrent Reader.
+// It provides the input to the Reader when members are decoded sequentially.
+type concurrentReader struct {
+	n       int
+	segSize int
+	queue   chan *readSegment
+	stopC   chan struct{}
+
+	cur      []byte // Remaining input of the current segment.
+	out      []byte // Remaining output of a segment decoded concurrently.
+	boundary bool   // The input is at the start of a segment, which is the start of a member.
+	err      error
+
+	readers sync.Pool
+}
+
+// readSegment is a segment of the input.
+type readSegment struct {
+	in   []byte
+	out  []byte
+	ok   bool  // Set if out contains the decoded input.
+	err  error // Read error after the segment.
+	done chan struct{}
+}
+
+// start reading r.
+func (c *concurrentReader) start(r io.Reader) {
+	c.stop()
+	c.cur, c.out, c.boundary, c.err = nil, nil, false, nil
+	c.queue = make(chan *readSegment, c.n)
+	c.stopC = make(chan struct{})
+	go c.split(r, c.queue, c.stopC)
+}
+
+// stop the background goroutines.
+func (c *concurrentReader) stop() {
+	if c.queue == nil {
+		return
+	}
+	close(c.stopC)
+	for range c.queue {
+	}
+	c.queue = nil
+}
+
+// split the input into segments and queue them.
+// Segments starting with a possible member are decoded concurrently.
+func (c *concurrentReader) split(r io.Reader, queue chan<- *readSegment, stop <-chan struct{}) {
+	defer close(queue)
+	var buf []byte
+	var readErr error
+	// The first member is decoded sequentially to read the header,
+	// and segments following a segment that was cut do not start with a member.
+	decode := false
+	for {
+		end, cut := -1, false
+		for end < 0 {
+			if e := segmentEnd(buf, c.segSize); e > 0 {
+				end = e
+				break
+			}
+			if len(buf) >= c.segSize*concurrentMaxSegment {
+				end, cut = c.segSize*concurrentMaxSegment, true
+				break
+			}
+			if readErr != nil {
+				end = len(buf)
+				break
+			}
+			if cap(buf)-len(buf) < c.segSize/16 {
+				buf = append(buf, make([]byte, max(len(buf), c.segSize/4))...)[:len(buf)]
+			}
+			var n int
+			n, readErr = r.Read(buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+		}
+		seg := &readSegment{in: bytes.Clone(buf[:end]), done: make(chan struct{})}
+		if end == 0 {
+			if readErr == io.EOF {
+				return
+			}
+			seg.err = readErr
+		}
+		if decode && !cut && seg.err == nil {
+			go c.decode(seg)
+		} else {
+			close(seg.done)
+		}
+		select {
+		case queue <- seg:
+		case <-stop:
+			return
+		}
+		if seg.err != nil {
+			return
+		}
+		decode = !cut
+		buf = buf[:copy(buf, buf[end:])]
+	}
+}
+
+// segmentEnd returns the end of a segment of at least size bytes at the start of buf,
+// or 0 if no end can be found in buf.
+func segmentEnd(buf []byte, size int) int {
+	pos := 0
+	// Skip BGZF blocks.
+	for pos+18 <= len(buf) && pos < size {
+		b := buf[pos:]
+		if b[0] != gzipID1 || b[1] != gzipID2 || b[2] != gzipDeflate || b[3] != flagExtra ||
+			le.Uint16(b[10:]) != 6 || b[12] != 'B' || b[13] != 'C' || le.Uint16(b[14:]) != 2 {
+			break
+		}
+		pos += int(le.Uint16(b[16:])) + 1
+	}
+	if pos >= size {
+		if pos <= len(buf) {
+			return pos
+		}
+		return 0
+	}
+	// Search for a header.
+	for pos = max(pos, size); pos+4 <= len(buf); pos++ {
+		i := bytes.Index(buf[pos:], []byte{gzipID1, gzipID2, gzipDeflate})
+		if i < 0 {
+			return 0
+		}
+		pos += i
+		// Reserved flags must be zero.
+		if pos+4 <= len(buf) && buf[pos+3]>>5 == 0 {
+			return pos
+		}
+	}
+	return 0
+}
+
+// decode the segment.
+func (c *concurrentReader) decode(seg *readSegment) {
+	defer close(seg.done)
+	zr, ok := c.readers.Get().(*Reader)
+	if !ok {
+		zr = new(Reader)
+	}
+	defer c.readers.Put(zr)
+	src := bytes.NewReader(seg.in)
+	if zr.Reset(src) != nil {
+		return
+	}
+	dst := limitedBuffer{max: c.segSize * concurrentMaxOutput}
+	dst.Grow(min(len(seg.in)*4, dst.max))
+	if _, err := zr.WriteTo(&dst); err != nil || src.Len() != 0 {
+		return
+	}
+	seg.out = dst.Bytes()
+	seg.ok = true
+}
+
+// limitedBuffer is a bytes.Buffer that returns an error when exceeding max bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if l.Len()+len(p) > l.max {
+		return 0, errSegmentTooLarge
+	}
+	return l.Buffer.Write(p)
+}
+
+// pull the next segment from the queue.
+func (c *concurrentReader) pull() (*readSegment, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	seg, ok := <-c.queue
+	if !ok {
+		c.err = io.EOF
+		return nil, c.err
+	}
+	<-seg.done
+	if seg.err != nil {
+		c.err = seg.err
+		return nil, c.err
+	}
+	return seg, nil
+}
+
+// Read provides the input for sequential decoding.
+// Output of segments that are read is discarded.
+func (c *concurrentReader) Read(p []byte) (int, error) {
+	for len(c.cur) == 0 {
+		seg, err := c.pull()
+		if err != nil {
+			return 0, err
+		}
+		c.cur = seg.in
+	}
+	n := copy(p, c.cur)
+	c.cur = c.cur[n:]
+	return n, nil
+}
+
+// ReadByte provides the input for sequential decoding.
+func (c *concurrentReader) ReadByte() (byte, error) {
+	for len(c.cur) == 0 {
+		seg, err := c.pull()
+		if err != nil {
+			return 0, err
+		}
+		c.cur = seg.in
+	}
+	b := c.cur[0]
+	c.cur = c.cur[1:]
+	return b, nil
+}
+
+// nextOutput makes the output of the next segment available in c.out.
+// If the segment could not be decoded concurrently,
+// the header of the first member is read, and sequential decoding must continue.
+// It returns whether output is available.
+func (c *concurrentReader) nextOutput(z *Reader) (bool, error) {
+	for len(c.out) == 0 {
+		seg, err := c.pull()
+		if err != nil {
+			return false, err
+		}
+		if !seg.ok {
+			c.cur = seg.in
+			c.boundary = false
+			_, err := z.readHeader()
+			return false, err
+		}
+		c.out = seg.out
+	}
+	return true, nil
+}
+
+// read output at a segment boundary.
+func (c *concurrentReader) read(z *Reader, p []byte) (int, error) {
+	ok, err := c.nextOutput(z)
+	if !ok {
+		return 0, err
+	}
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// writeTo writes output at segment boundaries,
+// until a segment must be decoded sequentially.
+func (c *concurrentReader) writeTo(z *Reader, w io.Writer) (int64, error) {
+	var total int64
+	for {
+		ok, err := c.nextOutput(z)
+		if !ok {
+			return total, err
+		}
+		n, err := w.Write(c.out)
+		total += int64(n)
+		if err == nil && n != len(c.out) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return total, err
+		}
+		c.out = nil
+	}
+}
+
+// atBoundary is called when a member has ended.
+// It returns true if the next member starts a segment.
+func (c *concurrentReader) atBoundary() bool {
+	c.boundary = len(c.cur) == 0
+	return c.boundary
+}
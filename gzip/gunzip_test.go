@@ -10,6 +10,7 @@ import (
 	oldgz "compress/gzip"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -769,3 +770,131 @@ func TestBufferedPartialCopyGzip(t *testing.T) {
 		t.Errorf("reading the remainder: %v", err)
 	}
 }
+
+func TestReaderConcurrent(t *testing.T) {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat = bytes.Repeat(dat, 50)
+	// Stored data containing gzip headers, so false member starts are found.
+	fake := bytes.Repeat([]byte("\x1f\x8b\x08\x00 fake header"), 200000)
+
+	type testCase struct {
+		name       string
+		compressed []byte
+		want       []byte
+	}
+	var tests []testCase
+
+	members := func(name string, in []byte, size, level int, bgzf bool) {
+		var buf bytes.Buffer
+		for rem := in; len(rem) > 0; {
+			n := min(len(rem), size)
+			start := buf.Len()
+			w, _ := NewWriterLevel(&buf, level)
+			if bgzf {
+				w.Extra = []byte("BC\x02\x00\x00\x00")
+			}
+			w.Write(rem[:n])
+			w.Close()
+			if bgzf {
+				b := buf.Bytes()
+				le.PutUint16(b[start+16:], uint16(len(b)-start-1))
+			}
+			rem = rem[n:]
+		}
+		tests = append(tests, testCase{name: name, compressed: buf.Bytes(), want: in})
+	}
+	members("single", dat, len(dat), DefaultCompression, false)
+	members("members-100K", dat, 100<<10, DefaultCompression, false)
+	members("members-2M", dat, 2<<20, BestSpeed, false)
+	members("bgzf", dat, 0xff00, DefaultCompression, true)
+	members("fake-headers", fake, 500<<10, NoCompression, false)
+	members("fake-headers-single", fake, len(fake), NoCompression, false)
+	members("empty", nil, 1, DefaultCompression, false)
+	var both []byte
+	both = append(both, tests[1].compressed...)
+	both = append(both, tests[4].compressed...)
+	tests = append(tests, testCase{name: "mixed", compressed: both, want: append(bytes.Clone(dat), fake...)})
+
+	for _, test := range tests {
+		for _, n := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s-%d", test.name, n), func(t *testing.T) {
+				segSize := concurrentSegmentSize
+				if n > 1 {
+					// Use small segments to test boundaries.
+					segSize = 64 << 10
+				}
+				if len(test.want) == 0 {
+					test.compressed = append(test.compressed, test.compressed...)
+					var buf bytes.Buffer
+					w := NewWriter(&buf)
+					w.Close()
+					test.compressed = buf.Bytes()
+				}
+				z, err := newReaderConcurrent(bytes.NewReader(test.compressed), n, segSize)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer z.Close()
+				var got bytes.Buffer
+				if _, err := z.WriteTo(&got); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got.Bytes(), test.want) {
+					t.Fatalf("WriteTo: content mismatch, got %d bytes, want %d", got.Len(), len(test.want))
+				}
+
+				// Read in small pieces.
+				if err := z.Reset(bytes.NewReader(test.compressed)); err != nil {
+					t.Fatal(err)
+				}
+				got.Reset()
+				if _, err := io.CopyBuffer(&got, struct{ io.Reader }{z}, make([]byte, 1000)); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got.Bytes(), test.want) {
+					t.Fatalf("Read: content mismatch, got %d bytes, want %d", got.Len(), len(test.want))
+				}
+			})
+		}
+	}
+}
+
+func TestReaderConcurrentCorrupt(t *testing.T) {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat = bytes.Repeat(dat, 20)
+	var buf bytes.Buffer
+	for range 10 {
+		w := NewWriter(&buf)
+		w.Write(dat)
+		w.Close()
+	}
+	// Corrupt the CRC of the last member.
+	b := buf.Bytes()
+	b[len(b)-8]++
+	z, err := newReaderConcurrent(bytes.NewReader(b), 4, 64<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := z.WriteTo(io.Discard)
+	if err != ErrChecksum {
+		t.Fatalf("got %v, want %v", err, ErrChecksum)
+	}
+	if n != int64(len(dat)*10) {
+		t.Fatalf("got %d bytes, want %d", n, len(dat)*10)
+	}
+	z.Close()
+
+	// Close before reading everything.
+	z, err = NewReaderConcurrent(bytes.NewReader(b), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.Read(make([]byte, 100))
+	z.Close()
+}
@@ -23,6 +23,10 @@ const (
 	ConstantCompression = flate.ConstantCompression
 	HuffmanOnly         = flate.HuffmanOnly
 
+	// BestCompressionZopfli is the best, but very slow, compression level.
+	// See flate.BestCompressionZopfli.
+	BestCompressionZopfli = flate.BestCompressionZopfli
+
 	// StatelessCompression will do compression but without maintaining any state
 	// between Write calls.
 	// There will be no memory kept between Write calls,
@@ -39,11 +43,13 @@ type Writer struct {
 	level       int
 	err         error
 	compressor  *flate.Writer
+	rsyncable   bool
 	digest      uint32 // CRC-32, IEEE polynomial (section 8)
 	size        uint32 // Uncompressed size (section 2.3.1)
 	wroteHeader bool
 	closed      bool
 	buf         [10]byte
+	conc        *concurrentWriter // Set when compressing concurrently.
 }
 
 // NewWriter returns a new Writer.
@@ -63,10 +69,10 @@ func NewWriter(w io.Writer) *Writer {
 // of assuming DefaultCompression.
 //
 // The compression level can be DefaultCompression, NoCompression, or any
-// integer value between BestSpeed and BestCompression inclusive. The error
+// integer value between BestSpeed and BestCompressionZopfli inclusive. The error
 // returned will be nil if the level is valid.
 func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
-	if level < StatelessCompression || level > BestCompression {
+	if level < StatelessCompression || level > BestCompressionZopfli {
 		return nil, fmt.Errorf("gzip: invalid compression level: %d", level)
 	}
 	z := new(Writer)
@@ -74,6 +80,24 @@ func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
 	return z, nil
 }
 
+// NewWriterRsyncable returns a new Writer, which produces "rsyncable" output,
+// similar to gzip --rsyncable.
+// The compressor state is reset at content-defined boundaries,
+// so a local change in the input only changes a small part of the output.
+// The output is a standard gzip stream.
+// See flate.NewWriterRsyncable for details.
+//
+// The compression level can be DefaultCompression, NoCompression, HuffmanOnly
+// or any integer value between BestSpeed and BestCompressionZopfli inclusive.
+func NewWriterRsyncable(w io.Writer, level int) (*Writer, error) {
+	if level < HuffmanOnly || level > BestCompressionZopfli {
+		return nil, fmt.Errorf("gzip: invalid compression level: %d", level)
+	}
+	z := &Writer{rsyncable: true}
+	z.init(w, level)
+	return z, nil
+}
+
 // MinCustomWindowSize is the minimum window size that can be sent to NewWriterWindow.
 const MinCustomWindowSize = flate.MinCustomWindowSize
 
@@ -102,6 +126,11 @@ func (z *Writer) init(w io.Writer, level int) {
 			compressor.Reset(w)
 		}
 	}
+	conc := z.conc
+	if conc != nil {
+		conc.stop()
+	}
+	rsyncable := z.rsyncable
 
 	*z = Writer{
 		Header: Header{
@@ -110,6 +139,11 @@ func (z *Writer) init(w io.Writer, level int) {
 		w:          w,
 		level:      level,
 		compressor: compressor,
+		conc:       conc,
+		rsyncable:  rsyncable,
+	}
+	if conc != nil {
+		conc.reset()
 	}
 }
 
@@ -190,7 +224,7 @@ func (z *Writer) Write(p []byte) (int, error) {
 			z.buf[3] |= 0x10
 		}
 		le.PutUint32(z.buf[4:8], uint32(z.ModTime.Unix()))
-		if z.level == BestCompression {
+		if z.level >= BestCompression {
 			z.buf[8] = 2
 		} else if z.level == BestSpeed {
 			z.buf[8] = 4
@@ -221,10 +255,19 @@ func (z *Writer) Write(p []byte) (int, error) {
 			}
 		}
 
-		if z.compressor == nil && z.level != StatelessCompression {
-			z.compressor, _ = flate.NewWriter(z.w, z.level)
+		if z.compressor == nil && z.level != StatelessCompression && z.conc == nil {
+			if z.rsyncable {
+				z.compressor, _ = flate.NewWriterRsyncable(z.w, z.level)
+			} else {
+				z.compressor, _ = flate.NewWriter(z.w, z.level)
+			}
 		}
 	}
+	if z.conc != nil {
+		// Size and checksum are updated when blocks are written.
+		n, z.err = z.conc.write(z, p)
+		return n, z.err
+	}
 	z.size += uint32(len(p))
 	z.digest = crc32.Update(z.digest, crc32.IEEETable, p)
 	if z.level == StatelessCompression {
@@ -255,6 +298,10 @@ func (z *Writer) Flush() error {
 			return z.err
 		}
 	}
+	if z.conc != nil {
+		z.err = z.conc.flush(z)
+		return z.err
+	}
 	z.err = z.compressor.Flush()
 	return z.err
 }
@@ -277,6 +324,8 @@ func (z *Writer) Close() error {
 	}
 	if z.level == StatelessCompression {
 		z.err = flate.StatelessDeflate(z.w, nil, true, nil)
+	} else if z.conc != nil {
+		z.err = z.conc.close(z)
 	} else {
 		z.err = z.compressor.Close()
 	}
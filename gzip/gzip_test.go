@@ -8,6 +8,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/rand"
 	"os"
@@ -575,3 +576,190 @@ func BenchmarkCompressAllocationsSingle(b *testing.B) {
 		}
 	})
 }
+
+func TestWriterConcurrent(t *testing.T) {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat = bytes.Repeat(dat, 8)
+	for _, level := range []int{HuffmanOnly, NoCompression, BestSpeed, DefaultCompression, BestCompression} {
+		for _, blockSize := range []int{1 << 10, 100 << 10} {
+			for _, n := range []int{1, 4} {
+				t.Run(fmt.Sprintf("level-%d-bs-%d-n-%d", level, blockSize, n), func(t *testing.T) {
+					var buf bytes.Buffer
+					w, err := NewWriterConcurrent(&buf, level, blockSize, n)
+					if err != nil {
+						t.Fatal(err)
+					}
+					w.Name = "name"
+					w.Comment = "comment"
+					// Write in uneven pieces, with a flush in the middle.
+					rng := rand.New(rand.NewSource(int64(level)))
+					for in := dat; len(in) > 0; {
+						n := min(len(in), rng.Intn(50000))
+						if _, err := w.Write(in[:n]); err != nil {
+							t.Fatal(err)
+						}
+						in = in[n:]
+						if len(in) < len(dat)/2 && len(in)+n >= len(dat)/2 {
+							if err := w.Flush(); err != nil {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := w.Close(); err != nil {
+						t.Fatal(err)
+					}
+					r, err := NewReader(&buf)
+					if err != nil {
+						t.Fatal(err)
+					}
+					r.Multistream(false)
+					if r.Name != "name" || r.Comment != "comment" {
+						t.Errorf("header mismatch: %q, %q", r.Name, r.Comment)
+					}
+					got, err := io.ReadAll(r)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, dat) {
+						t.Fatal("decoded content does not match")
+					}
+					// Must be a single member.
+					if r.Reset(&buf) != io.EOF {
+						t.Fatal("expected a single gzip member")
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestWriterConcurrentReset(t *testing.T) {
+	var buf, buf2 bytes.Buffer
+	w, err := NewWriterConcurrent(&buf, BestSpeed, 1<<10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.conc.queue != nil {
+		t.Fatal("output goroutine started before first write")
+	}
+	msg := bytes.Repeat([]byte("hello world "), 1000)
+	// Reset without closing.
+	w.Write(msg)
+	w.Reset(&buf)
+	buf.Reset()
+	for _, dst := range []*bytes.Buffer{&buf, &buf2} {
+		w.Reset(dst)
+		if _, err := w.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Errorf("output after Reset does not match")
+	}
+	for _, b := range []*bytes.Buffer{&buf, &buf2} {
+		r, err := NewReader(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatal("decoded content does not match")
+		}
+	}
+
+	// Empty stream.
+	buf.Reset()
+	w.Reset(&buf)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("ReadAll = %d bytes, %v", len(got), err)
+	}
+}
+
+func TestCRC32Combine(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, size := range []int{0, 1, 7, 100, 65536, 100003} {
+		a := make([]byte, rng.Intn(1000))
+		b := make([]byte, size)
+		rng.Read(a)
+		rng.Read(b)
+		want := crc32.ChecksumIEEE(append(a, b...))
+		got := crc32Combine(crc32.ChecksumIEEE(a), crc32.ChecksumIEEE(b), int64(len(b)))
+		if got != want {
+			t.Errorf("size %d: got %08x, want %08x", size, got, want)
+		}
+	}
+}
+
+func TestWriterRsyncable(t *testing.T) {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat = bytes.Repeat(dat, 4)
+	if _, err := NewWriterRsyncable(io.Discard, BestCompressionZopfli+1); err == nil {
+		t.Fatal("expected error on invalid level")
+	}
+	var buf bytes.Buffer
+	w, err := NewWriterRsyncable(&buf, DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		buf.Reset()
+		w.Reset(&buf)
+		w.Name = name
+		if _, err := w.Write(dat); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, dat) || r.Name != name {
+			t.Fatal("content mismatch")
+		}
+	}
+}
+
+func BenchmarkWriterConcurrent(b *testing.B) {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	dat = bytes.Repeat(dat, 32)
+	w, err := NewWriterConcurrent(io.Discard, DefaultCompression, 0, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	for b.Loop() {
+		w.Reset(io.Discard)
+		w.Write(dat)
+		w.Close()
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"github.com/klauspost/compress/flate"
+)
+
+// DefaultIndexSpacing is the default distance between index points
+// in the uncompressed stream.
+const DefaultIndexSpacing = 1 << 20
+
+// indexMagic is the start of a serialized index, followed by the version.
+const (
+	indexMagic   = "gzidx"
+	indexVersion = 1
+)
+
+// maxWindowSize is the deflate window, the maximum history stored at an index point.
+const maxWindowSize = 32 << 10
+
+var (
+	errIndexCorrupt    = errors.New("gzip: corrupt index")
+	errIndexIncomplete = errors.New("gzip: index incomplete, stream not read to EOF")
+	errNegativeOffset  = errors.New("gzip: negative offset")
+)
+
+// Index contains points in a gzip stream, where decompression can be resumed.
+// This allows random access to existing gzip files,
+// without any changes to the files.
+//
+// An Index is built while reading a stream with a Reader created by NewIndexingReader.
+// Each point stores the 32KB of output preceding it, compressed.
+// Besides points inside deflate streams, the start of gzip members are used as points,
+// since they need no history.
+type Index struct {
+	// TotalUncompressed is the uncompressed size of the stream.
+	TotalUncompressed int64
+	// TotalCompressed is the compressed size of the stream.
+	TotalCompressed int64
+
+	points []indexPoint
+}
+
+type indexPoint struct {
+	compressed   int64
+	uncompressed int64
+	bitOffset    uint8
+	member       bool   // Point is the start of a gzip member.
+	windowLen    int    // Uncompressed size of the window.
+	window       []byte // Window compressed with deflate.
+}
+
+// Points returns the number of points in the index.
+func (i *Index) Points() int {
+	return len(i.points)
+}
+
+// find returns the last point at or before the uncompressed offset.
+// If no such point exists, nil is returned.
+func (i *Index) find(offset int64) *indexPoint {
+	n := sort.Search(len(i.points), func(n int) bool {
+		return i.points[n].uncompressed > offset
+	})
+	if n == 0 {
+		return nil
+	}
+	return &i.points[n-1]
+}
+
+// AppendTo appends the serialized index to b and returns the result.
+func (i *Index) AppendTo(b []byte) []byte {
+	start := len(b)
+	b = append(b, indexMagic...)
+	b = append(b, indexVersion)
+	b = binary.AppendUvarint(b, uint64(i.TotalUncompressed))
+	b = binary.AppendUvarint(b, uint64(i.TotalCompressed))
+	b = binary.AppendUvarint(b, uint64(len(i.points)))
+	var prev indexPoint
+	for _, p := range i.points {
+		b = binary.AppendUvarint(b, uint64(p.compressed-prev.compressed))
+		b = binary.AppendUvarint(b, uint64(p.uncompressed-prev.uncompressed))
+		flags := p.bitOffset
+		if p.member {
+			flags |= 1 << 3
+		}
+		b = append(b, flags)
+		if !p.member {
+			b = binary.AppendUvarint(b, uint64(p.windowLen))
+			b = binary.AppendUvarint(b, uint64(len(p.window)))
+			b = append(b, p.window...)
+		}
+		prev = p
+	}
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(b[start:]))
+}
+
+// Load a serialized index.
+// A zero value Index can be used or a previous one can be reused.
+// The remaining bytes after the index are returned.
+func (i *Index) Load(b []byte) ([]byte, error) {
+	in := b
+	if len(b) < len(indexMagic)+1 || string(b[:len(indexMagic)]) != indexMagic {
+		return b, errIndexCorrupt
+	}
+	if b[len(indexMagic)] != indexVersion {
+		return b, errIndexCorrupt
+	}
+	b = b[len(indexMagic)+1:]
+	readVar := func() int64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 || v > 1<<62 {
+			b = nil
+			return -1
+		}
+		b = b[n:]
+		return int64(v)
+	}
+	i.TotalUncompressed = readVar()
+	i.TotalCompressed = readVar()
+	n := readVar()
+	// Each point is at least 3 bytes.
+	if i.TotalUncompressed < 0 || i.TotalCompressed < 0 || n < 0 || n > int64(len(b)/3) {
+		return in, errIndexCorrupt
+	}
+	i.points = i.points[:0]
+	var prev indexPoint
+	for range n {
+		var p indexPoint
+		p.compressed = prev.compressed + readVar()
+		p.uncompressed = prev.uncompressed + readVar()
+		if len(b) < 1 || p.compressed < prev.compressed || p.uncompressed < prev.uncompressed {
+			return in, errIndexCorrupt
+		}
+		flags := b[0]
+		b = b[1:]
+		p.bitOffset = flags & 7
+		p.member = flags&(1<<3) != 0
+		if flags>>4 != 0 || p.member && p.bitOffset != 0 {
+			return in, errIndexCorrupt
+		}
+		if !p.member {
+			p.windowLen = int(readVar())
+			wLen := readVar()
+			if p.windowLen < 0 || p.windowLen > maxWindowSize || wLen < 0 || wLen > int64(len(b)) {
+				return in, errIndexCorrupt
+			}
+			p.window = b[:wLen:wLen]
+			b = b[wLen:]
+		}
+		if p.uncompressed > i.TotalUncompressed || p.compressed >= i.TotalCompressed {
+			return in, errIndexCorrupt
+		}
+		i.points = append(i.points, p)
+		prev = p
+	}
+	if len(b) < 4 {
+		return in, errIndexCorrupt
+	}
+	used := len(in) - len(b)
+	if binary.LittleEndian.Uint32(b) != crc32.ChecksumIEEE(in[:used]) {
+		return in, errIndexCorrupt
+	}
+	// Don't keep references to the input.
+	for j := range i.points {
+		i.points[j].window = bytes.Clone(i.points[j].window)
+	}
+	return b[4:], nil
+}
+
+// countReader counts the bytes read from the input.
+type countReader struct {
+	r flate.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// indexBuilder builds an index while a Reader is reading.
+type indexBuilder struct {
+	spacing  int64
+	idx      Index
+	cr       countReader
+	complete bool
+
+	base        int64 // Uncompressed offset of the current member.
+	deflateFrom int64 // Compressed offset of the current deflate stream.
+	last        int64 // Uncompressed offset of the last point.
+	fw          *flate.Writer
+	buf         bytes.Buffer
+}
+
+// NewIndexingReader creates a new Reader reading the given reader,
+// which builds an index while the stream is read.
+// The index can be retrieved with Reader.Index
+// when the stream has been read to the end.
+// Index points are added approximately every spacing bytes of uncompressed output.
+// If spacing is <= 0, DefaultIndexSpacing is used.
+// Each index point requires up to 32KB of memory, before compression.
+//
+// Since the input is read through a wrapper counting the compressed bytes,
+// decompression may be a bit slower than a regular Reader.
+// The index is reset by Reset.
+func NewIndexingReader(r io.Reader, spacing int64) (*Reader, error) {
+	if spacing <= 0 {
+		spacing = DefaultIndexSpacing
+	}
+	z := &Reader{idx: &indexBuilder{spacing: spacing}}
+	if err := z.Reset(r); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// Index returns the index built by a Reader created by NewIndexingReader.
+// The stream must have been read until io.EOF was returned,
+// otherwise an error is returned.
+func (z *Reader) Index() (*Index, error) {
+	if z.idx == nil || !z.idx.complete {
+		return nil, errIndexIncomplete
+	}
+	idx := z.idx.idx
+	return &idx, nil
+}
+
+// reset the builder and wrap the input of z.
+func (b *indexBuilder) reset(z *Reader) {
+	*b = indexBuilder{spacing: b.spacing, fw: b.fw, cr: countReader{r: z.r}}
+	z.r = &b.cr
+}
+
+// memberStart is called when the header of a member starting at
+// the compressed offset has been read.
+// A point is added, if needed.
+func (b *indexBuilder) memberStart(offset int64) {
+	if b.base > 0 && b.base-b.last >= b.spacing {
+		b.idx.points = append(b.idx.points, indexPoint{
+			compressed:   offset,
+			uncompressed: b.base,
+			member:       true,
+		})
+		b.last = b.base
+	}
+}
+
+// memberEnd is called when a member has been read, including the trailer.
+func (b *indexBuilder) memberEnd(multistream bool) {
+	b.idx.TotalCompressed = b.cr.n
+	b.idx.TotalUncompressed = b.base
+	if !multistream {
+		b.complete = true
+	}
+}
+
+// decompressor returns a decompressor for a deflate stream starting at the current position.
+func (b *indexBuilder) decompressor() io.ReadCloser {
+	b.deflateFrom = b.cr.n
+	return flate.NewReaderOpts(&b.cr, flate.WithEobCallback(b.checkpoint))
+}
+
+// checkpoint is called at the end of each deflate block.
+func (b *indexBuilder) checkpoint(cp flate.InflateCheckpoint) {
+	if cp.Final {
+		b.base += cp.UncompressedOffset
+		return
+	}
+	offset := b.base + cp.UncompressedOffset
+	if offset-b.last < b.spacing {
+		return
+	}
+	window := cp.Window[max(0, len(cp.Window)-maxWindowSize):]
+	if b.fw == nil {
+		b.fw, _ = flate.NewWriter(&b.buf, flate.DefaultCompression)
+	}
+	b.buf.Reset()
+	b.fw.Reset(&b.buf)
+	b.fw.Write(window)
+	b.fw.Close()
+	b.idx.points = append(b.idx.points, indexPoint{
+		compressed:   b.deflateFrom + cp.CompressedOffset,
+		uncompressed: offset,
+		bitOffset:    cp.BitOffset,
+		windowLen:    len(window),
+		window:       bytes.Clone(b.buf.Bytes()),
+	})
+	b.last = offset
+}
+
+// ReaderAt provides random access to a gzip stream using an Index.
+// Only output after an index point is decompressed,
+// so the cost of accessing data is bounded by the index spacing.
+//
+// Checksums are only verified for gzip members that are decompressed from the start.
+type ReaderAt struct {
+	r   io.ReaderAt
+	idx *Index
+
+	// State for Read and Seek.
+	pos int64
+	dec *indexDecoder
+}
+
+// NewReaderAt returns a ReaderAt reading the gzip stream in r,
+// using the supplied index.
+// The index must have been built from the same stream.
+// The returned ReaderAt implements io.ReaderAt and io.ReadSeeker.
+// ReadAt can be called concurrently, but Read and Seek cannot.
+func NewReaderAt(r io.ReaderAt, idx *Index) (*ReaderAt, error) {
+	if idx == nil || idx.TotalUncompressed < 0 || idx.TotalCompressed < 0 {
+		return nil, errIndexCorrupt
+	}
+	return &ReaderAt{r: r, idx: idx}, nil
+}
+
+// Size returns the uncompressed size of the stream.
+func (z *ReaderAt) Size() int64 {
+	return z.idx.TotalUncompressed
+}
+
+// ReadAt implements io.ReaderAt.
+func (z *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= z.idx.TotalUncompressed {
+		return 0, io.EOF
+	}
+	d, err := z.newDecoder(off)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(d, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Read implements io.Reader.
+func (z *ReaderAt) Read(p []byte) (int, error) {
+	if z.pos >= z.idx.TotalUncompressed {
+		return 0, io.EOF
+	}
+	// Reuse the current decoder, unless there is a point closer to the position.
+	if p := z.idx.find(z.pos); z.dec == nil || z.dec.pos > z.pos || p != nil && p.uncompressed > z.dec.pos {
+		d, err := z.newDecoder(z.pos)
+		if err != nil {
+			return 0, err
+		}
+		z.dec = d
+	} else if err := z.dec.skip(z.pos); err != nil {
+		return 0, err
+	}
+	n, err := z.dec.Read(p)
+	z.pos += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+// Seeking is done lazily on the following Read.
+func (z *ReaderAt) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += z.pos
+	case io.SeekEnd:
+		offset += z.idx.TotalUncompressed
+	default:
+		return 0, errors.New("gzip: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	z.pos = offset
+	return offset, nil
+}
+
+// newDecoder returns a decoder positioned at the uncompressed offset.
+func (z *ReaderAt) newDecoder(offset int64) (*indexDecoder, error) {
+	d := &indexDecoder{}
+	p := z.idx.find(offset)
+	var err error
+	switch {
+	case p == nil:
+		d.br = bufio.NewReader(io.NewSectionReader(z.r, 0, z.idx.TotalCompressed))
+		d.z, err = NewReader(d.br)
+	case p.member:
+		d.pos = p.uncompressed
+		d.br = bufio.NewReader(io.NewSectionReader(z.r, p.compressed, z.idx.TotalCompressed-p.compressed))
+		d.z, err = NewReader(d.br)
+	default:
+		d.pos = p.uncompressed
+		d.br = bufio.NewReader(io.NewSectionReader(z.r, p.compressed, z.idx.TotalCompressed-p.compressed))
+		var window []byte
+		window, err = io.ReadAll(flate.NewReader(bytes.NewReader(p.window)))
+		if err == nil && len(window) != p.windowLen {
+			err = errIndexCorrupt
+		}
+		if err == nil {
+			d.fr = flate.NewReaderOpts(d.br, flate.WithResumeFrom(flate.InflateCheckpoint{
+				CompressedOffset:   p.compressed,
+				UncompressedOffset: p.uncompressed,
+				BitOffset:          p.bitOffset,
+				Window:             window,
+			}))
+		}
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if err := d.skip(offset); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// indexDecoder decodes from an index point.
+type indexDecoder struct {
+	br  *bufio.Reader
+	fr  io.Reader // Set when decoding a partial member.
+	z   *Reader   // Set when decoding whole members.
+	pos int64     // Current uncompressed offset.
+}
+
+// skip forward to the uncompressed offset, which must be at or after the current position.
+func (d *indexDecoder) skip(offset int64) error {
+	if offset <= d.pos {
+		return nil
+	}
+	_, err := io.CopyN(io.Discard, d, offset-d.pos)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (d *indexDecoder) Read(p []byte) (int, error) {
+	for d.fr != nil {
+		n, err := d.fr.Read(p)
+		d.pos += int64(n)
+		if err != io.EOF {
+			return n, err
+		}
+		// Skip the trailer, since the checksum cannot be verified,
+		// and continue with the next member.
+		d.fr = nil
+		if _, err := d.br.Discard(8); err != nil {
+			return n, io.ErrUnexpectedEOF
+		}
+		d.z, err = NewReader(d.br)
+		if err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	if d.z == nil {
+		return 0, io.EOF
+	}
+	n, err := d.z.Read(p)
+	d.pos += int64(n)
+	return n, err
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gzip
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func testIndexInput(t testing.TB) []byte {
+	dat, err := os.ReadFile("testdata/test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mix compressible and incompressible data.
+	rng := rand.New(rand.NewSource(0))
+	var in []byte
+	for range 8 {
+		in = append(in, dat...)
+		rnd := make([]byte, rng.Intn(50000))
+		rng.Read(rnd)
+		in = append(in, rnd...)
+	}
+	return in
+}
+
+func testIndexReaderAt(t *testing.T, compressed, want []byte, idx *Index) {
+	t.Helper()
+	// Serialize and load the index.
+	b := idx.AppendTo([]byte("prefix"))
+	var loaded Index
+	rest, err := loaded.Load(b[len("prefix"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("%d bytes left after index", len(rest))
+	}
+	if loaded.Points() != idx.Points() || loaded.TotalUncompressed != int64(len(want)) || loaded.TotalCompressed != int64(len(compressed)) {
+		t.Fatalf("index mismatch: %d points, sizes %d/%d", loaded.Points(), loaded.TotalUncompressed, loaded.TotalCompressed)
+	}
+	t.Logf("%d points, index size %d bytes", loaded.Points(), len(b)-len("prefix"))
+
+	ra, err := NewReaderAt(bytes.NewReader(compressed), &loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.Size() != int64(len(want)) {
+		t.Fatalf("size %d, want %d", ra.Size(), len(want))
+	}
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		off := rng.Int63n(int64(len(want)))
+		buf := make([]byte, rng.Intn(100000))
+		n, err := ra.ReadAt(buf, off)
+		wantN := min(len(buf), len(want)-int(off))
+		if n != wantN {
+			t.Fatalf("ReadAt(%d, %d): got %d bytes, want %d (%v)", len(buf), off, n, wantN, err)
+		}
+		if n < len(buf) && err != io.EOF || n == len(buf) && err != nil {
+			t.Fatalf("ReadAt(%d, %d): unexpected error %v", len(buf), off, err)
+		}
+		if !bytes.Equal(buf[:n], want[off:off+int64(n)]) {
+			t.Fatalf("ReadAt(%d, %d): content mismatch", len(buf), off)
+		}
+
+		// Seek and read some.
+		if _, err := ra.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(io.LimitReader(ra, int64(len(buf))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, buf[:n]) {
+			t.Fatalf("Seek(%d) + Read(%d): content mismatch", off, len(buf))
+		}
+	}
+	// Read all, from the start.
+	ra.Seek(0, io.SeekStart)
+	got, err := io.ReadAll(ra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("ReadAll: content mismatch")
+	}
+	// Seek from end.
+	if _, err := ra.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	got, err = io.ReadAll(ra)
+	if err != nil || !bytes.Equal(got, want[len(want)-10:]) {
+		t.Fatalf("Seek from end: %v", err)
+	}
+	if n, err := ra.ReadAt(make([]byte, 10), int64(len(want))); n != 0 || err != io.EOF {
+		t.Fatalf("ReadAt after end: %d, %v", n, err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	in := testIndexInput(t)
+	for _, level := range []int{BestSpeed, DefaultCompression, HuffmanOnly} {
+		var buf bytes.Buffer
+		w, _ := NewWriterLevel(&buf, level)
+		w.Name = "name"
+		w.Write(in)
+		w.Close()
+		compressed := buf.Bytes()
+
+		for _, useWriteTo := range []bool{false, true} {
+			z, err := NewIndexingReader(bytes.NewReader(compressed), 64<<10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := z.Index(); err == nil {
+				t.Fatal("expected error on incomplete index")
+			}
+			var got []byte
+			if useWriteTo {
+				var out bytes.Buffer
+				_, err = z.WriteTo(&out)
+				got = out.Bytes()
+			} else {
+				got, err = io.ReadAll(struct{ io.Reader }{z})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, in) {
+				t.Fatal("decoded content mismatch")
+			}
+			idx, err := z.Index()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if idx.Points() < 2 {
+				t.Fatalf("level %d: got %d points", level, idx.Points())
+			}
+			testIndexReaderAt(t, compressed, in, idx)
+		}
+	}
+}
+
+func TestIndexMultiMember(t *testing.T) {
+	in := testIndexInput(t)
+	var buf bytes.Buffer
+	for rem := in; len(rem) > 0; {
+		n := min(len(rem), 10000)
+		w := NewWriter(&buf)
+		w.Write(rem[:n])
+		w.Close()
+		rem = rem[n:]
+	}
+	compressed := buf.Bytes()
+	z, err := NewIndexingReader(bytes.NewReader(compressed), 30000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Fatal("decoded content mismatch")
+	}
+	idx, err := z.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Points() < len(in)/30000-1 {
+		t.Fatalf("got %d points", idx.Points())
+	}
+	testIndexReaderAt(t, compressed, in, idx)
+
+	// Reset must reset the index.
+	w := NewWriter(&buf)
+	buf.Reset()
+	w.Write([]byte("hello"))
+	w.Close()
+	if err := z.Reset(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(z); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = z.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Points() != 0 || idx.TotalUncompressed != 5 {
+		t.Fatalf("got %d points, size %d after Reset", idx.Points(), idx.TotalUncompressed)
+	}
+}
+
+func TestIndexLoadCorrupt(t *testing.T) {
+	in := testIndexInput(t)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(in)
+	w.Close()
+	z, err := NewIndexingReader(&buf, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(io.Discard, z); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := z.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := idx.AppendTo(nil)
+	var loaded Index
+	for i := range b {
+		for _, v := range []byte{0, 1, 0xff} {
+			c := bytes.Clone(b)
+			if c[i] == v {
+				continue
+			}
+			c[i] = v
+			if _, err := loaded.Load(c); err == nil {
+				t.Fatalf("no error with byte %d set to %d", i, v)
+			}
+		}
+		if i > 200 {
+			break
+		}
+	}
+	if _, err := loaded.Load(b[:len(b)-1]); err == nil {
+		t.Fatal("no error on truncated index")
+	}
+}
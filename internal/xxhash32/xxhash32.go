@@ -0,0 +1,187 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xxhash32 implements the 32-bit variant of xxHash (XXH32) as described
+// at http://cyan4973.github.io/xxHash/.
+// This is the checksum used by the LZ4 frame format.
+package xxhash32
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime1 uint32 = 2654435761
+	prime2 uint32 = 2246822519
+	prime3 uint32 = 3266489917
+	prime4 uint32 = 668265263
+	prime5 uint32 = 374761393
+
+	// Initial values for v1 and v4 with a seed of 0.
+	// These are prime1 + prime2 and -prime1 modulo 2^32.
+	v1Init uint32 = 606290984
+	v4Init uint32 = 1640531535
+)
+
+// Size is the size of an XXH32 checksum in bytes.
+const Size = 4
+
+// BlockSize is the preferred size for writing to the Digest.
+const BlockSize = 16
+
+// Digest implements hash.Hash32.
+type Digest struct {
+	v1    uint32
+	v2    uint32
+	v3    uint32
+	v4    uint32
+	total uint64
+	mem   [16]byte
+	n     int // how much of mem is used
+}
+
+// New creates a new Digest that computes the 32-bit xxHash algorithm
+// with a seed of 0.
+func New() *Digest {
+	var d Digest
+	d.Reset()
+	return &d
+}
+
+// Reset clears the Digest's state so that it can be reused.
+func (d *Digest) Reset() {
+	d.v1 = v1Init
+	d.v2 = prime2
+	d.v3 = 0
+	d.v4 = v4Init
+	d.total = 0
+	d.n = 0
+}
+
+// Size always returns 4 bytes.
+func (d *Digest) Size() int { return Size }
+
+// BlockSize always returns 16 bytes.
+func (d *Digest) BlockSize() int { return BlockSize }
+
+// Write adds more data to d. It always returns len(b), nil.
+func (d *Digest) Write(b []byte) (n int, err error) {
+	n = len(b)
+	d.total += uint64(n)
+
+	if d.n+n < 16 {
+		// This new data doesn't even fill the current block.
+		copy(d.mem[d.n:], b)
+		d.n += n
+		return
+	}
+
+	if d.n > 0 {
+		// Finish off the partial block.
+		c := copy(d.mem[d.n:], b)
+		d.v1 = round(d.v1, binary.LittleEndian.Uint32(d.mem[0:4]))
+		d.v2 = round(d.v2, binary.LittleEndian.Uint32(d.mem[4:8]))
+		d.v3 = round(d.v3, binary.LittleEndian.Uint32(d.mem[8:12]))
+		d.v4 = round(d.v4, binary.LittleEndian.Uint32(d.mem[12:16]))
+		b = b[c:]
+		d.n = 0
+	}
+
+	if len(b) >= 16 {
+		// One or more full blocks left.
+		nw := d.writeBlocks(b)
+		b = b[nw:]
+	}
+
+	// Store any remaining partial block.
+	copy(d.mem[:], b)
+	d.n = len(b)
+	return
+}
+
+// writeBlocks consumes all full 16 byte blocks of b and returns the number of bytes consumed.
+func (d *Digest) writeBlocks(b []byte) int {
+	v1, v2, v3, v4 := d.v1, d.v2, d.v3, d.v4
+	n := len(b)
+	for len(b) >= 16 {
+		v1 = round(v1, binary.LittleEndian.Uint32(b[0:4]))
+		v2 = round(v2, binary.LittleEndian.Uint32(b[4:8]))
+		v3 = round(v3, binary.LittleEndian.Uint32(b[8:12]))
+		v4 = round(v4, binary.LittleEndian.Uint32(b[12:16]))
+		b = b[16:]
+	}
+	d.v1, d.v2, d.v3, d.v4 = v1, v2, v3, v4
+	return n - len(b)
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+// The hash is appended in big endian order, as hash.Hash32 requires.
+func (d *Digest) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint32(b, d.Sum32())
+}
+
+// Sum32 returns the current hash.
+func (d *Digest) Sum32() uint32 {
+	var h uint32
+	if d.total >= 16 {
+		h = bits.RotateLeft32(d.v1, 1) + bits.RotateLeft32(d.v2, 7) +
+			bits.RotateLeft32(d.v3, 12) + bits.RotateLeft32(d.v4, 18)
+	} else {
+		h = d.v3 + prime5
+	}
+	h += uint32(d.total)
+	return finalize(h, d.mem[:d.n])
+}
+
+// Checksum returns the XXH32 checksum of b with a seed of 0.
+func Checksum(b []byte) uint32 {
+	n := len(b)
+	var h uint32
+	if n >= 16 {
+		v1 := v1Init
+		v2 := prime2
+		v3 := uint32(0)
+		v4 := v4Init
+		for len(b) >= 16 {
+			v1 = round(v1, binary.LittleEndian.Uint32(b[0:4]))
+			v2 = round(v2, binary.LittleEndian.Uint32(b[4:8]))
+			v3 = round(v3, binary.LittleEndian.Uint32(b[8:12]))
+			v4 = round(v4, binary.LittleEndian.Uint32(b[12:16]))
+			b = b[16:]
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) +
+			bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = prime5
+	}
+	h += uint32(n)
+	return finalize(h, b)
+}
+
+// finalize mixes the remaining (less than 16) bytes in b into h
+// and applies the final avalanche.
+func finalize(h uint32, b []byte) uint32 {
+	for ; len(b) >= 4; b = b[4:] {
+		h += binary.LittleEndian.Uint32(b) * prime3
+		h = bits.RotateLeft32(h, 17) * prime4
+	}
+	for _, v := range b {
+		h += uint32(v) * prime5
+		h = bits.RotateLeft32(h, 11) * prime1
+	}
+	h ^= h >> 15
+	h *= prime2
+	h ^= h >> 13
+	h *= prime3
+	h ^= h >> 16
+	return h
+}
+
+func round(acc, input uint32) uint32 {
+	acc += input * prime2
+	acc = bits.RotateLeft32(acc, 13)
+	acc *= prime1
+	return acc
+}
@@ -0,0 +1,40 @@
+package xxhash32
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	var long strings.Builder
+	for i := range 1000 {
+		long.WriteByte(byte('A' + i%26))
+	}
+	for i, tt := range []struct {
+		input string
+		want  uint32
+	}{
+		{"", 0x02cc5d05},
+		{"a", 0x550d7456},
+		{"abc", 0x32d153ff},
+		{"Nobody inspects the spammish repetition", 0xe2293b2f},
+		{long.String(), 0x2e6d6091},
+	} {
+		if got := Checksum([]byte(tt.input)); got != tt.want {
+			t.Errorf("[%d] Checksum: got 0x%08x; want 0x%08x", i, got, tt.want)
+		}
+		// Write in uneven pieces to exercise buffering.
+		for _, split := range []int{1, 3, 15, 16, 17, 100} {
+			d := New()
+			in := []byte(tt.input)
+			for len(in) > 0 {
+				n := min(split, len(in))
+				d.Write(in[:n])
+				in = in[n:]
+			}
+			if got := d.Sum32(); got != tt.want {
+				t.Errorf("[%d] Digest (split %d): got 0x%08x; want 0x%08x", i, split, got, tt.want)
+			}
+		}
+	}
+}
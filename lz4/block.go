@@ -0,0 +1,109 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lz4
+
+import (
+	"encoding/binary"
+
+	"github.com/klauspost/compress/internal/lz4ref"
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+// encodeBlock compresses src into dst as a complete block,
+// including the block size and optional checksum.
+// If the block cannot be compressed it is stored.
+// dst must have a length of at least compressBoundBlock(len(src)).
+// src must not be empty.
+func encodeBlock(dst, src []byte, checksum bool) []byte {
+	// Only accept output that is smaller than the input.
+	n, err := lz4ref.CompressBlock(src, dst[4:4+len(src)-1])
+	size := uint32(n)
+	if err != nil || n == 0 {
+		n = copy(dst[4:], src)
+		size = uint32(n) | blockUncompressed
+	}
+	binary.LittleEndian.PutUint32(dst, size)
+	dst = dst[:4+n]
+	if checksum {
+		dst = binary.LittleEndian.AppendUint32(dst, xxhash32.Checksum(dst[4:]))
+	}
+	return dst
+}
+
+// decodeBlock decodes the LZ4 block src into dst, starting at d.
+// dst[:d] is used as history for matches.
+// Output is limited to the length of dst.
+// The new end of decoded data is returned.
+func decodeBlock(dst []byte, d int, src []byte) (int, error) {
+	s := 0
+	for {
+		if s >= len(src) {
+			return 0, ErrCorrupt
+		}
+		token := src[s]
+		s++
+
+		// Literals
+		ll := int(token >> 4)
+		if ll == 15 {
+			for {
+				if s >= len(src) {
+					return 0, ErrCorrupt
+				}
+				v := src[s]
+				s++
+				ll += int(v)
+				if v != 255 {
+					break
+				}
+			}
+		}
+		if ll > len(src)-s || ll > len(dst)-d {
+			return 0, ErrCorrupt
+		}
+		d += copy(dst[d:], src[s:s+ll])
+		s += ll
+		if s == len(src) {
+			// The last sequence contains only literals.
+			return d, nil
+		}
+
+		// Match
+		if s+2 > len(src) {
+			return 0, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[s:]))
+		s += 2
+		ml := int(token&15) + minMatch
+		if ml == 15+minMatch {
+			for {
+				if s >= len(src) {
+					return 0, ErrCorrupt
+				}
+				v := src[s]
+				s++
+				ml += int(v)
+				if v != 255 {
+					break
+				}
+			}
+		}
+		if offset == 0 || offset > d || ml > len(dst)-d {
+			return 0, ErrCorrupt
+		}
+		if offset >= ml {
+			d += copy(dst[d:d+ml], dst[d-offset:])
+			continue
+		}
+		// Overlapping copy must be done forwards.
+		a := dst[d : d+ml]
+		b := dst[d-offset:]
+		b = b[:len(a)]
+		for i := range a {
+			a[i] = b[i]
+		}
+		d += ml
+	}
+}
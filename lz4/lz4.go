@@ -0,0 +1,171 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lz4 implements reading and writing of the LZ4 frame format
+// as described at https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md
+//
+// The Writer compresses blocks independently, which allows blocks
+// to be compressed concurrently on multiple cores.
+// The Reader decodes both independent and linked blocks,
+// as well as concatenated and skippable frames.
+//
+// Content checksums are enabled by default and validated when reading.
+// Block checksums can optionally be added to each block.
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+const (
+	frameMagic         = 0x184D2204
+	frameMagicLegacy   = 0x184C2102
+	frameSkippableMask = 0xFFFFFFF0
+	frameSkippable     = 0x184D2A50
+
+	// Frame descriptor flags.
+	flagVersion         = 1 << 6
+	flagVersionMask     = 3 << 6
+	flagBlockIndep      = 1 << 5
+	flagBlockChecksum   = 1 << 4
+	flagContentSize     = 1 << 3
+	flagContentChecksum = 1 << 2
+	flagReserved        = 1 << 1
+	flagDictID          = 1 << 0
+
+	// blockUncompressed is set in the block size when the block is stored.
+	blockUncompressed = 1 << 31
+
+	// windowSize is the maximum match offset.
+	windowSize = 64 << 10
+
+	minMatch = 4
+
+	// maxBlockSize is the largest block size in the frame format.
+	maxBlockSize = 4 << 20
+
+	// Default block size
+	defaultBlockSize = maxBlockSize
+
+	// maxHeaderSize is the maximum size of a frame header,
+	// magic + flags + block descriptor + content size + dictionary ID + checksum.
+	maxHeaderSize = 4 + 1 + 1 + 8 + 4 + 1
+)
+
+var (
+	// ErrCorrupt reports that the input is invalid.
+	ErrCorrupt = errors.New("lz4: corrupt input")
+	// ErrChecksum reports that the input failed checksum validation.
+	ErrChecksum = errors.New("lz4: corrupt input, checksum mismatch")
+	// ErrUnsupported reports that the input isn't supported.
+	ErrUnsupported = errors.New("lz4: unsupported input")
+)
+
+// blockSizeID returns the block maximum size ID for size.
+// Returns 0 if the size isn't valid.
+func blockSizeID(size int) uint8 {
+	switch size {
+	case 64 << 10:
+		return 4
+	case 256 << 10:
+		return 5
+	case 1 << 20:
+		return 6
+	case 4 << 20:
+		return 7
+	}
+	return 0
+}
+
+// frameHeader describes a frame.
+type frameHeader struct {
+	blockIndep      bool
+	blockChecksum   bool
+	contentChecksum bool
+	hasContentSize  bool
+	blockMaxSize    int
+	contentSize     uint64
+}
+
+// appendTo appends the serialized header, including magic, to dst.
+func (f *frameHeader) appendTo(dst []byte) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, frameMagic)
+	start := len(dst)
+	flg := uint8(flagVersion)
+	if f.blockIndep {
+		flg |= flagBlockIndep
+	}
+	if f.blockChecksum {
+		flg |= flagBlockChecksum
+	}
+	if f.hasContentSize {
+		flg |= flagContentSize
+	}
+	if f.contentChecksum {
+		flg |= flagContentChecksum
+	}
+	dst = append(dst, flg, blockSizeID(f.blockMaxSize)<<4)
+	if f.hasContentSize {
+		dst = binary.LittleEndian.AppendUint64(dst, f.contentSize)
+	}
+	return append(dst, uint8(xxhash32.Checksum(dst[start:])>>8))
+}
+
+// parse the frame descriptor following the magic.
+// b must contain the complete descriptor, including the header checksum.
+// Use descriptorSize to determine the size.
+func (f *frameHeader) parse(b []byte) error {
+	flg, bd := b[0], b[1]
+	if flg&flagVersionMask != flagVersion {
+		return ErrUnsupported
+	}
+	if flg&flagReserved != 0 || bd&0x8f != 0 {
+		return ErrCorrupt
+	}
+	if flg&flagDictID != 0 {
+		// Dictionaries are not supported.
+		return ErrUnsupported
+	}
+	f.blockIndep = flg&flagBlockIndep != 0
+	f.blockChecksum = flg&flagBlockChecksum != 0
+	f.contentChecksum = flg&flagContentChecksum != 0
+	f.hasContentSize = flg&flagContentSize != 0
+	id := bd >> 4
+	if id < 4 {
+		return ErrCorrupt
+	}
+	f.blockMaxSize = 1 << (8 + 2*id)
+	n := 2
+	f.contentSize = 0
+	if f.hasContentSize {
+		f.contentSize = binary.LittleEndian.Uint64(b[n:])
+		n += 8
+	}
+	if b[n] != uint8(xxhash32.Checksum(b[:n])>>8) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// descriptorSize returns the size of the frame descriptor
+// following the magic, based on the flag byte.
+func descriptorSize(flg uint8) int {
+	n := 3
+	if flg&flagContentSize != 0 {
+		n += 8
+	}
+	if flg&flagDictID != 0 {
+		n += 4
+	}
+	return n
+}
+
+// compressBoundBlock returns the maximum size of a block of n bytes
+// including block header and checksum.
+func compressBoundBlock(n int) int {
+	return 4 + n + 4
+}
@@ -0,0 +1,307 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lz4
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func testSource(t testing.TB) []byte {
+	f, err := os.Open("testdata/source.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testInputs(t testing.TB) map[string][]byte {
+	rng := rand.New(rand.NewSource(1))
+	src := testSource(t)
+	random := make([]byte, 300<<10)
+	rng.Read(random)
+	var mixed []byte
+	for len(mixed) < 5<<20 {
+		switch rng.Intn(3) {
+		case 0:
+			mixed = append(mixed, random[:rng.Intn(1000)]...)
+		case 1:
+			mixed = append(mixed, src[:rng.Intn(len(src))]...)
+		case 2:
+			mixed = append(mixed, bytes.Repeat([]byte{byte(rng.Intn(3))}, rng.Intn(1000))...)
+		}
+	}
+	return map[string][]byte{
+		"empty":  {},
+		"short":  []byte("abcdefgh"),
+		"source": src,
+		"random": random,
+		"mixed":  mixed,
+	}
+}
+
+func TestWriterRoundtrip(t *testing.T) {
+	opts := map[string][]WriterOption{
+		"default":     nil,
+		"c1":          {WriterConcurrency(1)},
+		"64k":         {WriterBlockSize(64 << 10)},
+		"256k-c1":     {WriterBlockSize(256 << 10), WriterConcurrency(1)},
+		"blockcrc":    {WriterBlockChecksum(), WriterBlockSize(1 << 20)},
+		"nocrc":       {WriterContentChecksum(false)},
+		"nocrc-64k-2": {WriterContentChecksum(false), WriterBlockSize(64 << 10), WriterConcurrency(2)},
+	}
+	for name, data := range testInputs(t) {
+		for optName, opt := range opts {
+			t.Run(name+"-"+optName, func(t *testing.T) {
+				var buf bytes.Buffer
+				w := NewWriter(&buf, append(opt, WriterContentSize(int64(len(data))))...)
+				// Write in uneven pieces.
+				for in := data; len(in) > 0; {
+					n := min(len(in), 100000)
+					if _, err := w.Write(in[:n]); err != nil {
+						t.Fatal(err)
+					}
+					in = in[n:]
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("%d -> %d bytes", len(data), buf.Len())
+				got, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatal("output mismatch")
+				}
+
+				// Reuse with ReadFrom and WriteTo.
+				buf.Reset()
+				w.Reset(&buf)
+				if _, err := w.ReadFrom(bytes.NewReader(data)); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				var out bytes.Buffer
+				r := NewReader(nil)
+				r.Reset(&buf)
+				n, err := r.WriteTo(&out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+					t.Fatal("output mismatch")
+				}
+			})
+		}
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	data := testSource(t)
+	for _, c := range []int{1, 4} {
+		t.Run(fmt.Sprint("c", c), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, WriterConcurrency(c), WriterBlockSize(64<<10))
+			r := NewReader(&buf)
+			for in := data; len(in) > 0; {
+				n := min(len(in), 5000)
+				if _, err := w.Write(in[:n]); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Flush(); err != nil {
+					t.Fatal(err)
+				}
+				// All flushed content must be readable.
+				got := make([]byte, n)
+				if _, err := io.ReadFull(r, got); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, in[:n]) {
+					t.Fatal("output mismatch")
+				}
+				in = in[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+				t.Fatalf("want EOF, got %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestWriterContentSizeMismatch(t *testing.T) {
+	w := NewWriter(io.Discard, WriterContentSize(10))
+	w.Write([]byte("abc"))
+	if err := w.Close(); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestReaderFiles(t *testing.T) {
+	// Files created with the lz4 command line tool.
+	want := testSource(t)
+	for _, file := range []string{"linked-64k.lz4", "indep-blockcrc-size.lz4", "linked-hc-nocrc.lz4"} {
+		t.Run(file, func(t *testing.T) {
+			b, err := os.ReadFile("testdata/" + file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(NewReader(bytes.NewReader(b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatal("output mismatch")
+			}
+			_, err = io.ReadAll(NewReader(bytes.NewReader(b), ReaderMaxBlockSize(64<<10)))
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReaderConcatSkippable(t *testing.T) {
+	src := testSource(t)
+	var buf bytes.Buffer
+	var want []byte
+	for i := 0; i < 3; i++ {
+		// Skippable frame.
+		buf.Write(binary.LittleEndian.AppendUint32(nil, frameSkippable+uint32(i)))
+		buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(i*10)))
+		buf.Write(make([]byte, i*10))
+
+		w := NewWriter(&buf, WriterBlockSize(64<<10))
+		w.Write(src[i*1000:])
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, src[i*1000:]...)
+	}
+	linked, err := os.ReadFile("testdata/linked-64k.lz4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(linked)
+	want = append(want, src...)
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("output mismatch")
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	src := testSource(t)
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WriterBlockSize(64<<10), WriterBlockChecksum(), WriterContentSize(int64(len(src))))
+	w.Write(src)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+
+	// Truncated streams.
+	for i := 1; i < len(stream); i += 1 + i/8 {
+		_, err := io.ReadAll(NewReader(bytes.NewReader(stream[:i])))
+		if err == nil {
+			t.Fatalf("truncated at %d: no error", i)
+		}
+	}
+
+	// Modified streams.
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		corrupt := bytes.Clone(stream)
+		corrupt[rng.Intn(len(corrupt))] ^= 1 << rng.Intn(8)
+		_, err := io.ReadAll(NewReader(bytes.NewReader(corrupt)))
+		if err == nil {
+			t.Fatalf("corruption %d: no error", i)
+		}
+		// Blocks are still decoded safely without checksums.
+		io.ReadAll(NewReader(bytes.NewReader(corrupt), ReaderIgnoreChecksum()))
+	}
+
+	// The last 4 bytes are the content checksum.
+	corrupt := bytes.Clone(stream)
+	corrupt[len(corrupt)-1]++
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(corrupt))); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("want ErrChecksum, got %v", err)
+	}
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(corrupt), ReaderIgnoreChecksum())); err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames with larger blocks than allowed.
+	buf.Reset()
+	w = NewWriter(&buf, WriterBlockSize(256<<10))
+	w.Write(src)
+	w.Close()
+	if _, err := io.ReadAll(NewReader(&buf, ReaderMaxBlockSize(64<<10))); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("want ErrUnsupported, got %v", err)
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	data := testInputs(b)["mixed"]
+	for _, c := range []int{1, 4} {
+		b.Run(fmt.Sprint("c", c), func(b *testing.B) {
+			w := NewWriter(io.Discard, WriterConcurrency(c))
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w.Reset(io.Discard)
+				w.Write(data)
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	data := testInputs(b)["mixed"]
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	compressed := buf.Bytes()
+	r := NewReader(nil)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(bytes.NewReader(compressed))
+		if _, err := r.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+// NewReader returns a new Reader that decompresses from r,
+// using the LZ4 frame format.
+// Concatenated frames are decoded as a single stream
+// and skippable frames are ignored.
+func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
+	nr := Reader{
+		r:        r,
+		maxBlock: maxBlockSize,
+	}
+	for _, opt := range opts {
+		if err := opt(&nr); err != nil {
+			nr.err = err
+			return &nr
+		}
+	}
+	return &nr
+}
+
+// ReaderOption is an option for creating a decoder.
+type ReaderOption func(*Reader) error
+
+// ReaderMaxBlockSize allows to control the maximum block size of frames.
+// Frames declaring a larger block size will return an error.
+// This limits the memory used by the Reader.
+// Valid sizes are 64KB, 256KB, 1MB and 4MB. Default is 4MB.
+func ReaderMaxBlockSize(blockSize int) ReaderOption {
+	return func(r *Reader) error {
+		if blockSizeID(blockSize) == 0 {
+			return errors.New("lz4: block size must be 64KB, 256KB, 1MB or 4MB")
+		}
+		r.maxBlock = blockSize
+		return nil
+	}
+}
+
+// ReaderIgnoreChecksum will make the reader skip checksum checks.
+// Header checksums are still validated.
+func ReaderIgnoreChecksum() ReaderOption {
+	return func(r *Reader) error {
+		r.ignoreChecksum = true
+		return nil
+	}
+}
+
+// Reader is an io.Reader that can read LZ4 frames.
+type Reader struct {
+	r   io.Reader
+	err error
+
+	// buf contains decoded data.
+	// For linked blocks the previous 64KB is kept as history before decoded data.
+	buf []byte
+	// in is the buffer for compressed blocks.
+	in []byte
+	// buf[i:j] contains decoded bytes that have not yet been passed on.
+	i, j int
+
+	hdr      frameHeader
+	inFrame  bool
+	xxh      xxhash32.Digest
+	read     uint64 // Content bytes decoded in current frame.
+	maxBlock int
+	tmp      [maxHeaderSize]byte
+
+	ignoreChecksum bool
+}
+
+// Reset discards any buffered data, resets all state, and switches the
+// reader to read from r.
+// This permits reusing a Reader rather than allocating a new one.
+func (r *Reader) Reset(reader io.Reader) {
+	r.r = reader
+	r.err = nil
+	r.i = 0
+	r.j = 0
+	r.inFrame = false
+}
+
+// Read satisfies the io.Reader interface.
+func (r *Reader) Read(p []byte) (int, error) {
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.i < r.j {
+			n := copy(p, r.buf[r.i:r.j])
+			r.i += n
+			return n, nil
+		}
+		r.err = r.nextBlock()
+	}
+}
+
+// WriteTo writes data to w until there's no more data to write or
+// when an error occurs. The return value n is the number of bytes
+// written. Any error encountered during the write is also returned.
+func (r *Reader) WriteTo(w io.Writer) (n int64, err error) {
+	for {
+		if r.err != nil {
+			if r.err == io.EOF {
+				return n, nil
+			}
+			return n, r.err
+		}
+		if r.i < r.j {
+			n2, err := w.Write(r.buf[r.i:r.j])
+			n += int64(n2)
+			r.i += n2
+			if err == nil && r.i != r.j {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				r.err = err
+				return n, err
+			}
+		}
+		r.err = r.nextBlock()
+	}
+}
+
+// readFull reads len(p) bytes from the input.
+// Any EOF is reported as ErrCorrupt unless allowEOF is set,
+// in which case io.EOF is returned if no bytes could be read.
+func (r *Reader) readFull(p []byte, allowEOF bool) error {
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		if err == io.ErrUnexpectedEOF || (err == io.EOF && !allowEOF) {
+			return ErrCorrupt
+		}
+		return err
+	}
+	return nil
+}
+
+// readFrameHeader reads the next frame header.
+// Skippable frames are skipped.
+// io.EOF is returned if there are no more frames.
+func (r *Reader) readFrameHeader() error {
+	for {
+		if err := r.readFull(r.tmp[:4], true); err != nil {
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(r.tmp[:])
+		switch {
+		case magic == frameMagic:
+		case magic&frameSkippableMask == frameSkippable:
+			if err := r.readFull(r.tmp[:4], false); err != nil {
+				return err
+			}
+			size := int64(binary.LittleEndian.Uint32(r.tmp[:]))
+			if n, err := io.CopyN(io.Discard, r.r, size); err != nil {
+				if n < size {
+					return ErrCorrupt
+				}
+				return err
+			}
+			continue
+		case magic == frameMagicLegacy:
+			return fmt.Errorf("%w: legacy frame format", ErrUnsupported)
+		default:
+			return ErrCorrupt
+		}
+		// Read flags to determine the descriptor size.
+		desc := r.tmp[:1]
+		if err := r.readFull(desc, false); err != nil {
+			return err
+		}
+		desc = r.tmp[:descriptorSize(desc[0])]
+		if err := r.readFull(desc[1:], false); err != nil {
+			return err
+		}
+		if err := r.hdr.parse(desc); err != nil {
+			return err
+		}
+		if r.hdr.blockMaxSize > r.maxBlock {
+			return fmt.Errorf("%w: block size %d exceeds maximum %d", ErrUnsupported, r.hdr.blockMaxSize, r.maxBlock)
+		}
+		// Blocks are decoded after the history for linked blocks.
+		bufSize := r.hdr.blockMaxSize
+		if !r.hdr.blockIndep {
+			bufSize += windowSize
+		}
+		if cap(r.buf) < bufSize {
+			r.buf = make([]byte, bufSize)
+		}
+		r.buf = r.buf[:bufSize]
+		r.i, r.j = 0, 0
+		r.read = 0
+		r.xxh.Reset()
+		r.inFrame = true
+		return nil
+	}
+}
+
+// endFrame reads the frame trailer and validates the frame.
+func (r *Reader) endFrame() error {
+	r.inFrame = false
+	if r.hdr.contentChecksum {
+		if err := r.readFull(r.tmp[:4], false); err != nil {
+			return err
+		}
+		if !r.ignoreChecksum && binary.LittleEndian.Uint32(r.tmp[:]) != r.xxh.Sum32() {
+			return ErrChecksum
+		}
+	}
+	if r.hdr.hasContentSize && r.hdr.contentSize != r.read {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// nextBlock decodes the next block into r.buf.
+// io.EOF is returned when there is no more input.
+func (r *Reader) nextBlock() error {
+	if !r.inFrame {
+		return r.readFrameHeader()
+	}
+	if err := r.readFull(r.tmp[:4], false); err != nil {
+		return err
+	}
+	bSize := binary.LittleEndian.Uint32(r.tmp[:])
+	if bSize == 0 {
+		return r.endFrame()
+	}
+	size := int(bSize &^ blockUncompressed)
+	if size > r.hdr.blockMaxSize {
+		return ErrCorrupt
+	}
+	inSize := size
+	if r.hdr.blockChecksum {
+		inSize += 4
+	}
+	if cap(r.in) < inSize {
+		r.in = make([]byte, compressBoundBlock(r.hdr.blockMaxSize))
+	}
+	in := r.in[:inSize]
+	if err := r.readFull(in, false); err != nil {
+		return err
+	}
+	if r.hdr.blockChecksum {
+		in = in[:size]
+		if !r.ignoreChecksum && binary.LittleEndian.Uint32(r.in[size:]) != xxhash32.Checksum(in) {
+			return ErrChecksum
+		}
+	}
+
+	// Determine where to decode.
+	d := 0
+	if !r.hdr.blockIndep {
+		d = r.j
+		if d+r.hdr.blockMaxSize > len(r.buf) {
+			// Move the history to the front.
+			d = copy(r.buf, r.buf[d-windowSize:d])
+		}
+	}
+	dst := r.buf[:d+r.hdr.blockMaxSize]
+	end := d
+	if bSize&blockUncompressed != 0 {
+		end += copy(dst[d:], in)
+	} else {
+		var err error
+		end, err = decodeBlock(dst, d, in)
+		if err != nil {
+			return err
+		}
+	}
+	if r.hdr.contentChecksum && !r.ignoreChecksum {
+		r.xxh.Write(r.buf[d:end])
+	}
+	r.read += uint64(end - d)
+	r.i, r.j = d, end
+	return nil
+}
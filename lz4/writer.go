@@ -0,0 +1,416 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+// NewWriter returns a new Writer that compresses to w,
+// using the LZ4 frame format.
+//
+// Users must call Close to guarantee all data has been forwarded to
+// the underlying io.Writer and that resources are released.
+// They may also call Flush zero or more times before calling Close.
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	w2 := Writer{
+		blockSize:       defaultBlockSize,
+		concurrency:     runtime.GOMAXPROCS(0),
+		contentChecksum: true,
+		contentSize:     -1,
+	}
+	for _, opt := range opts {
+		if err := opt(&w2); err != nil {
+			w2.errState = err
+			return &w2
+		}
+	}
+	w2.obufLen = compressBoundBlock(w2.blockSize)
+	w2.paramsOK = true
+	w2.ibuf = make([]byte, 0, w2.blockSize)
+	w2.buffers.New = func() any {
+		return make([]byte, w2.obufLen)
+	}
+	w2.Reset(w)
+	return &w2
+}
+
+// Writer is an io.Writer that writes LZ4 frames.
+type Writer struct {
+	errMu    sync.Mutex
+	errState error
+
+	// ibuf is a buffer for the incoming (uncompressed) bytes.
+	ibuf []byte
+
+	blockSize     int
+	obufLen       int
+	concurrency   int
+	written       int64
+	uncompWritten int64 // Bytes sent to compression
+	contentSize   int64 // Declared content size, or -1 if not set.
+	output        chan chan result
+	buffers       sync.Pool
+	xxh           xxhash32.Digest
+
+	writer   io.Writer
+	writerWg sync.WaitGroup
+
+	// wroteHeader is whether we have written the frame header.
+	wroteHeader     bool
+	paramsOK        bool
+	blockChecksum   bool
+	contentChecksum bool
+}
+
+type result struct {
+	b []byte
+}
+
+// err returns the previously set error.
+// If no error has been set it is set to err if not nil.
+func (w *Writer) err(err error) error {
+	w.errMu.Lock()
+	errSet := w.errState
+	if errSet == nil && err != nil {
+		w.errState = err
+		errSet = err
+	}
+	w.errMu.Unlock()
+	return errSet
+}
+
+// Reset discards the writer's state and switches the writer to write to w.
+// This permits reusing a Writer rather than allocating a new one.
+func (w *Writer) Reset(writer io.Writer) {
+	if !w.paramsOK {
+		return
+	}
+	// Close previous writer, if any.
+	if w.output != nil {
+		close(w.output)
+		w.writerWg.Wait()
+		w.output = nil
+	}
+	w.errState = nil
+	w.ibuf = w.ibuf[:0]
+	w.wroteHeader = false
+	w.written = 0
+	w.writer = writer
+	w.uncompWritten = 0
+	w.xxh.Reset()
+
+	// If we didn't get a writer, stop here.
+	if writer == nil {
+		return
+	}
+	// If no concurrency requested, don't spin up writer goroutine.
+	if w.concurrency == 1 {
+		return
+	}
+
+	toWrite := make(chan chan result, w.concurrency)
+	w.output = toWrite
+	w.writerWg.Add(1)
+
+	// Start a writer goroutine that will write all output in order.
+	go func() {
+		defer w.writerWg.Done()
+
+		// Get a queued write.
+		for write := range toWrite {
+			// Wait for the data to be available.
+			input := <-write
+			in := input.b
+			if len(in) > 0 {
+				if w.err(nil) == nil {
+					// Don't expose data from previous buffers.
+					toWrite := in[:len(in):len(in)]
+					// Write to output.
+					n, err := writer.Write(toWrite)
+					if err == nil && n != len(toWrite) {
+						err = io.ErrShortWrite
+					}
+					_ = w.err(err)
+					w.written += int64(n)
+				}
+			}
+			if cap(in) >= w.obufLen {
+				w.buffers.Put(in)
+			}
+			// close the incoming write request.
+			// This can be used for synchronizing flushes.
+			close(write)
+		}
+	}()
+}
+
+// Write satisfies the io.Writer interface.
+func (w *Writer) Write(p []byte) (nRet int, errRet error) {
+	if err := w.err(nil); err != nil {
+		return 0, err
+	}
+	// If we exceed the input buffer size, start writing
+	for len(p) > (cap(w.ibuf)-len(w.ibuf)) && w.err(nil) == nil {
+		var n int
+		if len(w.ibuf) == 0 {
+			// Large write, empty buffer.
+			// Write directly from p to avoid copy.
+			n = len(p) - len(p)%w.blockSize
+			w.write(p[:n])
+		} else {
+			n = copy(w.ibuf[len(w.ibuf):cap(w.ibuf)], p)
+			w.ibuf = w.ibuf[:len(w.ibuf)+n]
+			w.write(w.ibuf)
+			w.ibuf = w.ibuf[:0]
+		}
+		nRet += n
+		p = p[n:]
+	}
+	if err := w.err(nil); err != nil {
+		return nRet, err
+	}
+	// p should always be able to fit into w.ibuf now.
+	n := copy(w.ibuf[len(w.ibuf):cap(w.ibuf)], p)
+	w.ibuf = w.ibuf[:len(w.ibuf)+n]
+	nRet += n
+	return nRet, nil
+}
+
+// ReadFrom implements the io.ReaderFrom interface.
+// ReadFrom reads data from r until EOF or error.
+// The return value n is the number of bytes read.
+// Any error except io.EOF encountered during the read is also returned.
+func (w *Writer) ReadFrom(r io.Reader) (n int64, err error) {
+	if err := w.err(nil); err != nil {
+		return 0, err
+	}
+	for {
+		n2, err := io.ReadFull(r, w.ibuf[len(w.ibuf):cap(w.ibuf)])
+		w.ibuf = w.ibuf[:len(w.ibuf)+n2]
+		n += int64(n2)
+		if len(w.ibuf) == cap(w.ibuf) {
+			w.write(w.ibuf)
+			w.ibuf = w.ibuf[:0]
+		}
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return n, w.err(nil)
+			}
+			return n, w.err(err)
+		}
+		if w.err(nil) != nil {
+			return n, w.err(nil)
+		}
+	}
+}
+
+// writeHeader writes the frame header if it hasn't been written.
+// Must be called from the goroutine calling Write.
+func (w *Writer) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	hdr := frameHeader{
+		blockIndep:      true,
+		blockChecksum:   w.blockChecksum,
+		contentChecksum: w.contentChecksum,
+		hasContentSize:  w.contentSize >= 0,
+		blockMaxSize:    w.blockSize,
+	}
+	if hdr.hasContentSize {
+		hdr.contentSize = uint64(w.contentSize)
+	}
+	return w.writeOut(hdr.appendTo(make([]byte, 0, maxHeaderSize)))
+}
+
+// writeOut writes b in order with compressed blocks.
+// b is not returned to the buffer pool.
+func (w *Writer) writeOut(b []byte) error {
+	if w.output == nil {
+		n, err := w.writer.Write(b)
+		if err == nil && n != len(b) {
+			err = io.ErrShortWrite
+		}
+		w.written += int64(n)
+		return w.err(err)
+	}
+	res := make(chan result, 1)
+	w.output <- res
+	res <- result{b: b}
+	return w.err(nil)
+}
+
+// write p as one or more complete blocks.
+func (w *Writer) write(p []byte) {
+	if w.writeHeader() != nil {
+		return
+	}
+	if w.contentChecksum {
+		w.xxh.Write(p)
+	}
+	w.uncompWritten += int64(len(p))
+	for len(p) > 0 && w.err(nil) == nil {
+		var uncompressed []byte
+		if len(p) > w.blockSize {
+			uncompressed, p = p[:w.blockSize], p[w.blockSize:]
+		} else {
+			uncompressed, p = p, nil
+		}
+		obuf := w.buffers.Get().([]byte)[:w.obufLen]
+		if w.output == nil {
+			block := encodeBlock(obuf, uncompressed, w.blockChecksum)
+			n, err := w.writer.Write(block)
+			if err == nil && n != len(block) {
+				err = io.ErrShortWrite
+			}
+			w.written += int64(n)
+			w.buffers.Put(obuf)
+			w.err(err)
+			continue
+		}
+
+		// Copy input, since the caller may reuse it.
+		inbuf := w.buffers.Get().([]byte)[:len(uncompressed)]
+		copy(inbuf, uncompressed)
+
+		output := make(chan result)
+		// Queue output now, so we keep order.
+		w.output <- output
+		go func() {
+			block := encodeBlock(obuf, inbuf, w.blockChecksum)
+			output <- result{b: block}
+			// Put unused buffer back in pool.
+			w.buffers.Put(inbuf[:cap(inbuf)])
+		}()
+	}
+}
+
+// Flush flushes the Writer to its underlying io.Writer.
+// Any buffered data is written as a block.
+func (w *Writer) Flush() error {
+	if err := w.err(nil); err != nil {
+		return err
+	}
+	if len(w.ibuf) > 0 {
+		w.write(w.ibuf)
+		w.ibuf = w.ibuf[:0]
+	}
+	if w.output == nil {
+		return w.err(nil)
+	}
+
+	// Send empty buffer
+	res := make(chan result)
+	w.output <- res
+	// Block until this has been picked up.
+	res <- result{b: nil}
+	// When it is closed, we have flushed.
+	<-res
+	return w.err(nil)
+}
+
+// Close calls Flush, writes the end of the frame and then closes the Writer.
+// Calling Close multiple times is ok.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.output != nil {
+		close(w.output)
+		w.writerWg.Wait()
+		w.output = nil
+	}
+	if w.err(err) == nil && w.writer != nil {
+		if w.contentSize >= 0 && w.contentSize != w.uncompWritten {
+			w.err(fmt.Errorf("lz4: declared content size %d, but %d bytes written", w.contentSize, w.uncompWritten))
+		}
+		w.err(w.writeHeader())
+		if w.err(nil) == nil {
+			// End mark and optional content checksum.
+			end := make([]byte, 4, 8)
+			if w.contentChecksum {
+				end = binary.LittleEndian.AppendUint32(end, w.xxh.Sum32())
+			}
+			w.err(w.writeOut(end))
+		}
+	}
+	err = w.err(errClosed)
+	if err == errClosed {
+		return nil
+	}
+	return err
+}
+
+var errClosed = errors.New("lz4: Writer is closed")
+
+// WriterOption is an option for creating a encoder.
+type WriterOption func(*Writer) error
+
+// WriterConcurrency will set the concurrency,
+// meaning the maximum number of blocks to compress concurrently.
+// The value supplied must be at least 1.
+// By default this will be set to GOMAXPROCS.
+func WriterConcurrency(n int) WriterOption {
+	return func(w *Writer) error {
+		if n <= 0 {
+			return errors.New("concurrency must be at least 1")
+		}
+		w.concurrency = n
+		return nil
+	}
+}
+
+// WriterBlockSize allows to override the default block size.
+// Valid sizes are 64KB, 256KB, 1MB and 4MB.
+//
+// Bigger blocks will increase compression slightly, but it will limit
+// the possible concurrency for smaller payloads.
+// Default block size is 4MB.
+func WriterBlockSize(n int) WriterOption {
+	return func(w *Writer) error {
+		if blockSizeID(n) == 0 {
+			return errors.New("lz4: block size must be 64KB, 256KB, 1MB or 4MB")
+		}
+		w.blockSize = n
+		return nil
+	}
+}
+
+// WriterBlockChecksum will add a checksum to each block.
+func WriterBlockChecksum() WriterOption {
+	return func(w *Writer) error {
+		w.blockChecksum = true
+		return nil
+	}
+}
+
+// WriterContentChecksum allows to disable the checksum of the
+// complete content, which is enabled by default.
+func WriterContentChecksum(enabled bool) WriterOption {
+	return func(w *Writer) error {
+		w.contentChecksum = enabled
+		return nil
+	}
+}
+
+// WriterContentSize will store the uncompressed size of the content
+// in the frame header.
+// Close will return an error if the written content doesn't match the size.
+func WriterContentSize(size int64) WriterOption {
+	return func(w *Writer) error {
+		if size < 0 {
+			return errors.New("lz4: content size must be >= 0")
+		}
+		w.contentSize = size
+		return nil
+	}
+}
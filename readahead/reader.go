@@ -9,10 +9,13 @@
 // The readahead object also fulfills the io.WriterTo interface, which
 // is likely to speed up copies.
 //
-// Package home: https://github.com/klauspost/readahead
+// Read-ahead can be stopped by canceling the context supplied to
+// NewContextReaderSize. Errors returned by the input are returned
+// once all data read before the error has been consumed.
 package readahead
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -30,12 +33,15 @@ type seekable struct {
 	*reader
 }
 
+// ReadSeekCloser is the interface returned by the read-ahead readers
+// when the input supports io.Seeker.
 type ReadSeekCloser interface {
 	io.ReadCloser
 	io.Seeker
 }
 
 type reader struct {
+	ctx     context.Context
 	in      io.Reader     // Input reader
 	closer  io.Closer     // Optional closer
 	ready   chan *buffer  // Buffers ready to be handed to the reader
@@ -150,6 +156,37 @@ func NewReaderSize(rd io.Reader, buffers, size int) (res io.ReadCloser, err erro
 	return
 }
 
+// NewContextReaderSize returns a reader with a custom number of buffers and size.
+// buffers is the number of queued buffers and size is the size of each
+// buffer in bytes.
+//
+// When the context is canceled reading ahead will stop, and once the
+// current buffer has been consumed reads will return the context error.
+// A Read on the input that is in progress when the context is canceled
+// will not be interrupted.
+func NewContextReaderSize(ctx context.Context, rd io.Reader, buffers, size int) (res io.ReadCloser, err error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("nil context supplied")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("buffer size too small")
+	}
+	if buffers <= 0 {
+		return nil, fmt.Errorf("number of buffers too small")
+	}
+	if rd == nil {
+		return nil, fmt.Errorf("nil input reader supplied")
+	}
+	a := &reader{ctx: ctx}
+	if _, ok := rd.(io.Seeker); ok {
+		res = &seekable{a}
+	} else {
+		res = a
+	}
+	a.init(rd, buffers, size)
+	return
+}
+
 // NewReaderBuffer returns a reader with a custom number of buffers and size.
 // All buffers must be the same size.
 // Buffers can be reused after Close has been called.
@@ -300,6 +337,10 @@ func (a *reader) initBuffers(rd io.Reader, buffers [][]byte, size int) {
 	a.cur = nil
 	a.err = nil
 	a.bufs = buffers
+	if a.ctx == nil {
+		a.ctx = context.Background()
+	}
+	done := a.ctx.Done()
 
 	// Create buffers
 	for _, buf := range buffers {
@@ -336,6 +377,8 @@ func (a *reader) initBuffers(rd io.Reader, buffers [][]byte, size int) {
 				}
 			case <-a.exit:
 				return
+			case <-done:
+				return
 			}
 		}
 	}()
@@ -349,8 +392,16 @@ func (a *reader) fill() (err error) {
 			a.reuse <- a.cur
 			a.cur = nil
 		}
-		b, ok := <-a.ready
+		var b *buffer
+		var ok bool
+		select {
+		case b, ok = <-a.ready:
+		case <-a.ctx.Done():
+		}
 		if !ok {
+			if a.err == nil {
+				a.err = a.ctx.Err()
+			}
 			if a.err == nil {
 				a.err = errors.New("readahead: read after Close")
 			}
@@ -401,13 +452,15 @@ func (a *seekable) Seek(offset int64, whence int) (res int64, err error) {
 	if whence == io.SeekCurrent {
 		//If need to seek based on current position, take into consideration the bytes we read but the consumer
 		//doesn't know about
-		err = nil
-		for a.cur != nil {
-			if err = a.fill(); err == nil && a.cur != nil {
-				offset -= int64(len(a.cur.buffer()))
-				a.cur.offset = len(a.cur.buf)
+		//This includes buffers that have been read ahead, but not yet been served.
+		for {
+			if err = a.fill(); err != nil || a.cur == nil {
+				break
 			}
+			offset -= int64(len(a.cur.buffer()))
+			a.cur.offset = len(a.cur.buf)
 		}
+		err = nil
 	}
 	//Seek the actual Seeker
 	if res, err = seeker.Seek(offset, whence); err == nil {
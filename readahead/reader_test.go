@@ -0,0 +1,258 @@
+// Copyright (c) 2015 Klaus Post, released under MIT License. See LICENSE file.
+
+package readahead
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func testData(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(b)
+	return b
+}
+
+func TestReader(t *testing.T) {
+	data := testData(1 << 20)
+	for _, buffers := range []int{1, 2, 4} {
+		for _, size := range []int{1, 100, 4096, 1 << 20, 2 << 20} {
+			t.Run(fmt.Sprintf("buffers=%d-size=%d", buffers, size), func(t *testing.T) {
+				// Half reads to test partial reads from input.
+				ra, err := NewReaderSize(iotest.HalfReader(bytes.NewBuffer(data)), buffers, size)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(ra)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatal("output mismatch")
+				}
+				if n, err := ra.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+					t.Fatalf("want EOF, got %d, %v", n, err)
+				}
+				if err := ra.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				ra, err = NewReaderSize(bytes.NewBuffer(data), buffers, size)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var dst bytes.Buffer
+				n, err := io.Copy(&dst, ra)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != int64(len(data)) || !bytes.Equal(dst.Bytes(), data) {
+					t.Fatal("output mismatch")
+				}
+				ra.Close()
+			})
+		}
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	data := testData(100000)
+	errTest := errors.New("test error")
+	for _, size := range []int{100, 4096, 1 << 20} {
+		t.Run(fmt.Sprint("size=", size), func(t *testing.T) {
+			// Error is returned with the last data.
+			in := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(errTest))
+			ra, err := NewReaderSize(in, 4, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(ra)
+			if err != errTest {
+				t.Fatalf("want %v, got %v", errTest, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("output mismatch, got %d bytes", len(got))
+			}
+			// Errors are sticky.
+			if _, err := ra.Read(make([]byte, 10)); err != errTest {
+				t.Fatalf("want %v, got %v", errTest, err)
+			}
+
+			// Error is returned with the data by the reader.
+			in = iotest.DataErrReader(io.MultiReader(bytes.NewReader(data), iotest.ErrReader(errTest)))
+			ra, err = NewReaderSize(in, 4, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dst bytes.Buffer
+			n, err := ra.(io.WriterTo).WriteTo(&dst)
+			if err != errTest {
+				t.Fatalf("want %v, got %v", errTest, err)
+			}
+			if n != int64(len(data)) || !bytes.Equal(dst.Bytes(), data) {
+				t.Fatalf("output mismatch, got %d bytes", n)
+			}
+		})
+	}
+}
+
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) {
+	panic("test panic")
+}
+
+func TestReaderPanic(t *testing.T) {
+	ra, err := NewReaderSize(panicReader{}, 2, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(ra)
+	if err == nil || !strings.Contains(err.Error(), "test panic") {
+		t.Fatalf("want panic error, got %v", err)
+	}
+}
+
+// blockingReader blocks reads until block is closed.
+type blockingReader struct {
+	block chan struct{}
+}
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b.block
+	return len(p), nil
+}
+
+func TestReaderContext(t *testing.T) {
+	in := blockingReader{block: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	ra, err := NewContextReaderSize(ctx, in, 2, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read some data.
+	close(in.block)
+	buf := make([]byte, 1000)
+	if _, err := io.ReadFull(ra, buf); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	// Data read ahead may still be returned.
+	var n int64
+	for {
+		n2, err := ra.Read(buf)
+		n += int64(n2)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("want context.Canceled, got %v", err)
+			}
+			break
+		}
+		if n > 100000 {
+			t.Fatal("reading did not stop")
+		}
+	}
+	if err := ra.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancel while waiting for input.
+	in = blockingReader{block: make(chan struct{})}
+	ctx, cancel = context.WithCancel(context.Background())
+	ra, err = NewContextReaderSize(ctx, in, 2, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := ra.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	if _, err := ra.(io.WriterTo).WriteTo(io.Discard); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	// Release the input read.
+	close(in.block)
+	ra.Close()
+}
+
+func TestReadSeeker(t *testing.T) {
+	data := testData(100000)
+	ra, err := NewReadSeekerSize(bytes.NewReader(data), 4, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ra.Close()
+	// Position must not include data read ahead.
+	if pos, err := ra.Seek(0, io.SeekCurrent); err != nil || pos != 0 {
+		t.Fatalf("want position 0, got %d, %v", pos, err)
+	}
+	buf := make([]byte, 1500)
+	if _, err := io.ReadFull(ra, buf); err != nil {
+		t.Fatal(err)
+	}
+	// Seek relative to consumed data.
+	pos, err := ra.Seek(500, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 2000 {
+		t.Fatalf("want position 2000, got %d", pos)
+	}
+	if _, err := io.ReadFull(ra, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data[2000:3500]) {
+		t.Fatal("output mismatch")
+	}
+	if _, err := ra.Seek(-100, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(ra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data[len(data)-100:]) {
+		t.Fatal("output mismatch")
+	}
+}
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestReadCloser(t *testing.T) {
+	in := &closeCounter{Reader: bytes.NewReader(testData(1000))}
+	ra := NewReadCloser(in)
+	if _, err := io.ReadAll(ra); err != nil {
+		t.Fatal(err)
+	}
+	ra.Close()
+	ra.Close()
+	if in.closed != 1 {
+		t.Fatalf("want 1 close, got %d", in.closed)
+	}
+
+	// Reading after close must fail.
+	ra, err := NewReaderSize(bytes.NewReader(testData(10000)), 2, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra.Close()
+	if _, err := io.ReadAll(ra); err == nil {
+		t.Fatal("want error after close")
+	}
+}
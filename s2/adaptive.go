@@ -0,0 +1,111 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// WriterAdaptive will select how each block is encoded,
+// based on an estimate of how well the block compresses.
+// Blocks that are not expected to compress are stored uncompressed,
+// and more effort is spent on blocks that are expected to compress well.
+// This is useful for mixed content, where for example already compressed
+// images are stored next to JSON, since no time is spent trying to
+// compress incompressible blocks with a slow encoder.
+//
+// The effort controls the trade-off between throughput and compression ratio,
+// and must be between 0 and 1 (inclusive).
+// With 0 the fastest encoder is used for all compressible blocks,
+// and with 1 the best encoder is used for all compressible blocks.
+// Between these, blocks with an estimated size reduction of at least
+// 1-effort use EncodeBetter, and blocks with an estimated size reduction
+// of at least 1-effort² use EncodeBest.
+// 0.5 is a reasonable trade-off.
+//
+// The estimate is roughly the cost of compressing the block with Encode.
+// Any level set by other options is ignored.
+// A custom encoder set with WriterCustomEncoder takes precedence.
+// Use Writer.AdaptiveStats to get the choices made.
+func WriterAdaptive(effort float64) WriterOption {
+	return func(w *Writer) error {
+		if !(effort >= 0 && effort <= 1) {
+			return errors.New("s2: adaptive effort must be between 0 and 1")
+		}
+		w.adaptive = &adaptiveState{
+			better: 1 - effort,
+			best:   1 - effort*effort,
+		}
+		return nil
+	}
+}
+
+// AdaptiveStats contains the choices made when using WriterAdaptive.
+type AdaptiveStats struct {
+	// Number of blocks stored uncompressed or compressed with each encoder.
+	Uncompressed, Fast, Better, Best int64
+
+	// Uncompressed bytes in the blocks above.
+	UncompressedBytes, FastBytes, BetterBytes, BestBytes int64
+}
+
+type adaptiveState struct {
+	// Minimum estimated size reduction to use each level.
+	better, best float64
+
+	// Blocks and bytes, indexed by level.
+	blocks, bytes [levelBest + 1]atomic.Int64
+}
+
+// AdaptiveStats returns the number of blocks encoded with each encoder
+// since the Writer was created or last Reset.
+// Blocks that are still being compressed may not be included.
+// If WriterAdaptive isn't used, zero values are returned.
+func (w *Writer) AdaptiveStats() AdaptiveStats {
+	a := w.adaptive
+	if a == nil {
+		return AdaptiveStats{}
+	}
+	return AdaptiveStats{
+		Uncompressed:      a.blocks[levelUncompressed].Load(),
+		Fast:              a.blocks[levelFast].Load(),
+		Better:            a.blocks[levelBetter].Load(),
+		Best:              a.blocks[levelBest].Load(),
+		UncompressedBytes: a.bytes[levelUncompressed].Load(),
+		FastBytes:         a.bytes[levelFast].Load(),
+		BetterBytes:       a.bytes[levelBetter].Load(),
+		BestBytes:         a.bytes[levelBest].Load(),
+	}
+}
+
+// reset the statistics.
+func (a *adaptiveState) reset() {
+	for i := range a.blocks {
+		a.blocks[i].Store(0)
+		a.bytes[i].Store(0)
+	}
+}
+
+// level returns the level to use for the block and records the choice.
+// EstimateBlockSize is used rather than compress.Estimate,
+// since S2 gains come from matches and not from entropy coding.
+func (a *adaptiveState) level(block []byte) uint8 {
+	level := uint8(levelUncompressed)
+	if est := EstimateBlockSize(block); est > 0 {
+		saved := 1 - float64(est)/float64(len(block))
+		switch {
+		case saved >= a.best:
+			level = levelBest
+		case saved >= a.better:
+			level = levelBetter
+		default:
+			level = levelFast
+		}
+	}
+	a.blocks[level].Add(1)
+	a.bytes[level].Add(int64(len(block)))
+	return level
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Input formats detected by ReaderAutoDetect.
+const (
+	formatFramed = iota
+	formatXerial
+	formatHadoop
+)
+
+// xerialHeader is the magic of xerial framed snappy streams,
+// followed by 8 bytes of version information.
+const xerialHeader = "\x82SNAPPY\x00"
+
+// ReaderAutoDetect will detect the format of the input when reading starts.
+// In addition to S2 and Snappy streams, the following formats are decoded:
+//
+//   - xerial framed Snappy, as used by Kafka and snappy-java.
+//     Blocks are prefixed with a 4 byte big endian length.
+//   - Hadoop block compressed Snappy, as written by the Hadoop SnappyCodec.
+//     Each block has a 4 byte big endian uncompressed length,
+//     followed by one or more compressed chunks, each with a 4 byte big endian length.
+//
+// Hadoop streams have no signature, so they are detected by checking
+// that the first block header is plausible.
+// Input that is not detected is decoded as an S2/Snappy stream.
+// The decoded size of blocks is limited by ReaderMaxBlockSize.
+// Seeking and indexes are only supported for S2/Snappy streams,
+// and blocks in other formats have no CRC.
+func ReaderAutoDetect() ReaderOption {
+	return func(r *Reader) error {
+		r.autoDetect = true
+		return nil
+	}
+}
+
+// detect the input format.
+// The input is left at the start of the stream.
+func (r *Reader) detect() bool {
+	r.detected = true
+	var hdr [len(xerialHeader) + 8]byte
+	n, err := io.ReadFull(r.r, hdr[:])
+	switch err {
+	case nil, io.ErrUnexpectedEOF, io.EOF:
+	default:
+		r.err = err
+		return false
+	}
+	b := hdr[:n]
+	switch {
+	case bytes.HasPrefix(b, []byte(xerialHeader)):
+		r.format = formatXerial
+		if n < len(hdr) {
+			r.err = ErrCorrupt
+			return false
+		}
+		// Skip the header.
+		r.stats.compressed.Add(int64(n))
+		r.readHeader = true
+		return true
+	case n >= 9 && b[0] != chunkTypeStreamIdentifier && !r.ignoreStreamID:
+		// Hadoop block: Uncompressed and compressed size, followed by a block.
+		uLen := binary.BigEndian.Uint32(b)
+		cLen := binary.BigEndian.Uint32(b[4:])
+		dLen, _, err := decodedLen(b[8:])
+		if err == nil && uLen > 0 && uLen <= uint32(r.maxBlock) && cLen > 0 && int64(cLen) <= int64(r.maxBufSize) && dLen > 0 && uint32(dLen) <= uLen {
+			r.format = formatHadoop
+			r.readHeader = true
+		}
+	}
+
+	// Put back the bytes read.
+	if rs, ok := r.r.(io.Seeker); ok {
+		if _, err := rs.Seek(-int64(n), io.SeekCurrent); err == nil {
+			return true
+		}
+	}
+	r.r = io.MultiReader(bytes.NewReader(b), r.r)
+	return true
+}
+
+// readFormatBlock reads the next block of a xerial or Hadoop stream into r.decoded.
+func (r *Reader) readFormatBlock() bool {
+	var tmp [4]byte
+	if !r.readFull(tmp[:], true) {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(tmp[:]))
+	if r.format == formatXerial {
+		// A single block.
+		if !r.ensureBufferSize(size) {
+			return false
+		}
+		buf := r.buf[:size]
+		if !r.readFull(buf, false) {
+			return false
+		}
+		n, err := DecodedLen(buf)
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if n > r.maxBlock {
+			r.err = ErrCorrupt
+			return false
+		}
+		if n > len(r.decoded) {
+			r.decoded = make([]byte, n)
+		}
+		if _, err := Decode(r.decoded[:n], buf); err != nil {
+			r.err = err
+			return false
+		}
+		r.stats.addBlock(n, false)
+		r.blockStart += int64(r.j)
+		r.i, r.j = 0, n
+		return true
+	}
+
+	// Hadoop: size is the uncompressed size of all chunks in the block.
+	if size > r.maxBlock {
+		r.err = ErrCorrupt
+		return false
+	}
+	if size > len(r.decoded) {
+		r.decoded = make([]byte, size)
+	}
+	for dst := r.decoded[:size]; len(dst) > 0; {
+		if !r.readFull(tmp[:], false) {
+			return false
+		}
+		cLen := int(binary.BigEndian.Uint32(tmp[:]))
+		if !r.ensureBufferSize(cLen) {
+			return false
+		}
+		buf := r.buf[:cLen]
+		if !r.readFull(buf, false) {
+			return false
+		}
+		n, err := DecodedLen(buf)
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if n > len(dst) {
+			r.err = ErrCorrupt
+			return false
+		}
+		if _, err := Decode(dst[:n], buf); err != nil {
+			r.err = err
+			return false
+		}
+		dst = dst[n:]
+	}
+	r.stats.addBlock(size, false)
+	r.blockStart += int64(r.j)
+	r.i, r.j = 0, size
+	return true
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// BlockReader decodes a single S2 or Snappy block incrementally,
+// reading the block from an io.Reader.
+// Unlike Decode, the decoded size of the block is not allocated up front.
+// Instead, a sliding window of previous output is kept,
+// so memory usage is bounded by the window size.
+// If a copy references data before the window, decoding fails
+// with an error wrapping ErrUnsupported.
+type BlockReader struct {
+	br     *bufio.Reader
+	window int
+	err    error
+
+	// buf[rd:pos] contains decoded bytes that have not yet been returned.
+	buf     []byte
+	rd, pos int
+
+	// Decoding state.
+	readHeader bool
+	produced   int // Bytes decoded so far.
+	left       int // Bytes not yet decoded by operations.
+	litLeft    int // Literal bytes remaining in the current operation.
+	copyLeft   int // Copy bytes remaining in the current operation.
+	offset     int
+}
+
+// NewBlockReader returns a BlockReader reading a single block from r.
+// The window is the maximum copy offset that can be decoded.
+// Blocks in S2 and Snappy streams never use offsets above 4MB.
+// If the window is <= 0, the full size of the block is used as window.
+// Memory usage is up to twice the window size, but not more than the block size.
+func NewBlockReader(r io.Reader, window int) *BlockReader {
+	b := &BlockReader{window: window}
+	b.Reset(r)
+	return b
+}
+
+// Reset discards the reader state and starts reading a new block from r.
+// The window buffer is reused if it is large enough.
+func (b *BlockReader) Reset(r io.Reader) {
+	if b.br == nil {
+		b.br = bufio.NewReader(r)
+	} else {
+		b.br.Reset(r)
+	}
+	b.err = nil
+	b.rd, b.pos = 0, 0
+	b.readHeader = false
+	b.produced, b.left, b.litLeft, b.copyLeft, b.offset = 0, 0, 0, 0, 0
+}
+
+// Read satisfies the io.Reader interface.
+func (b *BlockReader) Read(p []byte) (int, error) {
+	for b.rd == b.pos {
+		if b.err != nil {
+			return 0, b.err
+		}
+		b.fill()
+	}
+	n := copy(p, b.buf[b.rd:b.pos])
+	b.rd += n
+	return n, nil
+}
+
+// WriteTo writes the decoded block to w.
+// This avoids copying the output.
+// The number of bytes written is returned.
+func (b *BlockReader) WriteTo(w io.Writer) (n int64, err error) {
+	for {
+		if b.rd < b.pos {
+			n2, err := w.Write(b.buf[b.rd:b.pos])
+			n += int64(n2)
+			if err == nil && n2 != b.pos-b.rd {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				b.err = err
+				return n, err
+			}
+			b.rd = b.pos
+		}
+		if b.err != nil {
+			if b.err == io.EOF {
+				return n, nil
+			}
+			return n, b.err
+		}
+		b.fill()
+	}
+}
+
+// fill decodes more output to the window buffer.
+// Must only be called when all output has been read.
+func (b *BlockReader) fill() {
+	if !b.readHeader {
+		v, err := binary.ReadUvarint(b.br)
+		if err != nil || v > 0xffffffff {
+			b.err = ErrCorrupt
+			return
+		}
+		const wordSize = 32 << (^uint(0) >> 32 & 1)
+		if wordSize == 32 && v > 0x7fffffff {
+			b.err = ErrTooLarge
+			return
+		}
+		b.readHeader = true
+		b.left = int(v)
+		size := b.left
+		if b.window > 0 && b.window < size {
+			size = min(size, b.window+max(b.window, 64<<10))
+		}
+		if cap(b.buf) < size {
+			b.buf = make([]byte, size)
+		}
+		b.buf = b.buf[:size]
+	}
+	if b.pos == len(b.buf) {
+		if b.left == 0 && b.litLeft == 0 && b.copyLeft == 0 {
+			b.finish()
+			return
+		}
+		// Keep the window and reuse the rest of the buffer.
+		window := min(b.window, b.pos)
+		copy(b.buf, b.buf[b.pos-window:b.pos])
+		b.rd, b.pos = window, window
+	}
+
+	for b.pos < len(b.buf) {
+		switch {
+		case b.litLeft > 0:
+			n := min(b.litLeft, len(b.buf)-b.pos)
+			if _, err := io.ReadFull(b.br, b.buf[b.pos:b.pos+n]); err != nil {
+				b.err = noEOFCorrupt(err)
+				return
+			}
+			b.pos += n
+			b.produced += n
+			b.litLeft -= n
+			continue
+		case b.copyLeft > 0:
+			n := min(b.copyLeft, len(b.buf)-b.pos)
+			b.copyLeft -= n
+			b.produced += n
+			// Copy at most offset bytes at the time, so overlapping copies repeat.
+			for n > 0 {
+				c := copy(b.buf[b.pos:b.pos+n], b.buf[b.pos-b.offset:b.pos])
+				b.pos += c
+				n -= c
+			}
+			continue
+		case b.left == 0:
+			b.finish()
+			return
+		}
+		if !b.readOp() {
+			return
+		}
+	}
+}
+
+// finish checks that the input ends after the block.
+func (b *BlockReader) finish() {
+	if _, err := b.br.ReadByte(); err != io.EOF {
+		if err == nil {
+			err = ErrCorrupt
+		}
+		b.err = err
+		return
+	}
+	b.err = io.EOF
+}
+
+// readOp reads the next operation.
+func (b *BlockReader) readOp() bool {
+	var tmp [4]byte
+	read := func(n int) bool {
+		if _, err := io.ReadFull(b.br, tmp[:n]); err != nil {
+			b.err = noEOFCorrupt(err)
+			return false
+		}
+		return true
+	}
+	tag, err := b.br.ReadByte()
+	if err != nil {
+		b.err = noEOFCorrupt(err)
+		return false
+	}
+	var length int
+	switch tag & 0x03 {
+	case tagLiteral:
+		x := uint32(tag >> 2)
+		if x >= 60 {
+			// 1 to 4 bytes of length follow.
+			if !read(int(x - 59)) {
+				return false
+			}
+			x = binary.LittleEndian.Uint32(tmp[:])
+		}
+		length = int(x) + 1
+		if length <= 0 || length > b.left {
+			b.err = ErrCorrupt
+			return false
+		}
+		b.left -= length
+		b.litLeft = length
+		return true
+
+	case tagCopy1:
+		if !read(1) {
+			return false
+		}
+		offset := int(tag&0xe0)<<3 | int(tmp[0])
+		length = int(tag>>2) & 0x7
+		if offset == 0 {
+			// Repeat the last offset.
+			switch length {
+			case 5:
+				if !read(1) {
+					return false
+				}
+				length = int(tmp[0]) + 4
+			case 6:
+				if !read(2) {
+					return false
+				}
+				length = int(binary.LittleEndian.Uint16(tmp[:])) + 1<<8
+			case 7:
+				if !read(3) {
+					return false
+				}
+				length = (int(tmp[0]) | int(tmp[1])<<8 | int(tmp[2])<<16) + 1<<16
+			}
+		} else {
+			b.offset = offset
+		}
+		length += 4
+	case tagCopy2:
+		if !read(2) {
+			return false
+		}
+		b.offset = int(binary.LittleEndian.Uint16(tmp[:]))
+		length = 1 + int(tag)>>2
+	default:
+		if !read(4) {
+			return false
+		}
+		b.offset = int(binary.LittleEndian.Uint32(tmp[:]))
+		length = 1 + int(tag)>>2
+	}
+	if b.offset <= 0 || b.produced < b.offset || length > b.left {
+		b.err = ErrCorrupt
+		return false
+	}
+	if b.window > 0 && b.offset > b.window {
+		b.err = fmt.Errorf("%w: offset %d exceeds window size %d", ErrUnsupported, b.offset, b.window)
+		return false
+	}
+	b.left -= length
+	b.copyLeft = length
+	return true
+}
+
+// noEOFCorrupt converts EOF errors to ErrCorrupt.
+func noEOFCorrupt(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrCorrupt
+	}
+	return err
+}
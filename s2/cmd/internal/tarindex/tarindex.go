@@ -0,0 +1,107 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tarindex contains an index of tar members,
+// which is stored as a skippable block in compressed tar files.
+// Combined with the seek index of the stream, this allows
+// extracting single members without decompressing the entire archive.
+package tarindex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ChunkID is the ID of the skippable block containing the index.
+const ChunkID = 0x9b
+
+const magic = "s2tar\x01"
+
+// ErrCorrupt is returned if the index cannot be parsed.
+var ErrCorrupt = errors.New("tarindex: corrupt index")
+
+// Entry is a tar member.
+type Entry struct {
+	Name string
+	// Offset is the uncompressed offset of the tar header of the member.
+	Offset int64
+}
+
+// Index contains members in the order they appear in the archive.
+type Index struct {
+	Entries []Entry
+}
+
+// Add a member. Members must be added in the order they are stored.
+func (x *Index) Add(name string, offset int64) error {
+	if n := len(x.Entries); n > 0 && x.Entries[n-1].Offset >= offset {
+		return fmt.Errorf("tarindex: offset %d not after previous offset %d", offset, x.Entries[n-1].Offset)
+	}
+	x.Entries = append(x.Entries, Entry{Name: name, Offset: offset})
+	return nil
+}
+
+// AppendTo appends the serialized index to b.
+func (x *Index) AppendTo(b []byte) []byte {
+	b = append(b, magic...)
+	b = binary.AppendUvarint(b, uint64(len(x.Entries)))
+	var prev int64
+	for _, e := range x.Entries {
+		b = binary.AppendUvarint(b, uint64(len(e.Name)))
+		b = append(b, e.Name...)
+		// Offsets are stored as deltas.
+		b = binary.AppendUvarint(b, uint64(e.Offset-prev))
+		prev = e.Offset
+	}
+	return b
+}
+
+// Load a serialized index.
+func (x *Index) Load(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return ErrCorrupt
+	}
+	b = b[len(magic):]
+	n, l := binary.Uvarint(b)
+	// Each entry uses at least 2 bytes.
+	if l <= 0 || n > uint64(len(b)/2) {
+		return ErrCorrupt
+	}
+	b = b[l:]
+	x.Entries = make([]Entry, 0, n)
+	var prev int64
+	for range n {
+		nameLen, l := binary.Uvarint(b)
+		if l <= 0 || nameLen > uint64(len(b)-l) {
+			return ErrCorrupt
+		}
+		name := string(b[l : l+int(nameLen)])
+		b = b[l+int(nameLen):]
+		delta, l := binary.Uvarint(b)
+		if l <= 0 || delta > 1<<62 || (len(x.Entries) > 0 && delta == 0) {
+			return ErrCorrupt
+		}
+		b = b[l:]
+		prev += int64(delta)
+		if prev < 0 {
+			return ErrCorrupt
+		}
+		x.Entries = append(x.Entries, Entry{Name: name, Offset: prev})
+	}
+	if len(b) != 0 {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// Find returns the last member with the name.
+func (x *Index) Find(name string) (Entry, bool) {
+	for i := len(x.Entries) - 1; i >= 0; i-- {
+		if x.Entries[i].Name == name {
+			return x.Entries[i], true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tarindex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	var x Index
+	for i, name := range []string{"dir/", "dir/a.txt", "dir/b.txt", "dir/a.txt"} {
+		if err := x.Add(name, int64(i)*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := x.Add("c", 1024); err == nil {
+		t.Fatal("want error on decreasing offset")
+	}
+	b := x.AppendTo(nil)
+	var got Index
+	if err := got.Load(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, x) {
+		t.Fatalf("want %+v, got %+v", x, got)
+	}
+	if e, ok := got.Find("dir/a.txt"); !ok || e.Offset != 3072 {
+		t.Fatalf("unexpected find result: %+v, %v", e, ok)
+	}
+	if _, ok := got.Find("dir/c.txt"); ok {
+		t.Fatal("found non-existing entry")
+	}
+	for i := range b {
+		if err := got.Load(b[:i]); err == nil {
+			t.Fatalf("no error loading truncated index at %d", i)
+		}
+	}
+}
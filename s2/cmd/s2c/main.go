@@ -7,10 +7,12 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"runtime/pprof"
@@ -21,9 +23,9 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/klauspost/compress/readahead"
 	"github.com/klauspost/compress/s2"
 	"github.com/klauspost/compress/s2/cmd/internal/filepathx"
-	"github.com/klauspost/compress/s2/cmd/internal/readahead"
 )
 
 var (
@@ -43,6 +45,9 @@ var (
 	quiet     = flag.Bool("q", false, "Don't write any output to terminal, except errors")
 	bench     = flag.Int("bench", 0, "Run benchmark n times. No output will be written")
 	verify    = flag.Bool("verify", false, "Verify written files")
+	recursive = flag.Bool("r", false, "Compress all files in directories recursively. Modification time and permissions are preserved")
+	tarMode   = flag.Bool("tar", false, "Store all input files and directories in a single indexed tar archive")
+	lineIndex = flag.Bool("lineindex", false, "Add line index, so 's2d -lines' can seek to lines. Requires seek index")
 	help      = flag.Bool("help", false, "Display help")
 
 	cpuprofile, memprofile, traceprofile string
@@ -82,6 +87,10 @@ Use - as the only file name to read from stdin and write to stdout.
 
 Wildcards are accepted: testdir/*.txt will compress all files in testdir ending with .txt
 Directories can be wildcards as well. testdir/*/*.txt will match testdir/subdir/b.txt
+Use -r to compress all files in directories.
+
+With -tar all input files and directories are stored in a single tar archive,
+written as 'filename.tar`+s2Ext+`'. Single files can be extracted with 's2d -member'.
 
 File names beginning with 'http://' and 'https://' will be downloaded and compressed.
 Only http response code 200 is accepted.
@@ -103,10 +112,19 @@ Options:`)
 	if *snappy {
 		opts = append(opts, s2.WriterSnappyCompat())
 	}
+	if *lineIndex {
+		if !*index {
+			exitErr(errors.New("-lineindex requires the seek index"))
+		}
+		opts = append(opts, s2.WriterLineIndex())
+	}
 	wr := s2.NewWriter(nil, opts...)
 
 	// No args, use stdin/stdout
 	if len(args) == 1 && args[0] == "-" {
+		if *tarMode {
+			exitErr(errors.New("-tar cannot be used with stdin"))
+		}
 		// Catch interrupt, so we don't exit at once.
 		// os.Stdin will return EOF, so we should be able to get everything.
 		signal.Notify(make(chan os.Signal, 1), os.Interrupt)
@@ -143,6 +161,12 @@ Options:`)
 		if len(found) == 0 {
 			exitErr(fmt.Errorf("unable to find file %v", pattern))
 		}
+		if *recursive && !*tarMode {
+			for _, name := range found {
+				files = append(files, walkFiles(name)...)
+			}
+			continue
+		}
 		files = append(files, found...)
 	}
 	if cpuprofile != "" {
@@ -337,6 +361,13 @@ Options:`)
 	if *block {
 		ext += ".block"
 	}
+	if *tarMode {
+		if *block || *recomp || *remove {
+			exitErr(errors.New("-tar cannot be used with -block, -recomp or -rm"))
+		}
+		compressTar(wr, files, ext, sz)
+		return
+	}
 	if *out != "" && len(files) > 1 {
 		exitErr(errors.New("-out parameter can only be used with one input"))
 	}
@@ -357,6 +388,7 @@ Options:`)
 				// Input file.
 				file, _, mode := openFile(filename)
 				exitErr(err)
+				defer preserveAttrs(filename, dstFilename)()
 				defer closeOnce.Do(func() { file.Close() })
 				inBytes, err := io.ReadAll(file)
 				exitErr(err)
@@ -465,6 +497,7 @@ Options:`)
 			// Input file.
 			file, _, mode := openFile(filename)
 			exitErr(err)
+			defer preserveAttrs(filename, dstFilename)()
 			defer closeOnce.Do(func() { file.Close() })
 			src, err := readahead.NewReaderSize(file, *cpu+1, 1<<20)
 			exitErr(err)
@@ -535,6 +568,44 @@ Options:`)
 	}
 }
 
+// walkFiles returns all regular files in name, if it is a directory.
+// Files that are already compressed are skipped,
+// unless recompressing, where only compressed files are returned.
+func walkFiles(name string) []string {
+	if st, err := os.Stat(name); err != nil || !st.IsDir() {
+		return []string{name}
+	}
+	var files []string
+	err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		compressed := strings.HasSuffix(path, s2Ext) || strings.HasSuffix(path, snappyExt) || strings.HasSuffix(path, ".snappy")
+		if compressed == *recomp && !strings.HasSuffix(path, ".block") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	exitErr(err)
+	return files
+}
+
+// preserveAttrs reads the modification time and permissions of src.
+// The returned function applies them to dst, and should be called
+// when dst has been closed.
+// Attributes are only preserved in recursive mode.
+func preserveAttrs(src, dst string) func() {
+	if !*recursive || *stdout || isHTTP(src) {
+		return func() {}
+	}
+	st, err := os.Stat(src)
+	exitErr(err)
+	return func() {
+		exitErr(os.Chmod(dst, st.Mode().Perm()))
+		exitErr(os.Chtimes(dst, time.Time{}, st.ModTime()))
+	}
+}
+
 func isHTTP(name string) bool {
 	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/s2/cmd/internal/tarindex"
+)
+
+// compressTar stores all files and directories as a single tar archive.
+// An index of the members is added as a skippable block,
+// so single members can be extracted using the seek index.
+func compressTar(wr *s2.Writer, files []string, ext string, sz int) {
+	if !*index {
+		exitErr(errors.New("-tar requires the seek index"))
+	}
+	dstFilename := *out
+	if dstFilename == "" {
+		dstFilename = strings.TrimRight(files[0], `/\`) + ".tar" + ext
+	}
+	if *stdout {
+		dstFilename = "(stdout)"
+	}
+	if !*quiet {
+		fmt.Print("Compressing ", strings.Join(files, ", "), " -> ", dstFilename)
+	}
+
+	var out io.Writer
+	var dstStat os.FileInfo
+	switch {
+	case *stdout:
+		out = os.Stdout
+	default:
+		if *safe {
+			_, err := os.Stat(dstFilename)
+			if !os.IsNotExist(err) {
+				exitErr(errors.New("destination file exists"))
+			}
+		}
+		dstFile, err := os.OpenFile(dstFilename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+		exitErr(err)
+		defer dstFile.Close()
+		dstStat, err = dstFile.Stat()
+		exitErr(err)
+		bw := bufio.NewWriterSize(dstFile, sz*2)
+		defer bw.Flush()
+		out = bw
+	}
+	out, errFn := verifyTo(out)
+	wc := wCounter{out: out}
+	wr.Reset(&wc)
+	defer wr.Close()
+
+	// Count uncompressed bytes to get member offsets.
+	tarOut := wCounter{out: wr}
+	tw := tar.NewWriter(&tarOut)
+	var idx tarindex.Index
+	start := time.Now()
+	for _, name := range files {
+		if isHTTP(name) {
+			exitErr(errors.New("-tar does not support http input"))
+		}
+		base := filepath.Dir(filepath.Clean(name))
+		err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if dstStat != nil && os.SameFile(fi, dstStat) {
+				// Don't add the output.
+				return nil
+			}
+			var link string
+			switch {
+			case fi.Mode().IsRegular(), fi.IsDir():
+			case fi.Mode()&fs.ModeSymlink != 0:
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			default:
+				if !*quiet {
+					fmt.Print("\nSkipping ", path)
+				}
+				return nil
+			}
+			hdr, err := tar.FileInfoHeader(fi, link)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(rel)
+			if fi.IsDir() {
+				hdr.Name += "/"
+			}
+			// Write padding of the previous member, so we get the header offset.
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if err := idx.Add(hdr.Name, int64(tarOut.n)); err != nil {
+				return err
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		exitErr(err)
+	}
+	exitErr(tw.Close())
+	exitErr(wr.Flush())
+	exitErr(wr.AddSkippableBlock(tarindex.ChunkID, idx.AppendTo(nil)))
+	exitErr(wr.Close())
+	if !*quiet {
+		input := tarOut.n
+		elapsed := time.Since(start)
+		mbpersec := (float64(input) / (1024 * 1024)) / (float64(elapsed) / (float64(time.Second)))
+		pct := float64(wc.n) * 100 / float64(input)
+		fmt.Printf(" %d members, %d -> %d [%.02f%%]; %.01fMB/s\n", len(idx.Entries), input, wc.n, pct, mbpersec)
+	}
+	exitErr(errFn())
+}
@@ -7,8 +7,10 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"strconv"
@@ -17,9 +19,9 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/klauspost/compress/readahead"
 	"github.com/klauspost/compress/s2"
 	"github.com/klauspost/compress/s2/cmd/internal/filepathx"
-	"github.com/klauspost/compress/s2/cmd/internal/readahead"
 )
 
 var (
@@ -35,6 +37,10 @@ var (
 	out    = flag.String("o", "", "Write output to another file. Single input file only")
 	block  = flag.Bool("block", false, "Decompress as a single block. Will load content into memory.")
 	cpu    = flag.Int("cpu", runtime.NumCPU(), "Decompress streams using this amount of threads")
+	rec    = flag.Bool("r", false, "Decompress all compressed files in directories recursively. Modification time and permissions are preserved")
+	member = flag.String("member", "", "Extract a single member from a tar archive created with 's2c -tar'")
+	rangeB = flag.String("range", "", "Only output the bytes start:end. End is exclusive and optional. Examples: 100:200, 1M:2M, 64K:. Requires Index")
+	lines  = flag.String("lines", "", "Only output the zero-based lines start:end. End is exclusive and optional. Requires line index, see 's2c -lineindex'")
 
 	version = "(dev)"
 	date    = "(unknown)"
@@ -63,6 +69,7 @@ Use - as the only file name to read from stdin and write to stdout.
 
 Wildcards are accepted: testdir/*.txt will compress all files in testdir ending with .txt
 Directories can be wildcards as well. testdir/*/*.txt will match testdir/subdir/b.txt
+Use -r to decompress all compressed files in directories.
 
 File names beginning with 'http://' and 'https://' will be downloaded and decompressed.
 Extensions on downloaded files are ignored. Only http response code 200 is accepted.
@@ -75,9 +82,43 @@ Options:`)
 	exitErr(err)
 	offset, err := toSize(*offset)
 	exitErr(err)
-	if tailBytes > 0 && offset > 0 {
-		exitErr(errors.New("--offset and --tail cannot be used together"))
+	rangeStart, rangeEnd, err := parseRange(*rangeB, toSize)
+	exitErr(err)
+	lineStart, lineEnd, err := parseRange(*lines, func(s string) (int64, error) {
+		return strconv.ParseInt(s, 10, 64)
+	})
+	exitErr(err)
+	if rangeStart > 0 {
+		if offset > 0 {
+			exitErr(errors.New("--offset and --range cannot be used together"))
+		}
+		offset = rangeStart
+	}
+	// Number of bytes or lines to output, -1 for all.
+	limit := int64(-1)
+	if rangeEnd >= 0 {
+		limit = rangeEnd - rangeStart
+	}
+	byLines := *lines != ""
+	if byLines {
+		if *rangeB != "" {
+			exitErr(errors.New("--range and --lines cannot be used together"))
+		}
+		limit = -1
+		if lineEnd >= 0 {
+			limit = lineEnd - lineStart
+		}
 	}
+	if tailBytes > 0 && (offset > 0 || byLines) {
+		exitErr(errors.New("--offset, --range, --lines and --tail cannot be used together"))
+	}
+	if offset > 0 && byLines {
+		exitErr(errors.New("--offset and --lines cannot be used together"))
+	}
+	// Seeking is required when only part of the stream is output.
+	seeking := tailBytes > 0 || offset > 0 || byLines
+	// Random seeking is required for loading the line index.
+	randomSeek := tailBytes > 0 || byLines
 	if len(args) == 1 && args[0] == "-" {
 		r.Reset(os.Stdin)
 		if *verify {
@@ -119,6 +160,12 @@ Options:`)
 		if len(found) == 0 {
 			exitErr(fmt.Errorf("unable to find file %v", pattern))
 		}
+		if *rec {
+			for _, name := range found {
+				files = append(files, walkFiles(name)...)
+			}
+			continue
+		}
 		files = append(files, found...)
 	}
 
@@ -190,6 +237,13 @@ Options:`)
 		os.Exit(0)
 	}
 
+	if *member != "" {
+		if len(files) != 1 {
+			exitErr(errors.New("-member can only be used with one input"))
+		}
+		extractMember(r, files[0], *member)
+		return
+	}
 	if *out != "" && len(files) > 1 {
 		exitErr(errors.New("-out parameter can only be used with one input"))
 	}
@@ -227,15 +281,18 @@ Options:`)
 			}
 			// Input file.
 			file, _, mode := openFile(filename)
+			if !*verify {
+				defer preserveAttrs(filename, dstFilename)()
+			}
 			defer closeOnce.Do(func() { file.Close() })
 			var rc interface {
 				io.Reader
 				BytesRead() int64
 			}
-			if tailBytes > 0 || offset > 0 {
+			if seeking {
 				rs, ok := file.(io.ReadSeeker)
-				if !ok && tailBytes > 0 {
-					exitErr(errors.New("cannot tail with non-seekable input"))
+				if !ok && randomSeek {
+					exitErr(errors.New("cannot tail or seek to lines with non-seekable input"))
 				}
 				if ok {
 					rc = &rCountSeeker{in: rs}
@@ -246,7 +303,7 @@ Options:`)
 				rc = &rCounter{in: file}
 			}
 			var src io.Reader
-			if !block && tailBytes == 0 && offset == 0 {
+			if !block && !seeking {
 				ra, err := readahead.NewReaderSize(rc, 2, 4<<20)
 				exitErr(err)
 				defer ra.Close()
@@ -287,21 +344,32 @@ Options:`)
 				decoded = bytes.NewReader(b)
 			} else {
 				r.Reset(src)
-				if tailBytes > 0 || offset > 0 {
-					rs, err := r.ReadSeeker(tailBytes > 0, nil)
+				decoded = r
+				if seeking {
+					rs, err := r.ReadSeeker(randomSeek, nil)
 					exitErr(err)
-					if tailBytes > 0 {
+					switch {
+					case tailBytes > 0:
 						_, err = rs.Seek(-tailBytes, io.SeekEnd)
-					} else {
+					case byLines:
+						_, err = rs.SeekLine(lineStart)
+					default:
 						_, err = rs.Seek(offset, io.SeekStart)
 					}
 					exitErr(err)
+					decoded = rs
 				}
-				decoded = r
+			}
+			switch {
+			case limit < 0:
+			case byLines:
+				decoded = &lineLimiter{in: decoded, n: limit}
+			default:
+				decoded = io.LimitReader(decoded, limit)
 			}
 			var err error
 			var output int64
-			if dec, ok := decoded.(*s2.Reader); ok && tailBytes == 0 && offset == 0 {
+			if dec, ok := decoded.(*s2.Reader); ok && !seeking {
 				output, err = dec.DecodeConcurrent(out, *cpu)
 			} else {
 				output, err = io.Copy(out, decoded)
@@ -361,6 +429,42 @@ func cleanFileName(s string) string {
 	return s
 }
 
+// walkFiles returns all compressed files in name, if it is a directory.
+func walkFiles(name string) []string {
+	if st, err := os.Stat(name); err != nil || !st.IsDir() {
+		return []string{name}
+	}
+	var files []string
+	err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		base := strings.TrimSuffix(path, ".block")
+		if strings.HasSuffix(base, s2Ext) || strings.HasSuffix(base, snappyExt) || strings.HasSuffix(base, ".snappy") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	exitErr(err)
+	return files
+}
+
+// preserveAttrs reads the modification time and permissions of src.
+// The returned function applies them to dst, and should be called
+// when dst has been closed.
+// Attributes are only preserved in recursive mode.
+func preserveAttrs(src, dst string) func() {
+	if !*rec || *stdout || isHTTP(src) {
+		return func() {}
+	}
+	st, err := os.Stat(src)
+	exitErr(err)
+	return func() {
+		exitErr(os.Chmod(dst, st.Mode().Perm()))
+		exitErr(os.Chtimes(dst, time.Time{}, st.ModTime()))
+	}
+}
+
 func isHTTP(name string) bool {
 	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
 }
@@ -441,3 +545,51 @@ func toSize(size string) (int64, error) {
 		return 0, fmt.Errorf("unknown size suffix: %v", multiple)
 	}
 }
+
+// parseRange parses a "start:end" range. End is exclusive.
+// If end is omitted, -1 is returned.
+// If the range is empty, 0, -1 is returned.
+func parseRange(s string, conv func(string) (int64, error)) (start, end int64, err error) {
+	if s == "" {
+		return 0, -1, nil
+	}
+	startS, endS, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("range %q must be start:end", s)
+	}
+	if start, err = conv(startS); err != nil {
+		return 0, 0, err
+	}
+	if endS == "" {
+		end = -1
+	} else if end, err = conv(endS); err != nil {
+		return 0, 0, err
+	}
+	if start < 0 || (end >= 0 && end < start) {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	return start, end, nil
+}
+
+// lineLimiter returns data from in until n newlines have been read.
+type lineLimiter struct {
+	in io.Reader
+	n  int64
+}
+
+func (l *lineLimiter) Read(p []byte) (n int, err error) {
+	if l.n <= 0 {
+		return 0, io.EOF
+	}
+	n, err = l.in.Read(p)
+	for i, c := range p[:n] {
+		if c != '\n' {
+			continue
+		}
+		l.n--
+		if l.n == 0 {
+			return i + 1, nil
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/s2/cmd/internal/tarindex"
+)
+
+// extractMember extracts a single member of a tar archive created with 's2c -tar'.
+// The member index and the seek index is used to only decompress the needed blocks.
+func extractMember(r *s2.Reader, filename, name string) {
+	if isHTTP(filename) {
+		exitErr(errors.New("-member does not support http input"))
+	}
+	file, err := os.Open(filename)
+	exitErr(err)
+	defer file.Close()
+
+	var idx *tarindex.Index
+	exitErr(r.SkippableCB(tarindex.ChunkID, func(r io.Reader) error {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		idx = &tarindex.Index{}
+		return idx.Load(b)
+	}))
+	r.Reset(file)
+	rs, err := r.ReadSeeker(true, nil)
+	exitErr(err)
+
+	// The member index is stored after the last block.
+	_, err = rs.Seek(-1, io.SeekEnd)
+	exitErr(err)
+	_, err = io.Copy(io.Discard, rs)
+	exitErr(err)
+	if idx == nil {
+		exitErr(errors.New("no tar member index found. Archive must be created with 's2c -tar'"))
+	}
+	e, ok := idx.Find(name)
+	if !ok {
+		exitErr(fmt.Errorf("member %q not found", name))
+	}
+	_, err = rs.Seek(e.Offset, io.SeekStart)
+	exitErr(err)
+	tr := tar.NewReader(rs)
+	hdr, err := tr.Next()
+	exitErr(err)
+	if hdr.Name != name {
+		exitErr(fmt.Errorf("member index mismatch, want %q, got %q", name, hdr.Name))
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		exitErr(fmt.Errorf("member %q is not a regular file", name))
+	}
+
+	dstFilename := *out
+	switch {
+	case *verify:
+		dstFilename = "(verify)"
+	case *stdout:
+		dstFilename = "(stdout)"
+	case dstFilename == "":
+		dstFilename = path.Base(name)
+	}
+	if !*quiet {
+		fmt.Print("Extracting ", name, " from ", filename, " -> ", dstFilename)
+	}
+	start := time.Now()
+	var out io.Writer
+	finish := func() {}
+	switch {
+	case *verify:
+		out = io.Discard
+	case *stdout:
+		out = os.Stdout
+	default:
+		if *safe {
+			_, err := os.Stat(dstFilename)
+			if !os.IsNotExist(err) {
+				exitErr(errors.New("destination files exists"))
+			}
+		}
+		dstFile, err := os.OpenFile(dstFilename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+		exitErr(err)
+		bw := bufio.NewWriterSize(dstFile, 4<<20)
+		finish = func() {
+			exitErr(bw.Flush())
+			exitErr(dstFile.Close())
+			exitErr(os.Chtimes(dstFilename, time.Time{}, hdr.ModTime))
+		}
+		out = bw
+	}
+	output, err := io.Copy(out, tr)
+	exitErr(err)
+	finish()
+	if !*quiet {
+		elapsed := time.Since(start)
+		mbPerSec := (float64(output) / (1024 * 1024)) / (float64(elapsed) / (float64(time.Second)))
+		fmt.Printf(" %d bytes; %.01fMB/s\n", output, mbPerSec)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/s2/s2sx"
+)
+
+var (
+	help       = flag.Bool("help", false, "Display help")
+	untarFlag  = flag.Bool("untar", true, "Untar tar files if specified at creation")
+	forceUntar = flag.Bool("force-untar", false, "Always untar file")
+	quiet      = flag.Bool("q", false, "Don't write any output to terminal, except errors")
+)
+
+func main() {
+	flag.Parse()
+	me, err := os.Executable()
+	exitErr(err)
+	args := flag.Args()
+	invalidArgs := len(args) > 1
+	if *help || invalidArgs {
+		_, _ = fmt.Fprintf(os.Stderr, "s2sx Self Extracting Archive\n\n")
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: %s [options] [output-file/dir]\n\n", os.Args[0])
+		_, _ = fmt.Fprintf(os.Stderr, "Use - as file name to extract to stdout when not untarring.\n\n")
+		_, _ = fmt.Fprintln(os.Stderr, `Options:`)
+		flag.PrintDefaults()
+		if invalidArgs {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	stdout := len(args) > 0 && args[0] == "-"
+	*quiet = *quiet || stdout
+
+	f, err := os.Open(me)
+	exitErr(err)
+	defer f.Close()
+	stat, err := f.Stat()
+	exitErr(err)
+	mode, rd, err := s2sx.Open(f, stat.Size())
+	exitErr(err)
+	f2, err := os.Open(me + ".more")
+	if err == nil {
+		rd = io.MultiReader(rd, f2)
+	}
+	if !os.IsNotExist(err) {
+		exitErr(err)
+	}
+	dec := s2.NewReader(rd)
+	if !*untarFlag {
+		mode = s2sx.ModeUnpack
+	}
+	if *forceUntar {
+		mode = s2sx.ModeUntar
+	}
+	switch mode {
+	case s2sx.ModeUnpack:
+		outname := me + "-extracted"
+		if idx := strings.Index(me, ".s2sx"); idx > 0 {
+			// Trim from '.s2sx'
+			outname = me[:idx]
+		}
+		var out io.Writer
+		if stdout {
+			out = os.Stdout
+		} else {
+			if len(args) > 0 {
+				outname, err = filepath.Abs(args[0])
+				exitErr(err)
+			}
+			if !*quiet {
+				fmt.Printf("Extracting to \"%s\"...", outname)
+			}
+			f, err := os.Create(outname)
+			exitErr(err)
+			defer f.Close()
+			out = f
+		}
+		_, err = dec.DecodeConcurrent(out, 0)
+		exitErr(err)
+
+	case s2sx.ModeUntar:
+		dir, err := os.Getwd()
+		if err != nil {
+			dir = filepath.Dir(me)
+		}
+		if len(args) > 0 {
+			if args[0] == "-" {
+				exitErr(errors.New("cannot untar files to stdout. Use -untar=false to skip untar operation"))
+			}
+			if filepath.IsAbs(args[0]) {
+				dir = args[0]
+			} else {
+				dir = filepath.Join(dir, args[0])
+			}
+		}
+		if !*quiet {
+			fmt.Printf("Extracting TAR file to %s...\n", dir)
+		}
+		exitErr(s2sx.Untar(dir, dec, func(path string) {
+			if !*quiet {
+				fmt.Println(path)
+			}
+		}))
+	default:
+		exitErr(fmt.Errorf("unknown operation: %d", mode))
+	}
+	if !*quiet {
+		fmt.Println("\nDone.")
+	}
+}
+
+func exitErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "\nERROR:", err.Error())
+		os.Exit(2)
+	}
+}
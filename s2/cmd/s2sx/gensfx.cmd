@@ -1,6 +1,6 @@
 go build ../s2c
 
-DEL /Q sfx-exe\*
+DEL /Q sfx-exe\*.s2
 
 SET GOOS=linux
 SET GOARCH=amd64
@@ -28,6 +28,6 @@ go build %BUILDFLAGS% -o ./sfx-exe/%GOOS%-%GOARCH% ./_unpack/main.go
 SET GOARCH=386
 go build %BUILDFLAGS% -o ./sfx-exe/%GOOS%-%GOARCH% ./_unpack/main.go
 
-s2c.exe -rm -slower sfx-exe\*
+s2c.exe -rm -slower sfx-exe\*-*
 DEL /Q s2c.exe
 
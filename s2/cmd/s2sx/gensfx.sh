@@ -2,7 +2,7 @@
 
 go build -o=s2c ../s2c
 
-rm -rf sfx-exe/ || true
+rm -f sfx-exe/*.s2
 
 GOOS=linux GOARCH=amd64 go build -trimpath -ldflags="-s -w" -o ./sfx-exe/linux-amd64 ./_unpack/main.go
 GOOS=linux GOARCH=arm64 go build -trimpath -ldflags="-s -w" -o ./sfx-exe/linux-arm64 ./_unpack/main.go
@@ -16,6 +16,6 @@ GOOS=darwin GOARCH=arm64 go build -trimpath -ldflags="-s -w" -o ./sfx-exe/darwin
 GOOS=windows GOARCH=amd64 go build -trimpath -ldflags="-s -w" -o ./sfx-exe/windows-amd64 ./_unpack/main.go
 GOOS=windows GOARCH=386 go build -trimpath -ldflags="-s -w" -o ./sfx-exe/windows-386 ./_unpack/main.go
 
-./s2c -rm -slower sfx-exe/*
+./s2c -rm -slower sfx-exe/*-*
 
 rm s2c
@@ -19,13 +19,9 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/klauspost/compress/readahead"
 	"github.com/klauspost/compress/s2"
-	"github.com/klauspost/compress/s2/cmd/internal/readahead"
-)
-
-const (
-	opUnpack = iota + 1
-	opUnTar
+	"github.com/klauspost/compress/s2/s2sx"
 )
 
 var (
@@ -38,6 +34,7 @@ var (
 	remove = flag.Bool("rm", false, "Delete source file(s) after successful compression")
 	quiet  = flag.Bool("q", false, "Don't write any output to terminal, except errors")
 	untar  = flag.Bool("untar", false, "Untar on destination")
+	stub   = flag.String("unpacker", "", "Use this unpacker executable instead of the embedded ones. Build from s2/cmd/s2sx/_unpack")
 	help   = flag.Bool("help", false, "Display help")
 
 	version = "(dev)"
@@ -71,13 +68,8 @@ Directories can be wildcards as well. testdir/*/*.txt will match testdir/subdir/
 
 Options:`)
 		flag.PrintDefaults()
-		dir, err := embeddedFiles.ReadDir("sfx-exe")
-		exitErr(err)
 		_, _ = fmt.Fprintf(os.Stderr, "\nAvailable platforms are:\n\n")
-		for _, d := range dir {
-			_, _ = fmt.Fprintf(os.Stderr, " * %s\n", strings.TrimSuffix(d.Name(), ".s2"))
-		}
-
+		printPlatforms()
 		os.Exit(0)
 	}
 
@@ -85,37 +77,37 @@ Options:`)
 	wr := s2.NewWriter(nil, opts...)
 
 	wantPlat := *goos + "-" + *goarch
-	exec, err := embeddedFiles.ReadFile(path.Join("sfx-exe", wantPlat+".s2"))
-	if os.IsNotExist(err) {
-		dir, err := embeddedFiles.ReadDir("sfx-exe")
+	var exec []byte
+	if *stub != "" {
+		exec, err = os.ReadFile(*stub)
 		exitErr(err)
-		_, _ = fmt.Fprintf(os.Stderr, "os-arch %v not available. Available sfx platforms are:\n\n", wantPlat)
-		for _, d := range dir {
-			_, _ = fmt.Fprintf(os.Stderr, "* %s\n", strings.TrimSuffix(d.Name(), ".s2"))
+	} else {
+		exec, err = embeddedFiles.ReadFile(path.Join("sfx-exe", wantPlat+".s2"))
+		if os.IsNotExist(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "os-arch %v not available. Available sfx platforms are:\n\n", wantPlat)
+			printPlatforms()
+			_, _ = fmt.Fprintf(os.Stderr, "\nUse -os and -arch to specify the destination platform.")
+			os.Exit(1)
 		}
-		_, _ = fmt.Fprintf(os.Stderr, "\nUse -os and -arch to specify the destination platform.")
-		os.Exit(1)
+		exitErr(err)
+		exec, err = io.ReadAll(s2.NewReader(bytes.NewBuffer(exec)))
+		exitErr(err)
 	}
-	exec, err = io.ReadAll(s2.NewReader(bytes.NewBuffer(exec)))
-	exitErr(err)
 
 	written := int64(0)
 	if int64(len(exec))+1 >= sz {
 		exitErr(fmt.Errorf("max size less than unpacker. Max size must be at least %d bytes", len(exec)+1))
 	}
-	mode := byte(opUnpack)
+	mode := s2sx.ModeUnpack
 	if *untar {
-		mode = opUnTar
+		mode = s2sx.ModeUntar
 	}
 
 	// No args, use stdin/stdout
 	stdIn := len(args) == 1 && args[0] == "-"
 	if *stdout || stdIn {
 		// Write exec once to stdout
-		_, err = os.Stdout.Write(exec)
-		exitErr(err)
-		_, err = os.Stdout.Write([]byte{mode})
-		exitErr(err)
+		exitErr(s2sx.WriteHeader(os.Stdout, exec, mode))
 		written += int64(len(exec) + 1)
 	}
 
@@ -123,7 +115,8 @@ Options:`)
 		// Catch interrupt, so we don't exit at once.
 		// os.Stdin will return EOF, so we should be able to get everything.
 		signal.Notify(make(chan os.Signal, 1), os.Interrupt)
-		isCompressed, rd := isS2Input(os.Stdin)
+		isCompressed, rd, err := s2sx.IsStream(os.Stdin)
+		exitErr(err)
 		if isCompressed {
 			_, err := io.Copy(os.Stdout, rd)
 			exitErr(err)
@@ -149,7 +142,7 @@ Options:`)
 	for _, filename := range files {
 		func() {
 			var closeOnce sync.Once
-			dstFilename := fmt.Sprintf("%s%s", strings.TrimPrefix(filename, ".s2"), ".s2sx")
+			dstFilename := fmt.Sprintf("%s%s", strings.TrimSuffix(filename, ".s2"), ".s2sx")
 			if *goos == "windows" {
 				dstFilename += ".exe"
 			}
@@ -157,7 +150,8 @@ Options:`)
 			file, err := os.Open(filename)
 			exitErr(err)
 			defer closeOnce.Do(func() { file.Close() })
-			isCompressed, rd := isS2Input(file)
+			isCompressed, rd, err := s2sx.IsStream(file)
+			exitErr(err)
 			if !*quiet {
 				if !isCompressed {
 					fmt.Print("Compressing ", filename, " -> ", dstFilename, " for ", wantPlat)
@@ -201,9 +195,7 @@ Options:`)
 				bw := bufio.NewWriterSize(sw, 4<<20*2)
 				defer bw.Flush()
 				out = bw
-				_, err = out.Write(exec)
-				exitErr(err)
-				_, err = out.Write([]byte{mode})
+				err = s2sx.WriteHeader(out, exec, mode)
 			}
 			exitErr(err)
 			wc := wCounter{out: out}
@@ -266,17 +258,20 @@ func (w *wCounter) Write(p []byte) (n int, err error) {
 
 }
 
-func isS2Input(rd io.Reader) (bool, io.Reader) {
-	var tmp [4]byte
-	n, err := io.ReadFull(rd, tmp[:])
-	switch err {
-	case nil:
-		return bytes.Equal(tmp[:], []byte{0xff, 0x06, 0x00, 0x00}), io.MultiReader(bytes.NewBuffer(tmp[:]), rd)
-	case io.ErrUnexpectedEOF, io.EOF:
-		return false, bytes.NewBuffer(tmp[:n])
-	}
+// printPlatforms prints the embedded unpacker platforms.
+func printPlatforms() {
+	dir, err := embeddedFiles.ReadDir("sfx-exe")
 	exitErr(err)
-	return false, nil
+	found := false
+	for _, d := range dir {
+		if name, ok := strings.CutSuffix(d.Name(), ".s2"); ok {
+			_, _ = fmt.Fprintf(os.Stderr, " * %s\n", name)
+			found = true
+		}
+	}
+	if !found {
+		_, _ = fmt.Fprintln(os.Stderr, "No unpackers embedded. Run 'go generate' in s2/cmd/s2sx or use -unpacker.")
+	}
 }
 
 // toSize converts a size indication to bytes.
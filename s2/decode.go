@@ -23,6 +23,8 @@ var (
 	ErrTooLarge = errors.New("s2: decoded block is too large")
 	// ErrUnsupported reports that the input isn't supported.
 	ErrUnsupported = errors.New("s2: unsupported input")
+	// ErrTruncated reports that a stream ended before its stream trailer.
+	ErrTruncated = errors.New("s2: stream truncated")
 )
 
 // DecodedLen returns the length of the decoded block.
@@ -6,6 +6,7 @@ package s2
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -185,3 +186,65 @@ func TestDecoderMaxBlockSize(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockReader(t *testing.T) {
+	twain, err := os.ReadFile("testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random, err := os.ReadFile("testdata/random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		"empty":  nil,
+		"twain":  twain,
+		"random": random,
+		"repeat": bytes.Repeat(twain[:1000], 1000),
+		"long":   bytes.Repeat(append(twain, random...), 4),
+	}
+	encoders := map[string]func(dst, src []byte) []byte{
+		"default": Encode,
+		"better":  EncodeBetter,
+		"best":    EncodeBest,
+		"snappy":  EncodeSnappy,
+	}
+	for name, src := range inputs {
+		for encName, enc := range encoders {
+			block := enc(nil, src)
+			for _, window := range []int{0, 100, 64 << 10, 1 << 20, len(src) * 2} {
+				t.Run(fmt.Sprintf("%s-%s-%d", name, encName, window), func(t *testing.T) {
+					var got bytes.Buffer
+					br := NewBlockReader(bytes.NewReader(block), window)
+					n, err := br.WriteTo(&got)
+					if err != nil {
+						if window <= 0 || window >= len(src) || !errors.Is(err, ErrUnsupported) {
+							t.Fatal(err)
+						}
+						return
+					}
+					if n != int64(len(src)) || !bytes.Equal(got.Bytes(), src) {
+						t.Fatal("output mismatch")
+					}
+					// Read using small reads.
+					br.Reset(bytes.NewReader(block))
+					b, err := io.ReadAll(io.LimitReader(br, 1<<40))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(b, src) {
+						t.Fatal("output mismatch")
+					}
+				})
+			}
+		}
+	}
+
+	// Truncated and trailing data must be rejected.
+	block := EncodeBetter(nil, twain)
+	for _, b := range [][]byte{block[:len(block)-1], append(block[:len(block):len(block)], 0)} {
+		if _, err := NewBlockReader(bytes.NewReader(b), 0).WriteTo(io.Discard); err != ErrCorrupt {
+			t.Errorf("want ErrCorrupt, got %v", err)
+		}
+	}
+}
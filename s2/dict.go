@@ -72,6 +72,19 @@ func (d *Dict) Bytes() []byte {
 	return append(dst[:binary.PutUvarint(dst, uint64(d.repeat))], d.dict...)
 }
 
+// ID returns the ID of the dictionary.
+// The ID is a checksum of the serialized dictionary
+// and is used to identify the dictionary used by streams.
+func (d *Dict) ID() uint32 {
+	return crc(d.Bytes())
+}
+
+// appendDictChunk appends a dictionary ID chunk with the specified ID to dst.
+func appendDictChunk(dst []byte, id uint32) []byte {
+	dst = append(dst, chunkTypeDictID, dictIDSize, 0, 0)
+	return binary.LittleEndian.AppendUint32(dst, id)
+}
+
 // MakeDict will create a dictionary.
 // 'data' must be at least MinDictSize.
 // If data is longer than MaxDictSize only the last MaxDictSize bytes will be used.
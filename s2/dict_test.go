@@ -10,6 +10,7 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -339,6 +340,107 @@ func TestDictBest2(t *testing.T) {
 	}
 }
 
+func TestStreamDict(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var data []byte
+	for len(data) < 1<<20 {
+		data = fmt.Appendf(data, `{"id":%d,"name":"user-%x","email":"user%d@example.com","active":%t,"tags":["alpha","beta","gamma"]}`+"\n", rng.Intn(1e6), rng.Int63(), rng.Intn(1e4), rng.Intn(2) == 0)
+	}
+	d := MakeDict(data[:8<<10], []byte(`{"id":`))
+	if d == nil {
+		t.Fatal("no dict")
+	}
+	src := data[8<<10:]
+	opts := map[string][]WriterOption{
+		"fast":   nil,
+		"better": {WriterBetterCompression()},
+		"best":   {WriterBestCompression()},
+	}
+	for name, opt := range opts {
+		for _, conc := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s-c%d", name, conc), func(t *testing.T) {
+				var plain, dicted bytes.Buffer
+				opt := append([]WriterOption{WriterConcurrency(conc), WriterBlockSize(16 << 10)}, opt...)
+				enc := NewWriter(&plain, opt...)
+				if _, err := enc.Write(src); err != nil {
+					t.Fatal(err)
+				}
+				if err := enc.Close(); err != nil {
+					t.Fatal(err)
+				}
+				enc = NewWriter(&dicted, append(opt, WriterDict(d), WriterAddIndex())...)
+				if _, err := enc.Write(src); err != nil {
+					t.Fatal(err)
+				}
+				if err := enc.Close(); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("plain: %d, dict: %d", plain.Len(), dicted.Len())
+				if dicted.Len() >= plain.Len() {
+					t.Errorf("dictionary did not improve compression: %d >= %d", dicted.Len(), plain.Len())
+				}
+
+				// Read
+				got, err := io.ReadAll(NewReader(bytes.NewReader(dicted.Bytes()), ReaderDicts(d)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, src) {
+					t.Fatal("decoded mismatch")
+				}
+
+				// Decode concurrently
+				var buf bytes.Buffer
+				_, err = NewReader(bytes.NewReader(dicted.Bytes()), ReaderDicts(d)).DecodeConcurrent(&buf, 4)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), src) {
+					t.Fatal("concurrent decoded mismatch")
+				}
+
+				// Skip
+				dec := NewReader(bytes.NewReader(dicted.Bytes()), ReaderDicts(d))
+				const skip = 50000
+				if err := dec.Skip(skip); err != nil {
+					t.Fatal(err)
+				}
+				got, err = io.ReadAll(dec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, src[skip:]) {
+					t.Fatal("decoded mismatch after skip")
+				}
+
+				// Seek using index.
+				rs, err := NewReader(bytes.NewReader(dicted.Bytes()), ReaderDicts(d)).ReadSeeker(true, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tmp := make([]byte, 1000)
+				for _, off := range []int64{int64(len(src)) - 5000, 100000, 0} {
+					if _, err := rs.ReadAt(tmp, off); err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(tmp, src[off:off+int64(len(tmp))]) {
+						t.Fatalf("ReadAt mismatch at offset %d", off)
+					}
+				}
+
+				// Missing dictionary.
+				_, err = io.ReadAll(NewReader(bytes.NewReader(dicted.Bytes())))
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("want ErrUnsupported, got %v", err)
+				}
+			})
+		}
+	}
+	if err := NewWriter(io.Discard, WriterDict(d), WriterSnappyCompat()).Close(); err == nil {
+		t.Error("expected error using dictionary with snappy output")
+	}
+}
+
 func TestDictSize(t *testing.T) {
 	//f, err := os.Open("testdata/xlmeta.tar.s2")
 	//f, err := os.Open("testdata/broken.tar.s2")
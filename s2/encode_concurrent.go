@@ -0,0 +1,269 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"encoding/binary"
+	"runtime"
+	"sync"
+)
+
+const (
+	// minConcurrentSegment is the minimum size of segments encoded concurrently.
+	minConcurrentSegment = 256 << 10
+
+	// crossSegmentHistory is the size of the previous segment that can be referenced
+	// when ConcurrentCrossSegment is used.
+	crossSegmentHistory = 64 << 10
+)
+
+// ConcurrentOption is an option for EncodeBetterConcurrent and EncodeBestConcurrent.
+type ConcurrentOption func(*concurrentOptions)
+
+type concurrentOptions struct {
+	crossSegment bool
+}
+
+// ConcurrentCrossSegment will allow matches in each segment
+// to reference the last 64KB of the previous segment.
+// This improves compression, in particular with many segments,
+// at the cost of encoding the 64KB twice.
+func ConcurrentCrossSegment() ConcurrentOption {
+	return func(o *concurrentOptions) {
+		o.crossSegment = true
+	}
+}
+
+// EncodeBetterConcurrent returns the encoded form of src as a single block,
+// similar to EncodeBetter, but encoded using up to n goroutines.
+// If n <= 0, GOMAXPROCS goroutines are used.
+//
+// The input is split into segments that are encoded independently,
+// and concatenated as with ConcatBlocks.
+// This means matches cannot cross segment boundaries,
+// unless ConcurrentCrossSegment is specified.
+// Small inputs are encoded as a single segment.
+//
+// The dst and src must not overlap. It is valid to pass a nil dst.
+func EncodeBetterConcurrent(dst, src []byte, n int, opts ...ConcurrentOption) []byte {
+	return encodeConcurrent(dst, src, n, levelBetter, opts)
+}
+
+// EncodeBestConcurrent returns the encoded form of src as a single block,
+// similar to EncodeBest, but encoded using up to n goroutines.
+// If n <= 0, GOMAXPROCS goroutines are used.
+//
+// The input is split into segments that are encoded independently,
+// and concatenated as with ConcatBlocks.
+// This means matches cannot cross segment boundaries,
+// unless ConcurrentCrossSegment is specified.
+// Small inputs are encoded as a single segment.
+//
+// The dst and src must not overlap. It is valid to pass a nil dst.
+func EncodeBestConcurrent(dst, src []byte, n int, opts ...ConcurrentOption) []byte {
+	return encodeConcurrent(dst, src, n, levelBest, opts)
+}
+
+func encodeConcurrent(dst, src []byte, n int, level uint8, opts []ConcurrentOption) []byte {
+	var o concurrentOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	n = min(n, len(src)/minConcurrentSegment)
+	if n <= 1 {
+		if level == levelBest {
+			return EncodeBest(dst, src)
+		}
+		return EncodeBetter(dst, src)
+	}
+	if MaxEncodedLen(len(src)) < 0 {
+		panic(ErrTooLarge)
+	}
+
+	// Encode segments without the block header.
+	segSize := (len(src) + n - 1) / n
+	segments := make([][]byte, n)
+	var wg sync.WaitGroup
+	for i := range segments {
+		start := i * segSize
+		end := min(start+segSize, len(src))
+		// Encode the history with the segment and remove it after.
+		hist := 0
+		if o.crossSegment {
+			hist = min(start, crossSegmentHistory)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seg := src[start-hist : end]
+			var enc []byte
+			if level == levelBest {
+				enc = EncodeBest(nil, seg)
+			} else {
+				enc = EncodeBetter(nil, seg)
+			}
+			// Remove the block header.
+			var tmp [binary.MaxVarintLen64]byte
+			enc = enc[binary.PutUvarint(tmp[:], uint64(len(seg))):]
+			if hist > 0 {
+				enc = skipBlockOutput(enc, seg, hist)
+			}
+			segments[i] = enc
+		}()
+	}
+	wg.Wait()
+
+	var tmp [binary.MaxVarintLen64]byte
+	hdr := binary.PutUvarint(tmp[:], uint64(len(src)))
+	size := hdr
+	for _, seg := range segments {
+		size += len(seg)
+	}
+	if maxSize := MaxEncodedLen(len(src)); size > maxSize {
+		// Incompressible segments are stored as separate literals,
+		// which can exceed MaxEncodedLen. Store src as a single literal instead.
+		if cap(dst) < maxSize {
+			dst = make([]byte, maxSize)
+		} else {
+			dst = dst[:maxSize]
+		}
+		d := binary.PutUvarint(dst, uint64(len(src)))
+		d += emitLiteral(dst[d:], src)
+		return dst[:d]
+	}
+	if cap(dst) < size {
+		dst = make([]byte, 0, size)
+	}
+	dst = append(dst[:0], tmp[:hdr]...)
+	for _, seg := range segments {
+		dst = append(dst, seg...)
+	}
+	return dst
+}
+
+// skipBlockOutput removes the operations producing the first n bytes of output
+// from the encoded block b without header. src is the uncompressed content.
+// Operations crossing n are split.
+// Copies in the remaining block may reference the removed output.
+func skipBlockOutput(b, src []byte, n int) []byte {
+	var tmp [16]byte
+	pos, offset := 0, 0
+	for s := 0; s < len(b); {
+		var length, c int
+		switch b[s] & 0x03 {
+		case tagLiteral:
+			length, c = decodeLiteral(b[s:])
+			s += c + length
+			if pos+length > n {
+				// Emit the remaining literals.
+				out := make([]byte, 0, pos+length-n+5+len(b)-s)
+				out = out[:emitLiteral(out[:cap(out)], src[n:pos+length])]
+				return append(out, fixFirstRepeat(b[s:], offset)...)
+			}
+			pos += length
+			continue
+		case tagCopy1:
+			length, c = decodeCopy1(b[s:])
+			if b[s]&0xe0 != 0 || b[s+1] != 0 {
+				offset = int(b[s]&0xe0)<<3 | int(b[s+1])
+			}
+		case tagCopy2:
+			length = 1 + int(b[s])>>2
+			offset = int(binary.LittleEndian.Uint16(b[s+1:]))
+			c = 3
+		default:
+			length = 1 + int(b[s])>>2
+			offset = int(binary.LittleEndian.Uint32(b[s+1:]))
+			c = 5
+		}
+		s += c
+		pos += length
+		if pos <= n {
+			continue
+		}
+		// Emit the remaining copy.
+		if left := pos - n; left >= 4 {
+			c = emitCopy(tmp[:], offset, left)
+			return append(tmp[:c:c], b[s:]...)
+		}
+		c = emitLiteral(tmp[:], src[n:pos])
+		return append(tmp[:c:c], fixFirstRepeat(b[s:], offset)...)
+	}
+	return nil
+}
+
+// decodeLiteral returns the length and the header size of a literal operation.
+func decodeLiteral(b []byte) (length, n int) {
+	x := uint32(b[0] >> 2)
+	switch {
+	case x < 60:
+		n = 1
+	case x == 60:
+		x = uint32(b[1])
+		n = 2
+	case x == 61:
+		x = uint32(b[1]) | uint32(b[2])<<8
+		n = 3
+	case x == 62:
+		x = uint32(b[1]) | uint32(b[2])<<8 | uint32(b[3])<<16
+		n = 4
+	default:
+		x = binary.LittleEndian.Uint32(b[1:])
+		n = 5
+	}
+	return int(x) + 1, n
+}
+
+// decodeCopy1 returns the length and the encoded size of a tagCopy1 operation,
+// which may be a repeat.
+func decodeCopy1(b []byte) (length, n int) {
+	length = int(b[0]>>2) & 0x7
+	n = 2
+	if b[0]&0xe0 == 0 && b[1] == 0 {
+		// Repeat
+		switch length {
+		case 5:
+			length = int(b[2]) + 4
+			n = 3
+		case 6:
+			length = int(binary.LittleEndian.Uint16(b[2:])) + 1<<8
+			n = 4
+		case 7:
+			length = (int(b[2]) | int(b[3])<<8 | int(b[4])<<16) + 1<<16
+			n = 5
+		}
+	}
+	return length + 4, n
+}
+
+// fixFirstRepeat will replace a repeat before the first copy
+// in the encoded block b with a copy with the given offset.
+// This is needed when the offset of the previous copy changes.
+func fixFirstRepeat(b []byte, offset int) []byte {
+	for s := 0; s < len(b); {
+		switch b[s] & 0x03 {
+		case tagLiteral:
+			length, n := decodeLiteral(b[s:])
+			s += n + length
+		case tagCopy1:
+			if b[s]&0xe0 != 0 || b[s+1] != 0 {
+				return b
+			}
+			length, n := decodeCopy1(b[s:])
+			var tmp [16]byte
+			c := emitCopy(tmp[:], offset, length)
+			out := make([]byte, 0, len(b)-n+c)
+			out = append(out, b[:s]...)
+			out = append(out, tmp[:c]...)
+			return append(out, b[s+n:]...)
+		default:
+			return b
+		}
+	}
+	return b
+}
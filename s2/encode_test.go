@@ -6,8 +6,10 @@ package s2
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -67,3 +69,176 @@ func TestEncodeHuge(t *testing.T) {
 	}
 	test(t, make([]byte, MaxBlockSize))
 }
+
+func TestEncodeConcurrent(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	// Random text with long range repeats, so segments reference previous segments.
+	chunk := make([]byte, 5000)
+	for i := range chunk {
+		chunk[i] = 'a' + uint8(rng.Intn(26))
+	}
+	var src []byte
+	for len(src) < 4<<20 {
+		src = append(src, chunk[rng.Intn(len(chunk)/2):]...)
+		src = append(src, uint8(rng.Intn(256)))
+	}
+	random := make([]byte, 2<<20)
+	rng.Read(random)
+
+	for _, size := range []int{0, 100, minConcurrentSegment * 2, 1<<20 + 12345, len(src)} {
+		for _, data := range [][]byte{src[:size], random[:min(size, len(random))]} {
+			for _, n := range []int{0, 1, 3, 16} {
+				for _, cross := range []bool{false, true} {
+					var opts []ConcurrentOption
+					if cross {
+						opts = append(opts, ConcurrentCrossSegment())
+					}
+					for name, fn := range map[string]func(dst, src []byte, n int, opts ...ConcurrentOption) []byte{
+						"better": EncodeBetterConcurrent,
+						"best":   EncodeBestConcurrent,
+					} {
+						comp := fn(nil, data, n, opts...)
+						got, err := Decode(nil, comp)
+						if err != nil {
+							t.Fatalf("%s size %d, n %d, cross %v: %v", name, len(data), n, cross, err)
+						}
+						if !bytes.Equal(got, data) {
+							t.Fatalf("%s size %d, n %d, cross %v: output mismatch", name, len(data), n, cross)
+						}
+						if len(data) == len(src) {
+							t.Logf("%s n=%d cross=%v: %d -> %d", name, n, cross, len(data), len(comp))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFixFirstRepeat(t *testing.T) {
+	// History ending with a copy with offset 1.
+	hist := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	histEnc := make([]byte, len(hist)+10)
+	n := emitLiteral(histEnc, hist)
+	n += emitCopy(histEnc[n:], 1, 4)
+	histEnc = histEnc[:n]
+	hist = append(hist, "ffff"...)
+
+	for _, length := range []int{4, 8, 12, 100, 1000, 70000} {
+		// Literal, repeat and literal.
+		var b [100]byte
+		n := emitLiteral(b[:], []byte("xyz"))
+		n += emitRepeat(b[n:], 16, length)
+		n += emitLiteral(b[n:], []byte("z"))
+		fixed := fixFirstRepeat(b[:n], 16)
+
+		want := append(append([]byte{}, hist...), "xyz"...)
+		for range length {
+			want = append(want, want[len(want)-16])
+		}
+		want = append(want, 'z')
+		block := binary.AppendUvarint(nil, uint64(len(want)))
+		block = append(block, histEnc...)
+		block = append(block, fixed...)
+		got, err := Decode(nil, block)
+		if err != nil {
+			t.Fatalf("length %d: %v", length, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("length %d: output mismatch", length)
+		}
+	}
+}
+
+func TestSkipBlockOutput(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	var src []byte
+	for len(src) < 200000 {
+		if rng.Intn(2) == 0 {
+			src = append(src, uint8(rng.Intn(256)))
+			continue
+		}
+		// Repeat previous content, often with the same offset.
+		offset := 1 + rng.Intn(min(len(src)+1, 100))
+		if rng.Intn(4) == 0 {
+			offset = 1 + rng.Intn(len(src)+1)
+		}
+		for range 1 + rng.Intn(100) {
+			if offset > len(src) {
+				src = append(src, 0)
+				continue
+			}
+			src = append(src, src[len(src)-offset])
+		}
+	}
+	for _, enc := range [][]byte{EncodeBetter(nil, src), EncodeBest(nil, src)} {
+		_, hdr, err := decodedLen(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc = enc[hdr:]
+		for range 1000 {
+			n := 1 + rng.Intn(len(src)-1)
+			block := binary.AppendUvarint(nil, uint64(len(src)))
+			lit := make([]byte, n+5)
+			block = append(block, lit[:emitLiteral(lit, src[:n])]...)
+			block = append(block, skipBlockOutput(enc, src, n)...)
+			got, err := Decode(nil, block)
+			if err != nil {
+				t.Fatalf("n=%d: %v", n, err)
+			}
+			if !bytes.Equal(got, src) {
+				t.Fatalf("n=%d: output mismatch", n)
+			}
+		}
+	}
+}
+
+func TestEncodeConcurrentIncompressible(t *testing.T) {
+	src := make([]byte, 4<<20)
+	rand.New(rand.NewSource(0)).Read(src)
+	for _, enc := range []func(dst, src []byte, n int, opts ...ConcurrentOption) []byte{EncodeBetterConcurrent, EncodeBestConcurrent} {
+		for _, n := range []int{2, 8} {
+			dst := enc(nil, src, n)
+			if len(dst) > MaxEncodedLen(len(src)) {
+				t.Fatalf("n=%d: output %d > MaxEncodedLen %d", n, len(dst), MaxEncodedLen(len(src)))
+			}
+			got, err := Decode(nil, dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, src) {
+				t.Fatalf("n=%d: output mismatch", n)
+			}
+		}
+	}
+}
+
+func BenchmarkEncodeConcurrent(b *testing.B) {
+	data := expand(readFile(b, "../testdata/Mark.Twain-Tom.Sawyer.txt"), 4<<20)
+	dst := make([]byte, MaxEncodedLen(len(data))+100)
+	for _, n := range []int{1, 4, 0} {
+		for _, cross := range []bool{false, true} {
+			var opts []ConcurrentOption
+			if cross {
+				opts = append(opts, ConcurrentCrossSegment())
+			}
+			b.Run(fmt.Sprintf("better-n%d-cross-%v", n, cross), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					dst = EncodeBetterConcurrent(dst, data, n, opts...)
+				}
+				b.ReportMetric(100*float64(len(dst))/float64(len(data)), "pct")
+			})
+			b.Run(fmt.Sprintf("best-n%d-cross-%v", n, cross), func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					dst = EncodeBestConcurrent(dst, data, n, opts...)
+				}
+				b.ReportMetric(100*float64(len(dst))/float64(len(data)), "pct")
+			})
+		}
+	}
+}
@@ -30,6 +30,7 @@ type Index struct {
 		uncompressedOffset int64
 	}
 	estBlockUncomp int64
+	streams        []int64 // Compressed start offsets of merged streams.
 }
 
 func (i *Index) reset(maxBlock int) {
@@ -39,6 +40,7 @@ func (i *Index) reset(maxBlock int) {
 	if len(i.info) > 0 {
 		i.info = i.info[:0]
 	}
+	i.streams = i.streams[:0]
 }
 
 // allocInfos will allocate an empty slice of infos.
@@ -374,42 +376,111 @@ func (i *Index) Load(b []byte) ([]byte, error) {
 }
 
 // LoadStream will load an index from the end of the supplied stream.
+// If the stream consists of concatenated streams, each with an index appended,
+// the indexes are loaded backwards from the end and chained,
+// so the index covers all the streams.
+// Chaining stops at the first preceding stream without an index,
+// and the index will only cover the streams after it.
+// Uncompressed offsets in the index will then be relative to the start
+// of the first indexed stream, and will not match the offsets of regular
+// reads from the start of the input, which include the preceding streams.
+// Compressed offsets are always relative to the start of rs.
+// Streams written with padding do not store their compressed size,
+// so indexes of preceding streams cannot be located.
+// Chained streams may use different dictionaries.
 // ErrUnsupported will be returned if the signature cannot be found.
 // ErrCorrupt will be returned if unexpected values are found.
 // io.ErrUnexpectedEOF is returned if there are too few bytes.
 // IO errors are returned as-is.
 func (i *Index) LoadStream(rs io.ReadSeeker) error {
-	// Go to end.
-	_, err := rs.Seek(-10, io.SeekEnd)
+	end, err := rs.Seek(0, io.SeekEnd)
 	if err != nil {
 		return err
 	}
-	var tmp [10]byte
+	start, err := i.loadAt(rs, end)
+	if err != nil || start == 0 {
+		return err
+	}
+
+	// Load indexes of preceding streams.
+	type stream struct {
+		idx   Index
+		start int64
+	}
+	streams := []stream{{idx: *i, start: start}}
+	for start > 0 {
+		var s stream
+		s.start, err = s.idx.loadAt(rs, start)
+		if err != nil {
+			// Preceding data has no index.
+			break
+		}
+		streams = append(streams, s)
+		start = s.start
+	}
+	var merged Index
+	merged.reset(0)
+	var uncompOffset int64
+	for n := len(streams) - 1; n >= 0; n-- {
+		s := &streams[n]
+		if err := merged.Merge(&s.idx, s.start, uncompOffset); err != nil {
+			return err
+		}
+		uncompOffset += s.idx.TotalUncompressed
+	}
+	*i = merged
+	return nil
+}
+
+// loadAt loads the index ending at offset end of rs.
+// The start offset of the stream described by the index is returned.
+// If the compressed size of the stream is unknown, 0 is returned.
+func (i *Index) loadAt(rs io.ReadSeeker, end int64) (start int64, err error) {
+	var tmp [4 + len(S2IndexTrailer)]byte
+	if end < int64(len(tmp)) {
+		return 0, ErrUnsupported
+	}
+	_, err = rs.Seek(end-int64(len(tmp)), io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
 	_, err = io.ReadFull(rs, tmp[:])
 	if err != nil {
-		return err
+		return 0, err
 	}
 	// Check trailer...
 	if !bytes.Equal(tmp[4:4+len(S2IndexTrailer)], []byte(S2IndexTrailer)) {
-		return ErrUnsupported
+		return 0, ErrUnsupported
 	}
 	sz := binary.LittleEndian.Uint32(tmp[:4])
 	if sz > maxChunkSize+skippableFrameHeader {
-		return ErrCorrupt
+		return 0, ErrCorrupt
+	}
+	if int64(sz) > end {
+		return 0, io.ErrUnexpectedEOF
 	}
-	_, err = rs.Seek(-int64(sz), io.SeekEnd)
+	_, err = rs.Seek(end-int64(sz), io.SeekStart)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Read index.
 	buf := make([]byte, sz)
 	_, err = io.ReadFull(rs, buf)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = i.Load(buf)
-	return err
+	if _, err = i.Load(buf); err != nil {
+		return 0, err
+	}
+	if i.TotalCompressed < 0 {
+		return 0, nil
+	}
+	start = end - int64(sz) - i.TotalCompressed
+	if start < 0 {
+		return 0, ErrCorrupt
+	}
+	return start, nil
 }
 
 // IndexStream will return an index for a stream.
@@ -500,6 +571,12 @@ func IndexStream(r io.Reader) ([]byte, error) {
 				}
 			}
 
+			continue
+		case chunkTypeDictID:
+			// Dictionary ID. Blocks are not decoded, so no dictionary is needed.
+			if chunkLen != dictIDSize {
+				return nil, ErrCorrupt
+			}
 			continue
 		}
 
@@ -515,6 +592,167 @@ func IndexStream(r io.Reader) ([]byte, error) {
 	}
 }
 
+// Merge will append the entries of other to the index.
+// The stream described by other must start at compressedOffset
+// and uncompressedOffset of the stream described by i,
+// which must be at or after the end of the current entries.
+// The total sizes of i are updated to include other.
+// If the compressed size of other is unknown, the total compressed size will be -1.
+// This can be used to create an index for concatenated streams.
+func (i *Index) Merge(other *Index, compressedOffset, uncompressedOffset int64) error {
+	if other.TotalUncompressed < 0 {
+		return ErrUnsupported
+	}
+	if compressedOffset < 0 || uncompressedOffset < 0 {
+		return ErrCorrupt
+	}
+	if (i.TotalUncompressed > 0 && uncompressedOffset < i.TotalUncompressed) ||
+		(i.TotalCompressed > 0 && compressedOffset < i.TotalCompressed) {
+		return fmt.Errorf("s2: merge offset (%d, %d) before end of index (%d, %d)", compressedOffset, uncompressedOffset, i.TotalCompressed, i.TotalUncompressed)
+	}
+	if i.estBlockUncomp == 0 || (other.estBlockUncomp > 0 && other.estBlockUncomp < i.estBlockUncomp) {
+		i.estBlockUncomp = other.estBlockUncomp
+	}
+	if len(i.streams) == 0 && len(i.info) > 0 {
+		i.streams = append(i.streams, 0)
+	}
+	if len(other.streams) > 0 {
+		for _, start := range other.streams {
+			i.streams = append(i.streams, start+compressedOffset)
+		}
+	} else {
+		i.streams = append(i.streams, compressedOffset)
+	}
+	for _, info := range other.info {
+		c, u := info.compressedOffset+compressedOffset, info.uncompressedOffset+uncompressedOffset
+		if lastIdx := len(i.info) - 1; lastIdx >= 0 {
+			latest := i.info[lastIdx]
+			if latest.uncompressedOffset == u {
+				// Previous stream has no content after the entry.
+				i.info[lastIdx].compressedOffset = c
+				continue
+			}
+			if latest.uncompressedOffset > u || latest.compressedOffset > c {
+				return fmt.Errorf("s2: merge offset (%d, %d) before last entry (%d, %d)", c, u, latest.compressedOffset, latest.uncompressedOffset)
+			}
+		}
+		i.info = append(i.info, struct {
+			compressedOffset   int64
+			uncompressedOffset int64
+		}{compressedOffset: c, uncompressedOffset: u})
+	}
+	i.TotalUncompressed = uncompressedOffset + other.TotalUncompressed
+	i.TotalCompressed = -1
+	if other.TotalCompressed >= 0 {
+		i.TotalCompressed = compressedOffset + other.TotalCompressed
+	}
+	return nil
+}
+
+// ConcatStreams will concatenate the supplied S2 or Snappy streams and write
+// the result to dst, followed by a single index covering all streams.
+// The combined stream can be used with Reader.ReadSeeker.
+//
+// Indexes appended to the input streams are used if present,
+// otherwise the streams are indexed using IndexStream.
+// Indexes at the end of the input streams are not copied.
+// All streams must use the same dictionary, or no dictionary.
+// The input streams are read from the start.
+func ConcatStreams(dst io.Writer, srcs ...io.ReadSeeker) error {
+	var merged Index
+	merged.reset(0)
+	var compOffset, uncompOffset int64
+	var dictID []byte
+	for n, src := range srcs {
+		var idx Index
+		size, dataSize, err := loadConcatIndex(&idx, src)
+		if err != nil {
+			return fmt.Errorf("s2: stream %d: %w", n, err)
+		}
+
+		// Check the dictionary used by the stream.
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		var hdr [dictChunkLen + len(magicChunk)]byte
+		hn, err := io.ReadFull(src, hdr[:min(int64(len(hdr)), size)])
+		if err != nil {
+			return err
+		}
+		var id []byte
+		if hn == len(hdr) && hdr[len(magicChunk)] == chunkTypeDictID {
+			id = hdr[len(magicChunk):]
+		}
+		if n > 0 && dataSize > 0 && !bytes.Equal(id, dictID) {
+			return fmt.Errorf("%w: stream %d dictionary mismatch", ErrUnsupported, n)
+		}
+		if dataSize > 0 {
+			dictID = id
+		}
+
+		// Copy stream without index.
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, src, dataSize); err != nil {
+			return err
+		}
+		if err := merged.Merge(&idx, compOffset, uncompOffset); err != nil {
+			return err
+		}
+		compOffset += dataSize
+		uncompOffset += idx.TotalUncompressed
+	}
+	if merged.estBlockUncomp == 0 {
+		merged.estBlockUncomp = maxBlockSize
+	}
+	index := merged.appendTo(nil, uncompOffset, compOffset)
+	n, err := dst.Write(index)
+	if err == nil && n != len(index) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// loadConcatIndex loads the index of the stream, or indexes it if not present.
+// The size of the stream and size without an appended index is returned.
+func loadConcatIndex(idx *Index, rs io.ReadSeeker) (size, dataSize int64, err error) {
+	size, err = rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if size > 10 {
+		err = idx.LoadStream(rs)
+		switch err {
+		case nil:
+			// Read the index size from the end of the stream.
+			var tmp [4]byte
+			if _, err := rs.Seek(-10, io.SeekEnd); err != nil {
+				return 0, 0, err
+			}
+			if _, err := io.ReadFull(rs, tmp[:]); err != nil {
+				return 0, 0, err
+			}
+			return size, size - int64(binary.LittleEndian.Uint32(tmp[:])), nil
+		case ErrUnsupported:
+		default:
+			return 0, 0, err
+		}
+	}
+	// No index, create one.
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	b, err := IndexStream(rs)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := idx.Load(b); err != nil {
+		return 0, 0, err
+	}
+	return size, size, nil
+}
+
 // JSON returns the index as JSON text.
 func (i *Index) JSON() []byte {
 	type offset struct {
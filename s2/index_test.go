@@ -3,6 +3,7 @@ package s2_test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -296,6 +297,380 @@ func TestSeeking(t *testing.T) {
 	})
 }
 
+func TestConcatStreams(t *testing.T) {
+	opts := [][]s2.WriterOption{
+		{s2.WriterBlockSize(16 << 10), s2.WriterAddIndex()},
+		{s2.WriterBlockSize(64 << 10)},
+		{s2.WriterSnappyCompat(), s2.WriterAddIndex()},
+		{s2.WriterBetterCompression(), s2.WriterPadding(4 << 10), s2.WriterAddIndex()},
+		{s2.WriterAddIndex()},
+		{s2.WriterConcurrency(1), s2.WriterBestCompression()},
+	}
+	// Records of 25 bytes, numbered across streams.
+	var want []byte
+	var srcs []io.ReadSeeker
+	rec := 0
+	for i, opt := range opts {
+		var buf bytes.Buffer
+		enc := s2.NewWriter(&buf, opt...)
+		n := 100_000 * i
+		if i == 4 {
+			// Empty stream.
+			n = 0
+		}
+		start := len(want)
+		for end := rec + n; rec < end; rec++ {
+			want = fmt.Appendf(want, "Item %019d\n", rec)
+		}
+		if _, err := enc.Write(want[start:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, bytes.NewReader(buf.Bytes()))
+	}
+	var dst bytes.Buffer
+	if err := s2.ConcatStreams(&dst, srcs...); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(s2.NewReader(bytes.NewReader(dst.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("output mismatch")
+	}
+
+	var idx s2.Index
+	if err := idx.LoadStream(bytes.NewReader(dst.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if idx.TotalUncompressed != int64(len(want)) {
+		t.Fatalf("want total uncompressed %d, got %d", len(want), idx.TotalUncompressed)
+	}
+	t.Logf("%d records, %d bytes", rec, dst.Len())
+
+	dec := s2.NewReader(bytes.NewReader(dst.Bytes()))
+	seeker, err := dec.ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 25)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 10000; i++ {
+		r := rng.Intn(rec)
+		if _, err := seeker.ReadAt(buf, int64(r*25)); err != nil {
+			t.Fatalf("Failed to read record %d: %v", r, err)
+		}
+		expected := fmt.Sprintf("Item %019d\n", r)
+		if string(buf) != expected {
+			t.Fatalf("Expected %q, got %q", expected, buf)
+		}
+	}
+
+	// Streams with different dictionaries cannot be concatenated.
+	dict := s2.MakeDict(want[:64<<10], nil)
+	var a, b bytes.Buffer
+	for _, w := range []*s2.Writer{s2.NewWriter(&a, s2.WriterDict(dict)), s2.NewWriter(&b)} {
+		w.Write(want[:100<<10])
+		w.Close()
+	}
+	err = s2.ConcatStreams(io.Discard, bytes.NewReader(a.Bytes()), bytes.NewReader(b.Bytes()))
+	if !errors.Is(err, s2.ErrUnsupported) {
+		t.Fatalf("want ErrUnsupported, got %v", err)
+	}
+}
+
+func TestSeekAppendedStreams(t *testing.T) {
+	opts := [][]s2.WriterOption{
+		{s2.WriterBlockSize(16 << 10), s2.WriterAddIndex()},
+		{s2.WriterSnappyCompat(), s2.WriterAddIndex()},
+		{s2.WriterAddIndex()},
+		{s2.WriterBetterCompression(), s2.WriterAddIndex()},
+	}
+	// Records of 25 bytes, numbered across streams.
+	var want, file []byte
+	rec := 0
+	for i, opt := range opts {
+		var buf bytes.Buffer
+		enc := s2.NewWriter(&buf, opt...)
+		n := 100_000 * (i + 1)
+		start := len(want)
+		for end := rec + n; rec < end; rec++ {
+			want = fmt.Appendf(want, "Item %019d\n", rec)
+		}
+		if _, err := enc.Write(want[start:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		file = append(file, buf.Bytes()...)
+	}
+
+	var idx s2.Index
+	if err := idx.LoadStream(bytes.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+	if idx.TotalUncompressed != int64(len(want)) {
+		t.Fatalf("want total uncompressed %d, got %d", len(want), idx.TotalUncompressed)
+	}
+
+	dec := s2.NewReader(bytes.NewReader(file))
+	seeker, err := dec.ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 25)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		r := rng.Intn(rec)
+		if _, err := seeker.Seek(int64(r*25), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(seeker, buf); err != nil {
+			t.Fatalf("Failed to read record %d: %v", r, err)
+		}
+		expected := fmt.Sprintf("Item %019d\n", r)
+		if string(buf) != expected {
+			t.Fatalf("Expected %q, got %q", expected, buf)
+		}
+		r = rng.Intn(rec)
+		if _, err := seeker.ReadAt(buf, int64(r*25)); err != nil {
+			t.Fatalf("Failed to read record %d: %v", r, err)
+		}
+		expected = fmt.Sprintf("Item %019d\n", r)
+		if string(buf) != expected {
+			t.Fatalf("Expected %q, got %q", expected, buf)
+		}
+	}
+
+	// The first stream has no index, so the index only covers the following streams.
+	// Offsets are relative to the first indexed stream.
+	prefix := bytes.Repeat([]byte("prefix\n"), 1000)
+	var noIdx bytes.Buffer
+	enc := s2.NewWriter(&noIdx)
+	enc.Write(prefix)
+	enc.Close()
+	file = append(noIdx.Bytes(), file...)
+	if err := idx.LoadStream(bytes.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+	if idx.TotalUncompressed != int64(len(want)) {
+		t.Fatalf("want total uncompressed %d, got %d", len(want), idx.TotalUncompressed)
+	}
+	if c, u, err := idx.Find(0); err != nil || u != 0 || c < int64(noIdx.Len()) {
+		t.Fatalf("Find(0): got (%d, %d, %v), want compressed offset >= %d", c, u, err, noIdx.Len())
+	}
+	seeker, err = s2.NewReader(bytes.NewReader(file)).ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reading starts at the first indexed stream, so offsets match the index.
+	for i := 0; i < 2; i++ {
+		if _, err := io.ReadFull(seeker, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != fmt.Sprintf("Item %019d\n", 0) {
+			t.Fatalf("Expected first record, got %q", buf)
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		r := rng.Intn(rec)
+		if _, err := seeker.ReadAt(buf, int64(r*25)); err != nil {
+			t.Fatalf("Failed to read record %d: %v", r, err)
+		}
+		expected := fmt.Sprintf("Item %019d\n", r)
+		if string(buf) != expected {
+			t.Fatalf("Expected %q, got %q", expected, buf)
+		}
+	}
+
+	// Non-s2 data before the streams.
+	file = append([]byte("not an s2 stream"), file[noIdx.Len():]...)
+	if err := idx.LoadStream(bytes.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+	if idx.TotalUncompressed != int64(len(want)) {
+		t.Fatalf("want total uncompressed %d, got %d", len(want), idx.TotalUncompressed)
+	}
+}
+
+func TestReadAtAppendedDicts(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 1<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	dicts := []*s2.Dict{
+		s2.MakeDict(want[:32<<10], nil),
+		nil,
+		s2.MakeDict(want[len(want)-32<<10:], nil),
+	}
+	var file []byte
+	split := len(want) / len(dicts)
+	for i, d := range dicts {
+		opts := []s2.WriterOption{s2.WriterBlockSize(16 << 10), s2.WriterAddIndex()}
+		if d != nil {
+			opts = append(opts, s2.WriterDict(d))
+		}
+		var buf bytes.Buffer
+		enc := s2.NewWriter(&buf, opts...)
+		end := split * (i + 1)
+		if i == len(dicts)-1 {
+			end = len(want)
+		}
+		if _, err := enc.Write(want[split*i : end]); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		file = append(file, buf.Bytes()...)
+	}
+	seeker, err := s2.NewReader(bytes.NewReader(file), s2.ReaderDicts(dicts[0], dicts[2]), s2.ReaderBlockCache(0)).ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(0))
+	got := make([]byte, 50<<10)
+	for i := 0; i < 100; i++ {
+		off := rng.Int63n(int64(len(want) - len(got)))
+		if _, err := seeker.ReadAt(got, off); err != nil {
+			t.Fatalf("offset %d: %v", off, err)
+		}
+		if !bytes.Equal(got, want[off:off+int64(len(got))]) {
+			t.Fatalf("offset %d: output mismatch", off)
+		}
+	}
+}
+
+func TestSeekAppendedDicts(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 1<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	dict := s2.MakeDict(want[:32<<10], nil)
+	split := len(want) / 2
+	var file []byte
+	for i, d := range []*s2.Dict{dict, nil} {
+		opts := []s2.WriterOption{s2.WriterBlockSize(16 << 10), s2.WriterAddIndex()}
+		if d != nil {
+			opts = append(opts, s2.WriterDict(d))
+		}
+		var buf bytes.Buffer
+		enc := s2.NewWriter(&buf, opts...)
+		if _, err := enc.Write(want[split*i : split*(i+1)]); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		file = append(file, buf.Bytes()...)
+	}
+	want = want[:split*2]
+	seeker, err := s2.NewReader(bytes.NewReader(file), s2.ReaderDicts(dict)).ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read from the first into the second stream, then seek back and forth.
+	got := make([]byte, 100<<10)
+	for _, off := range []int64{int64(split) - 50<<10, 100 << 10, int64(split) + 100<<10, 0, int64(split)} {
+		if _, err := seeker.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(seeker, got); err != nil {
+			t.Fatalf("offset %d: %v", off, err)
+		}
+		if !bytes.Equal(got, want[off:off+int64(len(got))]) {
+			t.Fatalf("offset %d: output mismatch", off)
+		}
+	}
+}
+
+func TestReadAtConcurrent(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 8<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	dict := s2.MakeDict(want[:32<<10], nil)
+	tests := map[string]struct {
+		wOpts []s2.WriterOption
+		rOpts []s2.ReaderOption
+	}{
+		"nocache":      {wOpts: []s2.WriterOption{s2.WriterBlockSize(16 << 10)}, rOpts: []s2.ReaderOption{s2.ReaderBlockCache(0)}},
+		"cache":        {wOpts: []s2.WriterOption{s2.WriterBlockSize(16 << 10)}, rOpts: []s2.ReaderOption{s2.ReaderBlockCache(8)}},
+		"uncompressed": {wOpts: []s2.WriterOption{s2.WriterUncompressed(), s2.WriterPadding(1000)}, rOpts: []s2.ReaderOption{s2.ReaderBlockCache(2)}},
+		"dict":         {wOpts: []s2.WriterOption{s2.WriterDict(dict), s2.WriterBlockSize(64 << 10)}, rOpts: []s2.ReaderOption{s2.ReaderDicts(dict), s2.ReaderBlockCache(4)}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := s2.NewWriter(&buf, test.wOpts...)
+			if _, err := enc.Write(want); err != nil {
+				t.Fatal(err)
+			}
+			index, err := enc.CloseIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := s2.NewReader(bytes.NewReader(buf.Bytes()), test.rOpts...)
+			seeker, err := dec.ReadSeeker(true, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					rng := rand.New(rand.NewSource(int64(g)))
+					var got []byte
+					for i := 0; i < 1000; i++ {
+						off := rng.Int63n(int64(len(want)))
+						if i%10 == 0 {
+							// Read a few blocks.
+							got = make([]byte, rng.Intn(100<<10))
+						} else {
+							got = make([]byte, rng.Intn(100))
+						}
+						n, err := seeker.ReadAt(got, off)
+						end := min(off+int64(len(got)), int64(len(want)))
+						if n != int(end-off) {
+							t.Errorf("offset %d: want %d bytes, got %d", off, end-off, n)
+							return
+						}
+						if err != nil && (err != io.EOF || n == len(got)) {
+							t.Errorf("offset %d, length %d: %v", off, len(got), err)
+							return
+						}
+						if !bytes.Equal(got[:n], want[off:end]) {
+							t.Errorf("offset %d: output mismatch", off)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			// ReadAt must not affect regular reads.
+			got, err := io.ReadAll(dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatal("output mismatch")
+			}
+			if n, err := seeker.ReadAt(make([]byte, 10), int64(len(want))); n != 0 || err != io.EOF {
+				t.Fatalf("want EOF, got %d, %v", n, err)
+			}
+		})
+	}
+}
+
 // ExampleIndexStream shows an example of indexing a stream
 // and indexing it after it has been written.
 // The index can either be appended.
@@ -0,0 +1,241 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const lineIndexHeader = "s2line"
+
+// WriterLineIndex will add an index of the number of lines in the stream.
+// This allows ReadSeeker.SeekLine to seek to a specific line,
+// only decompressing the block containing the line.
+// Lines are separated by '\n'.
+//
+// The index is stored in a skippable chunk at the end of the stream.
+// To locate it, the seek index must be added to the stream with WriterAddIndex.
+func WriterLineIndex() WriterOption {
+	return func(w *Writer) error {
+		w.lineIndex = &LineIndex{}
+		return nil
+	}
+}
+
+// LineIndex contains the number of lines before block offsets of a stream.
+type LineIndex struct {
+	TotalUncompressed int64 // Total uncompressed size.
+	TotalLines        int64 // Total number of newlines.
+	info              []lineInfo
+}
+
+type lineInfo struct {
+	uncompressedOffset int64
+	lines              int64 // Newlines before uncompressedOffset.
+}
+
+func (l *LineIndex) reset() {
+	l.TotalUncompressed = 0
+	l.TotalLines = 0
+	l.info = l.info[:0]
+}
+
+// addBlock adds a block starting at the uncompressed offset.
+// Blocks must be added in order.
+func (l *LineIndex) addBlock(offset int64, block []byte) {
+	if n := len(l.info); n == 0 || l.info[n-1].uncompressedOffset+minIndexDist <= offset {
+		if n >= maxIndexEntries {
+			// Keep every other entry.
+			for i := range n / 2 {
+				l.info[i] = l.info[i*2]
+			}
+			l.info = l.info[:n/2]
+		}
+		l.info = append(l.info, lineInfo{uncompressedOffset: offset, lines: l.TotalLines})
+	}
+	l.TotalLines += int64(bytes.Count(block, []byte{'\n'}))
+}
+
+// appendTo appends the index as a skippable chunk to b.
+func (l *LineIndex) appendTo(b []byte, uncompTotal int64) []byte {
+	l.TotalUncompressed = uncompTotal
+	initSize := len(b)
+	b = append(b, chunkTypeLineIndex, 0, 0, 0)
+	b = append(b, lineIndexHeader...)
+	b = binary.AppendUvarint(b, uint64(l.TotalUncompressed))
+	b = binary.AppendUvarint(b, uint64(l.TotalLines))
+	b = binary.AppendUvarint(b, uint64(len(l.info)))
+	var prev lineInfo
+	for _, info := range l.info {
+		b = binary.AppendUvarint(b, uint64(info.uncompressedOffset-prev.uncompressedOffset))
+		b = binary.AppendUvarint(b, uint64(info.lines-prev.lines))
+		prev = info
+	}
+	chunkLen := len(b) - initSize - skippableFrameHeader
+	b[initSize+1] = uint8(chunkLen >> 0)
+	b[initSize+2] = uint8(chunkLen >> 8)
+	b[initSize+3] = uint8(chunkLen >> 16)
+	return b
+}
+
+// load the index from the content of a line index chunk.
+func (l *LineIndex) load(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(lineIndexHeader)) {
+		return ErrCorrupt
+	}
+	b = b[len(lineIndexHeader):]
+	var vals [3]uint64
+	for i := range vals {
+		v, n := binary.Uvarint(b)
+		if n <= 0 || v > math.MaxInt64 {
+			return ErrCorrupt
+		}
+		vals[i] = v
+		b = b[n:]
+	}
+	l.TotalUncompressed, l.TotalLines = int64(vals[0]), int64(vals[1])
+	entries := vals[2]
+	if entries > maxIndexEntries || entries > uint64(len(b)/2) {
+		return ErrCorrupt
+	}
+	l.info = make([]lineInfo, 0, entries)
+	var prev lineInfo
+	for range entries {
+		dOff, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrCorrupt
+		}
+		b = b[n:]
+		dLines, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrCorrupt
+		}
+		b = b[n:]
+		if len(l.info) > 0 && dOff == 0 {
+			return ErrCorrupt
+		}
+		info := lineInfo{uncompressedOffset: prev.uncompressedOffset + int64(dOff), lines: prev.lines + int64(dLines)}
+		if info.uncompressedOffset < prev.uncompressedOffset || info.uncompressedOffset > l.TotalUncompressed ||
+			info.lines < prev.lines || info.lines > l.TotalLines {
+			return ErrCorrupt
+		}
+		l.info = append(l.info, info)
+		prev = info
+	}
+	if len(b) != 0 {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// Find returns an uncompressed offset at or before the start of the line,
+// and the number of newlines before the offset.
+// Lines are zero based, so line n starts after n newlines.
+// If the line is after the end of the stream, io.ErrUnexpectedEOF is returned.
+func (l *LineIndex) Find(line int64) (uncompressedOff, lines int64, err error) {
+	if line < 0 || line > l.TotalLines {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	for _, info := range l.info {
+		// The line starts after the newline, so it must be before the entry.
+		if info.lines >= line {
+			break
+		}
+		uncompressedOff, lines = info.uncompressedOffset, info.lines
+	}
+	return uncompressedOff, lines, nil
+}
+
+// LineIndex returns the line index of the stream.
+// The index is loaded from the end of the stream on the first call,
+// which requires the stream to have a seek index.
+// If the stream doesn't contain a line index, ErrUnsupported is returned.
+func (r *ReadSeeker) LineIndex() (*LineIndex, error) {
+	if r.lines != nil {
+		return r.lines, nil
+	}
+	if r.index == nil {
+		return nil, ErrCantSeek{Reason: "stream has no index"}
+	}
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	// The line index is after the last block, so read from the last byte.
+	var lines *LineIndex
+	id := chunkTypeLineIndex - 0x80
+	cb := r.skippableCB[id]
+	r.skippableCB[id] = func(cr io.Reader) error {
+		b, err := io.ReadAll(cr)
+		if err != nil {
+			return err
+		}
+		lines = &LineIndex{}
+		return lines.load(b)
+	}
+	defer func() {
+		r.skippableCB[id] = cb
+	}()
+	if _, err := r.Seek(max(r.index.TotalUncompressed-1, 0), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, r.Reader); err != nil {
+		return nil, err
+	}
+	if lines == nil {
+		return nil, fmt.Errorf("%w: stream has no line index", ErrUnsupported)
+	}
+	if lines.TotalUncompressed != r.index.TotalUncompressed {
+		return nil, errors.New("s2: line index does not match stream size")
+	}
+	if _, err := r.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r.lines = lines
+	return lines, nil
+}
+
+// SeekLine seeks to the start of the line using the line index of the stream.
+// Lines are zero based, so line n starts after n newlines.
+// The new uncompressed offset is returned.
+// If the line is after the end of the stream, io.ErrUnexpectedEOF is returned.
+func (r *ReadSeeker) SeekLine(line int64) (int64, error) {
+	li, err := r.LineIndex()
+	if err != nil {
+		return 0, err
+	}
+	off, lines, err := li.Find(line)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	// Skip the remaining lines.
+	for lines < line {
+		if r.i == r.j {
+			if _, err := r.Read(nil); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			continue
+		}
+		idx := bytes.IndexByte(r.decoded[r.i:r.j], '\n')
+		if idx < 0 {
+			r.i = r.j
+			continue
+		}
+		r.i += idx + 1
+		lines++
+	}
+	return r.blockStart + int64(r.i), nil
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ReaderBlockCache enables concurrent ReadAt calls on ReadSeeker.
+// When the input supports io.ReaderAt and an index is available,
+// ReadAt will read blocks directly from the input using the index,
+// and will not affect regular reads or the position of Seek.
+// Up to the specified number of decoded blocks are kept in memory.
+// Least recently used blocks are evicted first.
+// This can greatly speed up repeated small reads within the same blocks.
+// Memory usage is up to the number of blocks times the block size of the stream.
+// Specifying 0 blocks will enable concurrent ReadAt without caching.
+func ReaderBlockCache(blocks int) ReaderOption {
+	return func(r *Reader) error {
+		if blocks < 0 {
+			return errors.New("s2: block cache size must be >= 0")
+		}
+		r.readAtDirect = true
+		r.cacheBlocks = blocks
+		return nil
+	}
+}
+
+// blockCache is a concurrency safe LRU cache of decoded blocks,
+// keyed by compressed offset.
+type blockCache struct {
+	mu     sync.Mutex
+	max    int
+	lru    list.List
+	blocks map[int64]*list.Element
+}
+
+type cachedBlock struct {
+	offset  int64
+	next    int64 // Offset of next chunk
+	decoded []byte
+}
+
+func newBlockCache(n int) *blockCache {
+	return &blockCache{max: n, blocks: make(map[int64]*list.Element, n)}
+}
+
+// get returns the decoded block at the compressed offset and the offset of the next chunk.
+// nil is returned if the block is not cached.
+// The returned slice must not be modified.
+func (c *blockCache) get(offset int64) (decoded []byte, next int64) {
+	if c == nil {
+		return nil, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.blocks[offset]
+	if !ok {
+		return nil, 0
+	}
+	c.lru.MoveToFront(e)
+	b := e.Value.(*cachedBlock)
+	return b.decoded, b.next
+}
+
+// add a decoded block at the compressed offset.
+// The block may not be modified after being added.
+func (c *blockCache) add(offset, next int64, decoded []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.blocks[offset]; ok {
+		// Added by another goroutine.
+		c.lru.MoveToFront(e)
+		return
+	}
+	if c.lru.Len() >= c.max {
+		e := c.lru.Back()
+		delete(c.blocks, e.Value.(*cachedBlock).offset)
+		c.lru.Remove(e)
+	}
+	c.blocks[offset] = c.lru.PushFront(&cachedBlock{offset: offset, next: next, decoded: decoded})
+}
+
+// initReadAt reads the stream headers using ra and sets up the block cache.
+// If the index covers several streams, the dictionary of each stream is looked up.
+func (r *ReadSeeker) initReadAt(ra io.ReaderAt) error {
+	if r.cacheBlocks > 0 {
+		r.cache = newBlockCache(r.cacheBlocks)
+	}
+	if r.ignoreStreamID {
+		return nil
+	}
+	starts := r.index.streams
+	if len(starts) == 0 {
+		starts = []int64{0}
+	}
+	r.atStreams = starts
+	r.atDicts = make([]*Dict, len(starts))
+	for i, start := range starts {
+		d, err := r.streamDictAt(ra, start)
+		if err != nil {
+			return err
+		}
+		r.atDicts[i] = d
+	}
+	return nil
+}
+
+// streamDictAt reads the stream identifier at compressed offset c
+// and returns the dictionary used by the stream, if any.
+func (r *ReadSeeker) streamDictAt(ra io.ReaderAt, c int64) (*Dict, error) {
+	// Read stream identifier and dictionary ID, if any.
+	var hdr [len(magicChunk) + dictChunkLen]byte
+	n, err := ra.ReadAt(hdr[:], c)
+	if n < len(magicChunk) {
+		if err == nil || err == io.EOF {
+			err = ErrCorrupt
+		}
+		return nil, err
+	}
+	return r.streamDict(hdr[:n])
+}
+
+// seekStreamDict returns the dictionary used by the stream
+// containing compressed offset c, if any.
+// Stream starts are only known if indexes were merged.
+// Otherwise the current dictionary is returned.
+// The read position of rs is changed.
+func (r *ReadSeeker) seekStreamDict(rs io.ReadSeeker, c int64) (*Dict, error) {
+	starts := r.index.streams
+	i := sort.Search(len(starts), func(i int) bool { return starts[i] > c }) - 1
+	if r.ignoreStreamID || i < 0 {
+		return r.dict, nil
+	}
+	if d, ok := r.seekDicts[starts[i]]; ok {
+		return d, nil
+	}
+	if _, err := rs.Seek(starts[i], io.SeekStart); err != nil {
+		return nil, err
+	}
+	var hdr [len(magicChunk) + dictChunkLen]byte
+	n, err := io.ReadFull(rs, hdr[:])
+	if n < len(magicChunk) {
+		return nil, noEOF(err)
+	}
+	d, err := r.streamDict(hdr[:n])
+	if err != nil {
+		return nil, err
+	}
+	if r.seekDicts == nil {
+		r.seekDicts = make(map[int64]*Dict, len(starts))
+	}
+	r.seekDicts[starts[i]] = d
+	return d, nil
+}
+
+// streamDict returns the dictionary used by the stream starting with hdr, if any.
+// hdr must contain the stream identifier,
+// optionally followed by the dictionary ID chunk.
+func (r *ReadSeeker) streamDict(hdr []byte) (*Dict, error) {
+	n := len(hdr)
+	if string(hdr[:len(magicChunk)]) != magicChunk && string(hdr[:len(magicChunk)]) != magicChunkSnappy {
+		return nil, ErrCorrupt
+	}
+	if n < len(hdr) || hdr[len(magicChunk)] != chunkTypeDictID {
+		return nil, nil
+	}
+	if chunkLen := int(hdr[len(magicChunk)+1]) | int(hdr[len(magicChunk)+2])<<8 | int(hdr[len(magicChunk)+3])<<16; chunkLen != dictIDSize {
+		return nil, ErrCorrupt
+	}
+	return r.dictID(binary.LittleEndian.Uint32(hdr[len(magicChunk)+chunkHeaderSize:]))
+}
+
+// dictID returns the supplied dictionary with the specified ID.
+func (r *ReadSeeker) dictID(id uint32) (*Dict, error) {
+	d := r.dicts[id]
+	if d == nil {
+		return nil, fmt.Errorf("%w: dictionary 0x%08x not supplied", ErrUnsupported, id)
+	}
+	return d, nil
+}
+
+// dictAt returns the dictionary of the stream containing compressed offset c.
+func (r *ReadSeeker) dictAt(c int64) *Dict {
+	i := sort.Search(len(r.atStreams), func(i int) bool { return r.atStreams[i] > c }) - 1
+	if i < 0 {
+		return nil
+	}
+	return r.atDicts[i]
+}
+
+// readAt reads from ra using the index to locate blocks.
+// The state of the Reader is not used, so this can be called concurrently.
+func (r *ReadSeeker) readAt(ra io.ReaderAt, p []byte, offset int64) (int, error) {
+	r.readAtOnce.Do(func() {
+		r.readAtErr = r.initReadAt(ra)
+	})
+	if r.readAtErr != nil {
+		return 0, r.readAtErr
+	}
+	if offset < 0 {
+		return 0, errors.New("s2: negative offset")
+	}
+	if offset >= r.index.TotalUncompressed {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	c, u, err := r.index.Find(offset)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for n < len(p) {
+		if offset+int64(n) >= r.index.TotalUncompressed {
+			return n, io.EOF
+		}
+		want := offset + int64(n) - u
+		decoded, size, next, err := r.blockAt(ra, c, want)
+		if err != nil {
+			return n, err
+		}
+		if decoded != nil {
+			n += copy(p[n:], decoded[want:])
+		}
+		u += size
+		c = next
+	}
+	return n, nil
+}
+
+// blockAt reads the chunk at compressed offset c.
+// The uncompressed size of the chunk and the offset of the next chunk is returned.
+// If the chunk contains the uncompressed offset want,
+// relative to the start of the chunk, the decoded content is returned.
+// The returned content may not be modified.
+func (r *ReadSeeker) blockAt(ra io.ReaderAt, c, want int64) (decoded []byte, size, next int64, err error) {
+	if b, next := r.cache.get(c); b != nil {
+		if want >= int64(len(b)) {
+			return nil, int64(len(b)), next, nil
+		}
+		return b, int64(len(b)), next, nil
+	}
+	// Header, checksum and uncompressed size.
+	var hdr [chunkHeaderSize + checksumSize + binary.MaxVarintLen32]byte
+	hn, err := ra.ReadAt(hdr[:], c)
+	if hn < chunkHeaderSize {
+		return nil, 0, 0, noEOF(err)
+	}
+	chunkType := hdr[0]
+	chunkLen := int(hdr[1]) | int(hdr[2])<<8 | int(hdr[3])<<16
+	next = c + chunkHeaderSize + int64(chunkLen)
+	switch chunkType {
+	case chunkTypeCompressedData, chunkTypeUncompressedData:
+		if chunkLen < checksumSize {
+			return nil, 0, 0, ErrCorrupt
+		}
+	case chunkTypeStreamIdentifier:
+		if chunkLen != len(magicBody) {
+			return nil, 0, 0, ErrCorrupt
+		}
+		return nil, 0, next, nil
+	case chunkTypeDictID:
+		// Stream starts are only known if indexes were merged,
+		// so check that the dictionary matches the one used for decoding.
+		if chunkLen != dictIDSize || hn < chunkHeaderSize+dictIDSize {
+			return nil, 0, 0, ErrCorrupt
+		}
+		if r.ignoreStreamID {
+			return nil, 0, next, nil
+		}
+		d, err := r.dictID(binary.LittleEndian.Uint32(hdr[chunkHeaderSize:]))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if d != r.dictAt(c) {
+			return nil, 0, 0, fmt.Errorf("%w: streams with different dictionaries", ErrUnsupported)
+		}
+		return nil, 0, next, nil
+	default:
+		if chunkType <= 0x7f {
+			// Section 4.5. Reserved unskippable chunks (chunk types 0x02-0x7f).
+			return nil, 0, 0, ErrUnsupported
+		}
+		// Padding and skippable chunks.
+		return nil, 0, next, nil
+	}
+
+	if chunkType == chunkTypeCompressedData {
+		dLen, _, err := decodedLen(hdr[chunkHeaderSize+checksumSize : min(hn, chunkHeaderSize+chunkLen)])
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		size = int64(dLen)
+	} else {
+		size = int64(chunkLen - checksumSize)
+	}
+	if size > int64(r.maxBlock) {
+		return nil, 0, 0, ErrCorrupt
+	}
+	if want >= size {
+		return nil, size, next, nil
+	}
+
+	// Read and decode the block.
+	buf := make([]byte, chunkLen)
+	if n, err := ra.ReadAt(buf, c+chunkHeaderSize); n < len(buf) {
+		return nil, 0, 0, noEOF(err)
+	}
+	checksum := binary.LittleEndian.Uint32(buf)
+	buf = buf[checksumSize:]
+	if chunkType == chunkTypeCompressedData {
+		decoded = make([]byte, size)
+		if err := decodeDict(decoded, buf, r.dictAt(c)); err != nil {
+			return nil, 0, 0, err
+		}
+	} else {
+		decoded = buf
+	}
+	if !r.ignoreCRC && crc(decoded) != checksum {
+		return nil, 0, 0, ErrCRC
+	}
+	r.cache.add(c, next, decoded)
+	return decoded, size, next, nil
+}
+
+// noEOF converts EOF errors to io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == nil || err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -6,6 +6,7 @@
 package s2
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,9 @@ import (
 	"math"
 	"runtime"
 	"sync"
+
+	"github.com/klauspost/compress/internal/xxhash"
+	"github.com/klauspost/compress/readahead"
 )
 
 // ErrCantSeek is returned if the stream cannot be seeked.
@@ -39,6 +43,7 @@ func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
 			return &nr
 		}
 	}
+	nr.setInput(r)
 	nr.maxBufSize = MaxEncodedLen(nr.maxBlock) + checksumSize
 	if nr.lazyBuf > 0 {
 		nr.buf = make([]byte, MaxEncodedLen(nr.lazyBuf)+checksumSize)
@@ -101,6 +106,8 @@ func ReaderIgnoreStreamIdentifier() ReaderOption {
 
 // ReaderSkippableCB will register a callback for chuncks with the specified ID.
 // ID must be a Reserved skippable chunks ID, 0x80-0xfd (inclusive).
+// In streams written with WriterStreamChecksum, chunks with ID 0x9a
+// contain the stream trailer and are not forwarded to the callback.
 // For each chunk with the ID, the callback is called with the content.
 // Any returned non-nil error will abort decompression.
 // Only one callback per ID is supported, latest sent will be used.
@@ -116,6 +123,49 @@ func ReaderSkippableCB(id uint8, fn func(r io.Reader) error) ReaderOption {
 	}
 }
 
+// ReaderReadAhead will read up to n bytes ahead from the input asynchronously.
+// This can improve throughput when the input is slow, for example
+// files on slow disks or network streams, since reading the input
+// and decompression are done in parallel.
+// The input is read in buffers of up to 1MB, and at least 2 buffers are used.
+//
+// The read-ahead stops when the input returns an error, including io.EOF,
+// or when the Reader is Reset or closed.
+// If the Reader is abandoned before the input has returned an error,
+// Close must be called to stop the read-ahead goroutine.
+// Random access using io.ReaderAt on the input will not be available.
+func ReaderReadAhead(n int) ReaderOption {
+	return func(r *Reader) error {
+		if n < 0 {
+			return errors.New("s2: read-ahead must be >= 0")
+		}
+		r.readAhead = n
+		return nil
+	}
+}
+
+// setInput sets the input of the reader,
+// and starts read-ahead if requested.
+// Any previous read-ahead is stopped.
+func (r *Reader) setInput(in io.Reader) {
+	if r.ra != nil {
+		r.ra.Close()
+		r.ra = nil
+	}
+	r.r = in
+	if r.readAhead <= 0 || in == nil {
+		return
+	}
+	size := min(r.readAhead, 1<<20)
+	ra, err := readahead.NewReaderSize(in, max((r.readAhead+size-1)/size, 2), size)
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.ra = ra
+	r.r = ra
+}
+
 // ReaderIgnoreCRC will make the reader skip CRC calculation and checks.
 func ReaderIgnoreCRC() ReaderOption {
 	return func(r *Reader) error {
@@ -124,6 +174,26 @@ func ReaderIgnoreCRC() ReaderOption {
 	}
 }
 
+// ReaderDicts allows to supply dictionaries for decoding streams
+// that have been created with WriterDict.
+// The dictionary used by a stream is identified by its ID.
+// If a stream references a dictionary that has not been supplied,
+// ErrUnsupported is returned when reading.
+func ReaderDicts(dicts ...*Dict) ReaderOption {
+	return func(r *Reader) error {
+		if r.dicts == nil {
+			r.dicts = make(map[uint32]*Dict, len(dicts))
+		}
+		for _, d := range dicts {
+			if d == nil {
+				return errors.New("s2: nil dictionary supplied")
+			}
+			r.dicts[d.ID()] = d
+		}
+		return nil
+	}
+}
+
 // Reader is an io.Reader that can read Snappy-compressed bytes.
 type Reader struct {
 	r           io.Reader
@@ -133,6 +203,9 @@ type Reader struct {
 	skippableCB [0xff - 0x80]func(r io.Reader) error
 	blockStart  int64 // Uncompressed offset at start of current.
 	index       *Index
+	dicts       map[uint32]*Dict
+	dict        *Dict // Dictionary used for the current stream.
+	stats       streamStats
 
 	// decoded[i:j] contains decoded bytes that have not yet been passed on.
 	i, j int
@@ -147,6 +220,19 @@ type Reader struct {
 	snappyFrame    bool
 	ignoreStreamID bool
 	ignoreCRC      bool
+	autoDetect     bool
+	detected       bool  // Input format has been detected.
+	format         uint8 // Detected input format.
+	cacheBlocks    int
+	readAtDirect   bool // Use io.ReaderAt for ReadSeeker.ReadAt, if possible.
+	readAhead      int
+	ra             io.Closer // Active read-ahead, if any.
+
+	// Stream trailer state.
+	streamTrailer bool  // Current stream will end with a trailer.
+	streamHashed  bool  // All content of the current stream has been decoded.
+	streamSize    int64 // Decoded size of the current stream.
+	streamHash    xxhash.Digest
 }
 
 // GetBufferCapacity returns the capacity of the internal buffer.
@@ -171,6 +257,17 @@ func (r *Reader) ensureBufferSize(n int) bool {
 	return true
 }
 
+// Close stops any read-ahead and releases the input.
+// The input is not closed.
+// Reads will return an error until the Reader is Reset.
+func (r *Reader) Close() error {
+	r.setInput(nil)
+	r.err = errReaderClosed
+	return nil
+}
+
+var errReaderClosed = errors.New("s2: Reader is closed")
+
 // Reset discards any buffered data, resets all state, and switches the Snappy
 // reader to read from r. This permits reusing a Reader rather than allocating
 // a new one.
@@ -179,12 +276,100 @@ func (r *Reader) Reset(reader io.Reader) {
 		return
 	}
 	r.index = nil
-	r.r = reader
 	r.err = nil
+	r.setInput(reader)
 	r.i = 0
 	r.j = 0
 	r.blockStart = 0
+	r.dict = nil
 	r.readHeader = r.ignoreStreamID
+	r.streamTrailer = false
+	r.streamHashed = false
+	r.stats.reset()
+	r.detected = false
+	r.format = formatFramed
+}
+
+// readDictChunk reads the content of a dictionary ID chunk
+// and sets the dictionary to use for the following blocks.
+func (r *Reader) readDictChunk(chunkLen int) (ok bool) {
+	if chunkLen != dictIDSize {
+		r.err = ErrCorrupt
+		return false
+	}
+	if !r.readFull(r.buf[:dictIDSize], false) {
+		return false
+	}
+	id := binary.LittleEndian.Uint32(r.buf[:dictIDSize])
+	d := r.dicts[id]
+	if d == nil {
+		r.err = fmt.Errorf("%w: dictionary 0x%08x not supplied", ErrUnsupported, id)
+		return false
+	}
+	r.dict = d
+	return true
+}
+
+// isStreamTrailer reports whether a chunk with the stream trailer ID
+// and the specified length belongs to the stream trailer.
+// Streams with a trailer start with an empty chunk, which user skippable chunks cannot be.
+// Otherwise the chunk is a user skippable chunk.
+func (r *Reader) isStreamTrailer(chunkLen int) bool {
+	return chunkLen == 0 || r.streamTrailer
+}
+
+// readStreamTrailer reads a stream trailer chunk.
+// An empty chunk marks the start of a stream that ends with a trailer.
+// Otherwise the trailer is verified against the decoded content,
+// unless some of the content has been skipped.
+func (r *Reader) readStreamTrailer(chunkLen int) (ok bool) {
+	if chunkLen == 0 {
+		r.streamTrailer = true
+		r.streamHashed = true
+		r.streamSize = 0
+		r.streamHash.Reset()
+		return true
+	}
+	if chunkLen != streamTrailerSize {
+		r.err = ErrCorrupt
+		return false
+	}
+	if !r.readFull(r.buf[:streamTrailerSize], false) {
+		return false
+	}
+	if r.streamHashed {
+		size := binary.LittleEndian.Uint64(r.buf[:8])
+		sum := binary.LittleEndian.Uint64(r.buf[8:streamTrailerSize])
+		if size != uint64(r.streamSize) || (!r.ignoreCRC && sum != r.streamHash.Sum64()) {
+			r.err = ErrCorrupt
+			return false
+		}
+	}
+	r.streamTrailer = false
+	r.streamHashed = false
+	return true
+}
+
+// hashStream adds decoded content to the stream checksum.
+// Must be called in stream order.
+func (r *Reader) hashStream(b []byte) {
+	if r.streamHashed {
+		r.streamSize += int64(len(b))
+		if !r.ignoreCRC {
+			r.streamHash.Write(b)
+		}
+	}
+}
+
+// decodeDict decodes src into dst using the dictionary, if any.
+// dst must be large enough to hold the decoded output.
+func decodeDict(dst, src []byte, dict *Dict) error {
+	if dict != nil {
+		_, err := dict.Decode(dst, src)
+		return err
+	}
+	_, err := Decode(dst, src)
+	return err
 }
 
 func (r *Reader) readFull(p []byte, allowEOF bool) (ok bool) {
@@ -194,6 +379,7 @@ func (r *Reader) readFull(p []byte, allowEOF bool) (ok bool) {
 		}
 		return false
 	}
+	r.stats.compressed.Add(int64(len(p)))
 	return true
 }
 
@@ -213,13 +399,18 @@ func (r *Reader) skippable(tmp []byte, n int, allowEOF bool, id uint8) (ok bool)
 			return false
 		}
 		_, r.err = io.CopyBuffer(ioutil.Discard, rd, tmp)
-		return r.err == nil
+		if r.err != nil {
+			return false
+		}
+		r.stats.compressed.Add(int64(n))
+		return true
 	}
 	if rs, ok := r.r.(io.ReadSeeker); ok {
 		if cur, err := rs.Seek(0, io.SeekCurrent); err == nil {
 			if end, err := rs.Seek(0, io.SeekEnd); err == nil {
 				if cur+int64(n) <= end {
 					if _, err := rs.Seek(cur+int64(n), io.SeekStart); err == nil {
+						r.stats.compressed.Add(int64(n))
 						return true
 					}
 				}
@@ -240,6 +431,7 @@ func (r *Reader) skippable(tmp []byte, n int, allowEOF bool, id uint8) (ok bool)
 			}
 			return false
 		}
+		r.stats.compressed.Add(int64(len(tmp)))
 		n -= len(tmp)
 	}
 	return true
@@ -250,13 +442,25 @@ func (r *Reader) Read(p []byte) (int, error) {
 	if r.err != nil {
 		return 0, r.err
 	}
+	if r.autoDetect && !r.detected && !r.detect() {
+		return 0, r.err
+	}
 	for {
 		if r.i < r.j {
 			n := copy(p, r.decoded[r.i:r.j])
 			r.i += n
 			return n, nil
 		}
+		if r.format != formatFramed {
+			if !r.readFormatBlock() {
+				return 0, r.err
+			}
+			continue
+		}
 		if !r.readFull(r.buf[:4], true) {
+			if r.err == io.EOF && r.streamTrailer {
+				r.err = ErrTruncated
+			}
 			return 0, r.err
 		}
 		chunkType := r.buf[0]
@@ -309,14 +513,17 @@ func (r *Reader) Read(p []byte) (int, error) {
 				}
 				r.decoded = make([]byte, n)
 			}
-			if _, err := Decode(r.decoded, buf); err != nil {
+			if err := decodeDict(r.decoded, buf, r.dict); err != nil {
 				r.err = err
 				return 0, r.err
 			}
 			if !r.ignoreCRC && crc(r.decoded[:n]) != checksum {
+				r.stats.crcFailures.Add(1)
 				r.err = ErrCRC
 				return 0, r.err
 			}
+			r.stats.addBlock(n, chunkType == chunkTypeUncompressedData)
+			r.hashStream(r.decoded[:n])
 			r.i, r.j = 0, n
 			continue
 
@@ -355,14 +562,22 @@ func (r *Reader) Read(p []byte) (int, error) {
 				return 0, r.err
 			}
 			if !r.ignoreCRC && crc(r.decoded[:n]) != checksum {
+				r.stats.crcFailures.Add(1)
 				r.err = ErrCRC
 				return 0, r.err
 			}
+			r.stats.addBlock(n, chunkType == chunkTypeUncompressedData)
+			r.hashStream(r.decoded[:n])
 			r.i, r.j = 0, n
 			continue
 
 		case chunkTypeStreamIdentifier:
 			// Section 4.1. Stream identifier (chunk type 0xff).
+			if r.streamTrailer {
+				// Previous stream ended without a trailer.
+				r.err = ErrTruncated
+				return 0, r.err
+			}
 			if chunkLen != len(magicBody) {
 				r.err = ErrCorrupt
 				return 0, r.err
@@ -380,6 +595,23 @@ func (r *Reader) Read(p []byte) (int, error) {
 			} else {
 				r.snappyFrame = false
 			}
+			r.dict = nil
+			continue
+
+		case chunkTypeDictID:
+			if !r.readDictChunk(chunkLen) {
+				return 0, r.err
+			}
+			continue
+
+		case chunkTypeStreamTrailer:
+			if !r.isStreamTrailer(chunkLen) {
+				// User skippable chunk.
+				break
+			}
+			if !r.readStreamTrailer(chunkLen) {
+				return 0, r.err
+			}
 			continue
 		}
 
@@ -417,6 +649,13 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 	if concurrent <= 0 {
 		concurrent = runtime.NumCPU()
 	}
+	if r.autoDetect && !r.detected && !r.detect() {
+		return 0, r.err
+	}
+	if r.format != formatFramed {
+		// Other formats are decoded sequentially.
+		return io.Copy(w, r)
+	}
 
 	// Write to output
 	var errMu sync.Mutex
@@ -469,6 +708,7 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 			}
 			n, err := w.Write(entry)
 			want := len(entry)
+			r.hashStream(entry)
 			writtenBlocks <- entry
 			if err != nil {
 				setErr(err)
@@ -482,6 +722,21 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 		}
 	}()
 
+	// waitWritten waits for all queued blocks to be written.
+	waitWritten := func() {
+		entry := <-reUse
+		queue <- entry
+		entry <- nil
+		// When all entries are back, the writer has passed the empty entry.
+		entries := make([]chan []byte, 0, concurrent)
+		for range concurrent {
+			entries = append(entries, <-reUse)
+		}
+		for _, e := range entries {
+			reUse <- e
+		}
+	}
+
 	defer func() {
 		if r.err != nil {
 			setErr(r.err)
@@ -501,6 +756,9 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 		if !r.readFull(r.buf[:4], true) {
 			if r.err == io.EOF {
 				r.err = nil
+				if r.streamTrailer {
+					r.err = ErrTruncated
+				}
 			}
 			return 0, r.err
 		}
@@ -552,15 +810,17 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 				r.err = ErrCorrupt
 				return 0, r.err
 			}
+			r.stats.addBlock(n, false)
 			wg.Add(1)
 
 			decoded := <-writtenBlocks
 			entry := <-reUse
 			queue <- entry
+			dict := r.dict
 			go func() {
 				defer wg.Done()
 				decoded = decoded[:n]
-				_, err := Decode(decoded, buf)
+				err := decodeDict(decoded, buf, dict)
 				toRead <- orgBuf
 				if err != nil {
 					writtenBlocks <- decoded
@@ -569,6 +829,7 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 					return
 				}
 				if !r.ignoreCRC && crc(decoded) != checksum {
+					r.stats.crcFailures.Add(1)
 					writtenBlocks <- decoded
 					setErr(ErrCRC)
 					entry <- nil
@@ -614,9 +875,11 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 			}
 
 			if !r.ignoreCRC && crc(buf) != checksum {
+				r.stats.crcFailures.Add(1)
 				r.err = ErrCRC
 				return 0, r.err
 			}
+			r.stats.addBlock(n, true)
 			entry := <-reUse
 			queue <- entry
 			entry <- buf
@@ -624,6 +887,11 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 
 		case chunkTypeStreamIdentifier:
 			// Section 4.1. Stream identifier (chunk type 0xff).
+			if r.streamTrailer {
+				// Previous stream ended without a trailer.
+				r.err = ErrTruncated
+				return 0, r.err
+			}
 			if chunkLen != len(magicBody) {
 				r.err = ErrCorrupt
 				return 0, r.err
@@ -641,6 +909,25 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 			} else {
 				r.snappyFrame = false
 			}
+			r.dict = nil
+			continue
+
+		case chunkTypeDictID:
+			if !r.readDictChunk(chunkLen) {
+				return 0, r.err
+			}
+			continue
+
+		case chunkTypeStreamTrailer:
+			if !r.isStreamTrailer(chunkLen) {
+				// User skippable chunk.
+				break
+			}
+			// Wait for all queued blocks to be hashed.
+			waitWritten()
+			if !r.readStreamTrailer(chunkLen) {
+				return 0, r.err
+			}
 			continue
 		}
 
@@ -668,7 +955,8 @@ func (r *Reader) DecodeConcurrent(w io.Writer, concurrent int) (written int64, e
 
 // Skip will skip n bytes forward in the decompressed output.
 // For larger skips this consumes less CPU and is faster than reading output and discarding it.
-// CRC is not checked on skipped blocks.
+// CRC is not checked on skipped blocks,
+// and stream checksums will not be verified for the stream.
 // io.ErrUnexpectedEOF is returned if the stream ends before all bytes have been skipped.
 // If a decoding error is encountered subsequent calls to Read will also fail.
 func (r *Reader) Skip(n int64) error {
@@ -678,6 +966,9 @@ func (r *Reader) Skip(n int64) error {
 	if r.err != nil {
 		return r.err
 	}
+	if r.autoDetect && !r.detected && !r.detect() {
+		return r.err
+	}
 
 	for n > 0 {
 		if r.i < r.j {
@@ -695,6 +986,15 @@ func (r *Reader) Skip(n int64) error {
 			n -= int64(r.j - r.i)
 			r.i = r.j
 		}
+		if r.format != formatFramed {
+			if !r.readFormatBlock() {
+				if r.err == io.EOF {
+					r.err = io.ErrUnexpectedEOF
+				}
+				return r.err
+			}
+			continue
+		}
 
 		// Buffer empty; read blocks until we have content.
 		if !r.readFull(r.buf[:4], true) {
@@ -718,6 +1018,8 @@ func (r *Reader) Skip(n int64) error {
 		switch chunkType {
 		case chunkTypeCompressedData:
 			r.blockStart += int64(r.j)
+			// Skipped content cannot be verified by the stream trailer.
+			r.streamHashed = false
 			// Section 4.2. Compressed data (chunk type 0x00).
 			if chunkLen < checksumSize {
 				r.err = ErrCorrupt
@@ -746,15 +1048,17 @@ func (r *Reader) Skip(n int64) error {
 				return r.err
 			}
 			// Check if destination is within this block
+			r.stats.addBlock(dLen, false)
 			if int64(dLen) > n {
 				if len(r.decoded) < dLen {
 					r.decoded = make([]byte, dLen)
 				}
-				if _, err := Decode(r.decoded, buf); err != nil {
+				if err := decodeDict(r.decoded, buf, r.dict); err != nil {
 					r.err = err
 					return r.err
 				}
 				if crc(r.decoded[:dLen]) != checksum {
+					r.stats.crcFailures.Add(1)
 					r.err = ErrCorrupt
 					return r.err
 				}
@@ -768,6 +1072,8 @@ func (r *Reader) Skip(n int64) error {
 			continue
 		case chunkTypeUncompressedData:
 			r.blockStart += int64(r.j)
+			// Skipped content cannot be verified by the stream trailer.
+			r.streamHashed = false
 			// Section 4.3. Uncompressed data (chunk type 0x01).
 			if chunkLen < checksumSize {
 				r.err = ErrCorrupt
@@ -798,14 +1104,21 @@ func (r *Reader) Skip(n int64) error {
 			}
 			if int64(n2) < n {
 				if crc(r.decoded[:n2]) != checksum {
+					r.stats.crcFailures.Add(1)
 					r.err = ErrCorrupt
 					return r.err
 				}
 			}
+			r.stats.addBlock(n2, true)
 			r.i, r.j = 0, n2
 			continue
 		case chunkTypeStreamIdentifier:
 			// Section 4.1. Stream identifier (chunk type 0xff).
+			if r.streamTrailer {
+				// Previous stream ended without a trailer.
+				r.err = ErrTruncated
+				return r.err
+			}
 			if chunkLen != len(magicBody) {
 				r.err = ErrCorrupt
 				return r.err
@@ -819,7 +1132,23 @@ func (r *Reader) Skip(n int64) error {
 					return r.err
 				}
 			}
+			r.dict = nil
+			continue
+
+		case chunkTypeDictID:
+			if !r.readDictChunk(chunkLen) {
+				return r.err
+			}
+			continue
 
+		case chunkTypeStreamTrailer:
+			if !r.isStreamTrailer(chunkLen) {
+				// User skippable chunk.
+				break
+			}
+			if !r.readStreamTrailer(chunkLen) {
+				return r.err
+			}
 			continue
 		}
 
@@ -846,6 +1175,19 @@ func (r *Reader) Skip(n int64) error {
 type ReadSeeker struct {
 	*Reader
 	readAtMu sync.Mutex
+
+	// State for concurrent ReadAt.
+	readAtOnce sync.Once
+	readAtErr  error
+	cache      *blockCache
+	atStreams  []int64 // Compressed start offset of each stream.
+	atDicts    []*Dict // Dictionary of each stream.
+
+	// Dictionaries of merged streams used by Seek, keyed by stream start.
+	seekDicts map[int64]*Dict
+
+	// Line index, loaded on first use.
+	lines *LineIndex
 }
 
 // ReadSeeker will return an io.ReadSeeker and io.ReaderAt
@@ -856,9 +1198,15 @@ type ReadSeeker struct {
 // the io.Seeker interface.
 // A custom index can be specified which will be used if supplied.
 // When using a custom index, it will not be read from the input stream.
-// The ReadAt position will affect regular reads and the current position of Seek.
+// If the input consists of appended streams, each with an index,
+// all indexes are loaded, allowing seeking across all streams.
+// If the index only covers the streams after a stream without an index,
+// reading will start at the first indexed stream, since offsets are relative to it.
+// The ReadAt position will affect regular reads and the current position of Seek,
+// unless the ReaderBlockCache option is used, the input supports io.ReaderAt
+// and an index is available.
 // So using Read after ReadAt will continue from where the ReadAt stopped.
-// No functions should be used concurrently.
+// No functions should be used concurrently, except ReadAt as described.
 // The returned ReadSeeker contains a shallow reference to the existing Reader,
 // meaning changes performed to one is reflected in the other.
 func (r *Reader) ReadSeeker(random bool, index []byte) (*ReadSeeker, error) {
@@ -912,6 +1260,10 @@ func (r *Reader) ReadSeeker(random bool, index []byte) (*ReadSeeker, error) {
 	}
 
 	// reset position.
+	if len(r.index.streams) > 0 && pos < r.index.streams[0] {
+		// Offsets are relative to the first indexed stream.
+		pos = r.index.streams[0]
+	}
 	_, err = rs.Seek(pos, io.SeekStart)
 	if err != nil {
 		return nil, ErrCantSeek{Reason: "seeking input returned: " + err.Error()}
@@ -981,6 +1333,13 @@ func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
 		return r.blockStart + int64(r.i), err
 	}
 
+	// Use the dictionary of the stream containing the block.
+	dict, err := r.seekStreamDict(rs, c)
+	if err != nil {
+		return 0, err
+	}
+	r.dict = dict
+
 	// Seek to next block
 	_, err = rs.Seek(c, io.SeekStart)
 	if err != nil {
@@ -989,6 +1348,9 @@ func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
 
 	r.i = r.j                     // Remove rest of current block.
 	r.blockStart = u - int64(r.j) // Adjust current block start for accounting.
+	// The stream trailer cannot be verified after seeking.
+	r.streamTrailer = false
+	r.streamHashed = false
 	if u < absOffset {
 		// Forward inside block
 		return absOffset, r.Skip(absOffset - u)
@@ -1019,9 +1381,17 @@ func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
 // ReadAt should not affect nor be affected by the underlying
 // seek offset.
 //
-// Clients of ReadAt can execute parallel ReadAt calls on the
-// same input source. This is however not recommended.
+// If the ReaderBlockCache option is used, the input implements io.ReaderAt
+// and an index is available, blocks are read using the io.ReaderAt
+// and ReadAt will not affect regular reads or the position of Seek.
+// In this case ReadAt calls can safely be executed in parallel.
+//
+// Otherwise clients of ReadAt can execute parallel ReadAt calls on the
+// same input source, but calls will be serialized.
 func (r *ReadSeeker) ReadAt(p []byte, offset int64) (int, error) {
+	if ra, ok := r.r.(io.ReaderAt); ok && r.readAtDirect && r.index != nil {
+		return r.readAt(ra, p, offset)
+	}
 	r.readAtMu.Lock()
 	defer r.readAtMu.Unlock()
 	_, err := r.Seek(offset, io.SeekStart)
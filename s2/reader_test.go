@@ -6,8 +6,11 @@ package s2
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io"
 	"testing"
+	"testing/iotest"
 )
 
 func TestLeadingSkippableBlock(t *testing.T) {
@@ -43,3 +46,234 @@ func TestLeadingSkippableBlock(t *testing.T) {
 		t.Errorf("didn't get correct compressed data: %q", string(data))
 	}
 }
+
+func TestSkippableBlockTrailerID(t *testing.T) {
+	// Chunk ID 0x9a is used for stream trailers,
+	// but user blocks must still be readable in streams without a trailer.
+	blocks := [][]byte{[]byte("skippable block"), bytes.Repeat([]byte{1}, streamTrailerSize)}
+	want := bytes.Repeat([]byte("some data "), 10000)
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WriterBlockSize(64<<10))
+	for _, b := range blocks {
+		if err := w.AddSkippableBlock(chunkTypeStreamTrailer, b); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(want); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]byte
+	cb := ReaderSkippableCB(chunkTypeStreamTrailer, func(sr io.Reader) error {
+		b, err := io.ReadAll(sr)
+		got = append(got, b)
+		return err
+	})
+	check := func(name string) {
+		t.Helper()
+		if len(got) != len(blocks) {
+			t.Fatalf("%s: got %d skippable blocks, want %d", name, len(got), len(blocks))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], blocks[i]) {
+				t.Errorf("%s: block %d mismatch: %q", name, i, got[i])
+			}
+		}
+		got = nil
+	}
+
+	data, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes()), cb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, append(want, want...)) {
+		t.Fatal("output mismatch")
+	}
+	check("Read")
+
+	var out bytes.Buffer
+	if _, err := NewReader(bytes.NewReader(buf.Bytes()), cb).DecodeConcurrent(&out, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), append(want, want...)) {
+		t.Fatal("output mismatch")
+	}
+	check("DecodeConcurrent")
+
+	if err := NewReader(bytes.NewReader(buf.Bytes()), cb).Skip(int64(2 * len(want))); err != nil {
+		t.Fatal(err)
+	}
+	check("Skip")
+
+	// The ID is reserved when the writer adds a trailer.
+	w = NewWriter(io.Discard, WriterStreamChecksum())
+	if err := w.AddSkippableBlock(chunkTypeStreamTrailer, blocks[0]); err == nil {
+		t.Fatal("want error adding block with trailer ID")
+	}
+}
+
+func TestReaderReadAhead(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 5<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WriterBlockSize(256<<10), WriterAddIndex())
+	if _, err := w.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{100, 64 << 10, 4 << 20} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			r := NewReader(iotest.HalfReader(bytes.NewReader(buf.Bytes())), ReaderReadAhead(n))
+			// Abandon the first read.
+			if _, err := io.ReadFull(r, make([]byte, 1000)); err != nil {
+				t.Fatal(err)
+			}
+			r.Reset(iotest.HalfReader(bytes.NewReader(buf.Bytes())))
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatal("output mismatch")
+			}
+
+			// Input errors must be forwarded.
+			r.Reset(iotest.TimeoutReader(bytes.NewReader(buf.Bytes())))
+			if _, err := io.ReadAll(r); err != iotest.ErrTimeout {
+				t.Fatalf("want %v, got %v", iotest.ErrTimeout, err)
+			}
+
+			// Close stops read-ahead before the input is fully read.
+			r.Reset(iotest.HalfReader(bytes.NewReader(buf.Bytes())))
+			if _, err := io.ReadFull(r, make([]byte, 1000)); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Read(make([]byte, 1000)); err == nil {
+				t.Fatal("want error after Close")
+			}
+
+			// Seeking is still possible.
+			r.Reset(bytes.NewReader(buf.Bytes()))
+			rs, err := r.ReadSeeker(true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rs.Seek(-25, io.SeekEnd); err != nil {
+				t.Fatal(err)
+			}
+			got, err = io.ReadAll(rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want[len(want)-25:]) {
+				t.Fatalf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestReaderAutoDetect(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 1<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	inputs := map[string][]byte{}
+	for name, opts := range map[string][]WriterOption{
+		"s2":     {WriterBlockSize(64 << 10)},
+		"snappy": {WriterSnappyCompat()},
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, opts...)
+		w.Write(want)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		inputs[name] = buf.Bytes()
+	}
+
+	// xerial: Header and version, followed by blocks with big endian length.
+	xerial := []byte("\x82SNAPPY\x00\x00\x00\x00\x01\x00\x00\x00\x01")
+	for b := want; len(b) > 0; {
+		block := EncodeSnappy(nil, b[:min(len(b), 32<<10)])
+		xerial = binary.BigEndian.AppendUint32(xerial, uint32(len(block)))
+		xerial = append(xerial, block...)
+		b = b[min(len(b), 32<<10):]
+	}
+	inputs["xerial"] = xerial
+
+	// Hadoop: Uncompressed block size, followed by one or more compressed chunks.
+	var hadoop []byte
+	for b := want; len(b) > 0; {
+		block := b[:min(len(b), 256<<10)]
+		b = b[len(block):]
+		hadoop = binary.BigEndian.AppendUint32(hadoop, uint32(len(block)))
+		for _, chunk := range [][]byte{block[:len(block)/3], block[len(block)/3:]} {
+			enc := EncodeSnappy(nil, chunk)
+			hadoop = binary.BigEndian.AppendUint32(hadoop, uint32(len(enc)))
+			hadoop = append(hadoop, enc...)
+		}
+	}
+	inputs["hadoop"] = hadoop
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			// Test both seekable and non-seekable input.
+			for _, in := range []io.Reader{bytes.NewReader(input), struct{ io.Reader }{bytes.NewReader(input)}} {
+				r := NewReader(in, ReaderAutoDetect())
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatal("output mismatch")
+				}
+				if stats := r.Stats(); stats.Compressed != int64(len(input)) || stats.Uncompressed != int64(len(want)) {
+					t.Errorf("unexpected stats: %+v", stats)
+				}
+			}
+
+			r := NewReader(bytes.NewReader(input), ReaderAutoDetect())
+			var buf bytes.Buffer
+			if _, err := r.DecodeConcurrent(&buf, 2); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatal("output mismatch")
+			}
+
+			r.Reset(bytes.NewReader(input))
+			if err := r.Skip(100_000); err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want[100_000:]) {
+				t.Fatal("output mismatch")
+			}
+		})
+	}
+
+	// Empty input.
+	got, err := io.ReadAll(NewReader(bytes.NewReader(nil), ReaderAutoDetect()))
+	if err != nil || len(got) != 0 {
+		t.Fatalf("want empty output, got %d bytes, err: %v", len(got), err)
+	}
+	// Without the option, only streams are decoded.
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(hadoop))); err != ErrCorrupt {
+		t.Fatalf("want ErrCorrupt, got %v", err)
+	}
+}
@@ -99,12 +99,24 @@ const (
 	maxSnappyBlockSize = 1 << 16
 
 	obufHeaderLen = checksumSize + chunkHeaderSize
+
+	// dictIDSize is the size of a dictionary ID.
+	dictIDSize = 4
+	// dictChunkLen is the size of a complete dictionary ID chunk.
+	dictChunkLen = chunkHeaderSize + dictIDSize
+
+	// streamTrailerSize is the size of the stream trailer content.
+	// It contains the uncompressed size and the xxhash64 of the stream.
+	streamTrailerSize = 16
 )
 
 const (
 	chunkTypeCompressedData   = 0x00
 	chunkTypeUncompressedData = 0x01
+	chunkTypeDictID           = 0x02
 	ChunkTypeIndex            = 0x99
+	chunkTypeStreamTrailer    = 0x9a
+	chunkTypeLineIndex        = 0x9c
 	chunkTypePadding          = 0xfe
 	chunkTypeStreamIdentifier = 0xff
 )
@@ -0,0 +1,369 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+const (
+	lz4MinMatch    = 4
+	lz4MaxOffset   = 65535
+	lz4LastLits    = 5  // The last 5 bytes of a block must be literals.
+	lz4MatchLimit  = 12 // The last match must start at least 12 bytes before the end of a block.
+	lz4FrameMagic  = 0x184D2204
+	lz4BlockUncomp = 1 << 31
+)
+
+// S2ToLZ4Converter provides conversion from S2 and Snappy blocks
+// to LZ4 blocks as defined here:
+// https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md
+//
+// Literals and matches of the input are carried over to the output,
+// so no new match searching is performed.
+// Matches that cannot be represented in LZ4, for example offsets above 64KB
+// or matches shorter than 4 bytes, are emitted as literals.
+//
+// The converter keeps internal buffers, so a single converter
+// should not be used concurrently.
+type S2ToLZ4Converter struct {
+	decoded []byte
+	out     []byte
+}
+
+// MaxLZ4EncodedLen returns the maximum size of a converted LZ4 block
+// with n bytes of uncompressed data.
+func MaxLZ4EncodedLen(n int) int {
+	return n + n/255 + 16
+}
+
+// ConvertBlock will convert an S2 or Snappy block, including the block length,
+// and append it as an LZ4 block to dst.
+// The uncompressed size is returned as well.
+// dst must have capacity to contain the entire compressed block,
+// which is at most MaxLZ4EncodedLen(uncompressed size) bytes.
+func (c *S2ToLZ4Converter) ConvertBlock(dst, src []byte) ([]byte, int, error) {
+	dLen, hdr, err := decodedLen(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	if cap(dst)-len(dst) < MaxLZ4EncodedLen(dLen) {
+		return nil, 0, ErrDstTooSmall
+	}
+	// Decode the block, so we have literals for matches that must be converted.
+	// This also validates the input, so it can be parsed without checks below.
+	if cap(c.decoded) < dLen {
+		c.decoded = make([]byte, dLen)
+	}
+	decoded, err := Decode(c.decoded[:dLen], src)
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.convertBlock(dst, src[hdr:], decoded), dLen, nil
+}
+
+// convertBlock converts the S2 block src without length header.
+// decoded must contain the decoded content of src.
+func (c *S2ToLZ4Converter) convertBlock(dst, src, decoded []byte) []byte {
+	// Pending literals start at litStart.
+	// The pending match is kept so repeats continuing a match can be merged.
+	var litStart, mPos, mOff, mLen int
+	flush := func() {
+		if mLen == 0 {
+			return
+		}
+		// Check block end restrictions.
+		if mPos+lz4MatchLimit > len(decoded) {
+			return
+		}
+		if mPos+mLen > len(decoded)-lz4LastLits {
+			mLen = len(decoded) - lz4LastLits - mPos
+		}
+		if mLen < lz4MinMatch || mOff > lz4MaxOffset {
+			return
+		}
+		dst = appendLZ4Sequence(dst, decoded[litStart:mPos], mOff, mLen)
+		litStart = mPos + mLen
+	}
+
+	var s, d, offset int
+	for s < len(src) {
+		var length int
+		switch src[s] & 0x03 {
+		case tagLiteral:
+			x := uint32(src[s] >> 2)
+			switch {
+			case x < 60:
+				s++
+			case x == 60:
+				x = uint32(src[s+1])
+				s += 2
+			case x == 61:
+				x = uint32(binary.LittleEndian.Uint16(src[s+1:]))
+				s += 3
+			case x == 62:
+				x = uint32(src[s+1]) | uint32(src[s+2])<<8 | uint32(src[s+3])<<16
+				s += 4
+			case x == 63:
+				x = binary.LittleEndian.Uint32(src[s+1:])
+				s += 5
+			}
+			length = int(x) + 1
+			s += length
+			d += length
+			continue
+
+		case tagCopy1:
+			s += 2
+			toffset := int(uint32(src[s-2])&0xe0<<3 | uint32(src[s-1]))
+			length = int(src[s-2]) >> 2 & 0x7
+			if toffset == 0 {
+				// Repeat, keep last offset.
+				switch length {
+				case 5:
+					length = int(src[s]) + 4
+					s += 1
+				case 6:
+					length = int(binary.LittleEndian.Uint16(src[s:])) + 1<<8
+					s += 2
+				case 7:
+					length = int(uint32(src[s+2])<<16|uint32(src[s+1])<<8|uint32(src[s])) + 1<<16
+					s += 3
+				}
+			} else {
+				offset = toffset
+			}
+			length += 4
+		case tagCopy2:
+			offset = int(binary.LittleEndian.Uint16(src[s+1:]))
+			length = 1 + int(src[s])>>2
+			s += 3
+		case tagCopy4:
+			offset = int(binary.LittleEndian.Uint32(src[s+1:]))
+			length = 1 + int(src[s])>>2
+			s += 5
+		}
+		if mLen > 0 && d == mPos+mLen && offset == mOff {
+			// Continuation of the pending match.
+			mLen += length
+		} else {
+			flush()
+			mPos, mOff, mLen = d, offset, length
+		}
+		d += length
+	}
+	flush()
+
+	// Last sequence contains only literals.
+	return appendLZ4Sequence(dst, decoded[litStart:], 0, 0)
+}
+
+// appendLZ4Sequence appends a sequence with the literals and match to dst.
+// If matchLen is 0 only literals are emitted, which ends the block.
+func appendLZ4Sequence(dst, lits []byte, offset, matchLen int) []byte {
+	ll := len(lits)
+	ml := matchLen - lz4MinMatch
+	token := uint8(min(ll, 15)) << 4
+	if matchLen > 0 {
+		token |= uint8(min(ml, 15))
+	}
+	dst = append(dst, token)
+	if ll >= 15 {
+		dst = appendLZ4Length(dst, ll-15)
+	}
+	dst = append(dst, lits...)
+	if matchLen == 0 {
+		return dst
+	}
+	dst = append(dst, uint8(offset), uint8(offset>>8))
+	if ml >= 15 {
+		dst = appendLZ4Length(dst, ml-15)
+	}
+	return dst
+}
+
+// appendLZ4Length appends an extended LZ4 length.
+func appendLZ4Length(dst []byte, n int) []byte {
+	for n >= 255 {
+		dst = append(dst, 255)
+		n -= 255
+	}
+	return append(dst, uint8(n))
+}
+
+// ConvertStream will convert an S2 or Snappy stream read from src
+// to an LZ4 frame written to dst.
+// The frame is written with independent blocks and a content checksum.
+// Concatenated streams are converted to a single frame.
+// Compressed blocks are converted with ConvertBlock and CRCs of
+// the input are verified.
+// Streams using dictionaries are not supported.
+// The number of bytes written to dst is returned.
+func (c *S2ToLZ4Converter) ConvertStream(dst io.Writer, src io.Reader) (written int64, err error) {
+	var hdr [chunkHeaderSize]byte
+	write := func(b []byte) error {
+		n, err := dst.Write(b)
+		written += int64(n)
+		if err == nil && n != len(b) {
+			err = io.ErrShortWrite
+		}
+		return err
+	}
+	readFull := func(b []byte) error {
+		_, err := io.ReadFull(src, b)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrCorrupt
+		}
+		return err
+	}
+	// Each stream identifier determines the maximum block size of the following blocks.
+	maxBlock := maxSnappyBlockSize
+	var magic [len(magicBody)]byte
+	readStreamIdentifier := func(chunkLen int) error {
+		if chunkLen != len(magicBody) {
+			return ErrCorrupt
+		}
+		if err := readFull(magic[:]); err != nil {
+			return err
+		}
+		switch string(magic[:]) {
+		case magicBody:
+			maxBlock = maxBlockSize
+		case magicBodySnappy:
+			maxBlock = maxSnappyBlockSize
+		default:
+			return ErrCorrupt
+		}
+		return nil
+	}
+	// The input must start with a stream identifier.
+	// An empty input is an empty stream.
+	if _, err := io.ReadFull(src, hdr[:]); err != io.EOF {
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = ErrCorrupt
+			}
+			return 0, err
+		}
+		if hdr[0] != chunkTypeStreamIdentifier {
+			return 0, ErrCorrupt
+		}
+		if err := readStreamIdentifier(int(hdr[1]) | int(hdr[2])<<8 | int(hdr[3])<<16); err != nil {
+			return 0, err
+		}
+	}
+
+	// Frame header: version 1, independent blocks, content checksum.
+	// Concatenated streams may change the block size, so 4MB blocks are always used.
+	frameHdr := binary.LittleEndian.AppendUint32(c.out[:0], lz4FrameMagic)
+	frameHdr = append(frameHdr, 1<<6|1<<5|1<<2, 7<<4)
+	frameHdr = append(frameHdr, uint8(xxhash32.Checksum(frameHdr[4:])>>8))
+	if err := write(frameHdr); err != nil {
+		return written, err
+	}
+
+	var in []byte
+	xxh := xxhash32.New()
+	for {
+		if _, err := io.ReadFull(src, hdr[:]); err != nil {
+			if err != io.EOF {
+				if err == io.ErrUnexpectedEOF {
+					err = ErrCorrupt
+				}
+				return written, err
+			}
+			break
+		}
+		chunkType := hdr[0]
+		chunkLen := int(hdr[1]) | int(hdr[2])<<8 | int(hdr[3])<<16
+		switch chunkType {
+		case chunkTypeCompressedData, chunkTypeUncompressedData:
+			if chunkLen < checksumSize || chunkLen > MaxEncodedLen(maxBlock)+checksumSize {
+				return written, ErrCorrupt
+			}
+			if cap(in) < chunkLen {
+				in = make([]byte, chunkLen)
+			}
+			in = in[:chunkLen]
+			if err := readFull(in); err != nil {
+				return written, err
+			}
+			checksum := binary.LittleEndian.Uint32(in)
+			in = in[checksumSize:]
+
+			// Reserve space for the LZ4 block size.
+			out := c.out[:0]
+			var decoded []byte
+			if chunkType == chunkTypeCompressedData {
+				n, err := DecodedLen(in)
+				if err != nil {
+					return written, err
+				}
+				if n > maxBlock {
+					return written, ErrCorrupt
+				}
+				if cap(c.out) < 4+MaxLZ4EncodedLen(n) {
+					c.out = make([]byte, 4+MaxLZ4EncodedLen(maxBlock))
+				}
+				out, _, err = c.ConvertBlock(c.out[:4], in)
+				if err != nil {
+					return written, err
+				}
+				decoded = c.decoded[:n]
+			} else {
+				if len(in) > maxBlock {
+					return written, ErrCorrupt
+				}
+				decoded = in
+			}
+			if crc(decoded) != checksum {
+				return written, ErrCRC
+			}
+			xxh.Write(decoded)
+			if len(decoded) == 0 {
+				continue
+			}
+			if len(out) == 0 || len(out)-4 >= len(decoded) {
+				// Store uncompressed.
+				var tmp [4]byte
+				binary.LittleEndian.PutUint32(tmp[:], uint32(len(decoded))|lz4BlockUncomp)
+				if err := write(tmp[:]); err != nil {
+					return written, err
+				}
+				if err := write(decoded); err != nil {
+					return written, err
+				}
+				continue
+			}
+			binary.LittleEndian.PutUint32(out, uint32(len(out)-4))
+			if err := write(out); err != nil {
+				return written, err
+			}
+		case chunkTypeStreamIdentifier:
+			if err := readStreamIdentifier(chunkLen); err != nil {
+				return written, err
+			}
+		default:
+			if chunkType <= 0x7f {
+				// Reserved unskippable chunks, including dictionaries.
+				return written, ErrUnsupported
+			}
+			// Skippable chunk.
+			if _, err := io.CopyN(io.Discard, src, int64(chunkLen)); err != nil {
+				if err == io.EOF {
+					err = ErrCorrupt
+				}
+				return written, err
+			}
+		}
+	}
+	// End mark and content checksum.
+	var tmp [8]byte
+	binary.LittleEndian.PutUint32(tmp[4:], xxh.Sum32())
+	return written, write(tmp[:])
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/internal/lz4ref"
+	"github.com/klauspost/compress/internal/xxhash32"
+)
+
+// s2lz4TestInputs returns inputs that does not require downloads.
+func s2lz4TestInputs(t testing.TB) map[string][]byte {
+	rng := rand.New(rand.NewSource(1))
+	twain, err := os.ReadFile("testdata/Mark.Twain-Tom.Sawyer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 100<<10)
+	rng.Read(random)
+
+	// Matches with offsets beyond 64KB, short and overlapping matches.
+	var mixed []byte
+	for len(mixed) < 1<<20 {
+		switch rng.Intn(4) {
+		case 0:
+			mixed = append(mixed, random[:rng.Intn(100)]...)
+		case 1:
+			if len(mixed) > 100<<10 {
+				off := rng.Intn(len(mixed) - 70<<10)
+				mixed = append(mixed, mixed[off:off+rng.Intn(200)+1]...)
+			}
+		case 2:
+			mixed = append(mixed, twain[:rng.Intn(len(twain))]...)
+		case 3:
+			mixed = append(mixed, bytes.Repeat([]byte{byte(rng.Intn(3))}, rng.Intn(1000))...)
+		}
+	}
+	return map[string][]byte{
+		"empty":  {},
+		"short":  []byte("abcdefgh"),
+		"twain":  twain,
+		"random": random,
+		"mixed":  mixed,
+		"zeros":  make([]byte, 1<<20),
+	}
+}
+
+func testS2ToLZ4Block(t *testing.T, data []byte) {
+	encoders := map[string]func(dst, src []byte) []byte{
+		"default": Encode,
+		"better":  EncodeBetter,
+		"best":    EncodeBest,
+		"snappy":  EncodeSnappy,
+	}
+	var conv S2ToLZ4Converter
+	for name, enc := range encoders {
+		t.Run(name, func(t *testing.T) {
+			s2Data := enc(nil, data)
+			lz4Data, n, err := conv.ConvertBlock(make([]byte, 0, MaxLZ4EncodedLen(len(data))), s2Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(data) {
+				t.Fatalf("length mismatch: want %d, got %d", len(data), n)
+			}
+			t.Logf("%d -> s2: %d -> lz4: %d", len(data), len(s2Data), len(lz4Data))
+			got := make([]byte, len(data)+1)
+			if len(data) > 0 {
+				dn := lz4ref.UncompressBlock(got, lz4Data)
+				if dn != len(data) {
+					t.Fatalf("lz4 decode returned %d, want %d", dn, len(data))
+				}
+				if !bytes.Equal(got[:dn], data) {
+					t.Fatal("output mismatch")
+				}
+			}
+			_, _, err = conv.ConvertBlock(make([]byte, 0, MaxLZ4EncodedLen(len(data))-1), s2Data)
+			if err != ErrDstTooSmall {
+				t.Fatalf("want ErrDstTooSmall, got %v", err)
+			}
+		})
+	}
+}
+
+func TestS2ToLZ4Converter_ConvertBlock(t *testing.T) {
+	for name, data := range s2lz4TestInputs(t) {
+		t.Run(name, func(t *testing.T) {
+			testS2ToLZ4Block(t, data)
+		})
+	}
+	for _, tf := range testFiles {
+		t.Run(tf.label, func(t *testing.T) {
+			if err := downloadBenchmarkFiles(t, tf.filename); err != nil {
+				t.Fatalf("failed to download testdata: %s", err)
+			}
+
+			bDir := filepath.FromSlash(*benchdataDir)
+			data := readFile(t, filepath.Join(bDir, tf.filename))
+			if n := tf.sizeLimit; 0 < n && n < len(data) {
+				data = data[:n]
+			}
+			testS2ToLZ4Block(t, data)
+		})
+	}
+}
+
+func TestS2ToLZ4Converter_ConvertBlockCorrupt(t *testing.T) {
+	data := Encode(nil, bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), 100))
+	var conv S2ToLZ4Converter
+	for i := 1; i < len(data); i++ {
+		_, _, err := conv.ConvertBlock(make([]byte, 0, 10000), data[:i])
+		if err == nil {
+			t.Fatalf("truncated block at %d: no error", i)
+		}
+	}
+}
+
+// decodeLZ4Frame decodes an LZ4 frame with the features used by the converter.
+func decodeLZ4Frame(t *testing.T, frame []byte) []byte {
+	t.Helper()
+	if len(frame) < 7 || binary.LittleEndian.Uint32(frame) != lz4FrameMagic {
+		t.Fatal("invalid frame magic")
+	}
+	flg, bd, hc := frame[4], frame[5], frame[6]
+	if flg != 0x64 {
+		t.Fatalf("unexpected FLG: 0x%x", flg)
+	}
+	if hc != uint8(xxhash32.Checksum(frame[4:6])>>8) {
+		t.Fatal("header checksum mismatch")
+	}
+	maxBlock := 1 << (8 + 2*(bd>>4))
+	frame = frame[7:]
+	var out []byte
+	for {
+		bSize := binary.LittleEndian.Uint32(frame)
+		frame = frame[4:]
+		if bSize == 0 {
+			break
+		}
+		n := int(bSize &^ lz4BlockUncomp)
+		if n > maxBlock {
+			t.Fatalf("block size %d exceeds max %d", n, maxBlock)
+		}
+		if bSize&lz4BlockUncomp != 0 {
+			out = append(out, frame[:n]...)
+		} else {
+			dst := make([]byte, maxBlock)
+			dn := lz4ref.UncompressBlock(dst, frame[:n])
+			if dn < 0 {
+				t.Fatalf("block decode error %d", dn)
+			}
+			out = append(out, dst[:dn]...)
+		}
+		frame = frame[n:]
+	}
+	if len(frame) != 4 {
+		t.Fatalf("unexpected trailing bytes: %d", len(frame))
+	}
+	if got, want := binary.LittleEndian.Uint32(frame), xxhash32.Checksum(out); got != want {
+		t.Fatalf("content checksum mismatch: 0x%08x != 0x%08x", got, want)
+	}
+	return out
+}
+
+func TestS2ToLZ4Converter_ConvertStream(t *testing.T) {
+	opts := map[string][]WriterOption{
+		"default":      nil,
+		"best-index":   {WriterBestCompression(), WriterAddIndex()},
+		"small-blocks": {WriterBlockSize(4 << 10), WriterPadding(1000)},
+		"snappy":       {WriterSnappyCompat()},
+		"uncompressed": {WriterUncompressed()},
+	}
+	for name, data := range s2lz4TestInputs(t) {
+		for optName, opt := range opts {
+			if len(data) == 0 && optName == "best-index" {
+				// An empty stream with an index has no stream identifier,
+				// which is rejected, like by the Reader.
+				continue
+			}
+			t.Run(name+"-"+optName, func(t *testing.T) {
+				var s2Stream, lz4Frame bytes.Buffer
+				w := NewWriter(&s2Stream, opt...)
+				if _, err := w.Write(data); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				var conv S2ToLZ4Converter
+				n, err := conv.ConvertStream(&lz4Frame, &s2Stream)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != int64(lz4Frame.Len()) {
+					t.Fatalf("written mismatch: %d != %d", n, lz4Frame.Len())
+				}
+				got := decodeLZ4Frame(t, lz4Frame.Bytes())
+				if !bytes.Equal(got, data) {
+					t.Fatal("output mismatch")
+				}
+			})
+		}
+	}
+}
+
+func TestS2ToLZ4Converter_ConvertStreamConcat(t *testing.T) {
+	// A Snappy stream followed by an S2 stream with blocks above 64KB.
+	data := bytes.Repeat([]byte("Snappy followed by S2. "), 100000)
+	var s2Stream, lz4Frame bytes.Buffer
+	for _, opt := range [][]WriterOption{{WriterSnappyCompat()}, {WriterBlockSize(1 << 20)}} {
+		w := NewWriter(&s2Stream, opt...)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var conv S2ToLZ4Converter
+	if _, err := conv.ConvertStream(&lz4Frame, &s2Stream); err != nil {
+		t.Fatal(err)
+	}
+	got := decodeLZ4Frame(t, lz4Frame.Bytes())
+	if !bytes.Equal(got, append(data, data...)) {
+		t.Fatal("output mismatch")
+	}
+}
+
+func BenchmarkS2ToLZ4Converter_ConvertBlock(b *testing.B) {
+	for name, data := range s2lz4TestInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			var conv S2ToLZ4Converter
+			s2Data := Encode(nil, data)
+			dst := make([]byte, 0, MaxLZ4EncodedLen(len(data)))
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _, err := conv.ConvertBlock(dst, s2Data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
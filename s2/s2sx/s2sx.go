@@ -0,0 +1,326 @@
+// Copyright (c) 2021+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package s2sx creates and reads self-extracting S2 archives.
+//
+// A self-extracting archive is an unpacker executable,
+// followed by a byte describing the content and an S2 stream.
+// The unpacker locates the stream after the sections of the executable,
+// so no changes to the executable are needed.
+//
+// Unpacker executables are built from s2/cmd/s2sx/_unpack.
+package s2sx
+
+import (
+	"archive/tar"
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// Mode describes the content of an archive.
+type Mode uint8
+
+const (
+	// ModeUnpack means the content is a single file.
+	ModeUnpack Mode = iota + 1
+
+	// ModeUntar means the content is a tar file,
+	// which will be extracted to a directory.
+	ModeUntar
+)
+
+// ErrNoArchive is returned if no archive is found in an executable.
+var ErrNoArchive = errors.New("s2sx: no archive data found")
+
+// WriteHeader writes the unpacker executable and the mode to w.
+// The compressed stream must be written after the header.
+func WriteHeader(w io.Writer, unpacker []byte, mode Mode) error {
+	if mode != ModeUnpack && mode != ModeUntar {
+		return fmt.Errorf("s2sx: unknown mode %d", mode)
+	}
+	if _, err := w.Write(unpacker); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte{byte(mode)})
+	return err
+}
+
+// Create writes a self-extracting archive with the content of src to w.
+// The unpacker must be an uncompressed unpacker executable for the destination platform.
+// If src is already an S2 or Snappy stream, it is copied as is,
+// otherwise it is compressed using the supplied options.
+// The number of bytes read from src is returned.
+func Create(w io.Writer, unpacker []byte, mode Mode, src io.Reader, opts ...s2.WriterOption) (int64, error) {
+	if err := WriteHeader(w, unpacker, mode); err != nil {
+		return 0, err
+	}
+	compressed, src, err := IsStream(src)
+	if err != nil {
+		return 0, err
+	}
+	if compressed {
+		return io.Copy(w, src)
+	}
+	enc := s2.NewWriter(w, opts...)
+	n, err := enc.ReadFrom(src)
+	if err != nil {
+		enc.Close()
+		return n, err
+	}
+	return n, enc.Close()
+}
+
+// IsStream returns whether r starts with an S2 or Snappy stream identifier.
+// The returned reader will return all content of r.
+func IsStream(r io.Reader) (bool, io.Reader, error) {
+	var tmp [4]byte
+	n, err := io.ReadFull(r, tmp[:])
+	switch err {
+	case nil:
+		return bytes.Equal(tmp[:], []byte{0xff, 0x06, 0x00, 0x00}), io.MultiReader(bytes.NewReader(tmp[:]), r), nil
+	case io.ErrUnexpectedEOF, io.EOF:
+		return false, bytes.NewReader(tmp[:n]), nil
+	}
+	return false, nil, err
+}
+
+// Open locates the archive in an executable with the given size.
+// The mode and a reader returning the compressed stream is returned.
+// If the archive has been split, the remaining content must be appended to the returned reader.
+// If no archive is found, ErrNoArchive is returned.
+func Open(exe io.ReaderAt, size int64) (Mode, io.Reader, error) {
+	handlers := []func(io.ReaderAt) (int64, error){
+		exeEndMacho,
+		exeEndElf,
+		exeEndPe,
+	}
+	for _, handler := range handlers {
+		end, err := handler(exe)
+		if err != nil {
+			continue
+		}
+		if end >= size {
+			return 0, nil, ErrNoArchive
+		}
+		var tmp [1]byte
+		if _, err := exe.ReadAt(tmp[:], end); err != nil {
+			return 0, nil, err
+		}
+		return Mode(tmp[0]), io.NewSectionReader(exe, end+1, size-end-1), nil
+	}
+	return 0, nil, ErrNoArchive
+}
+
+// exeEndPe returns the end of the sections of a Portable Executable binary.
+func exeEndPe(rda io.ReaderAt) (int64, error) {
+	file, err := pe.NewFile(rda)
+	if err != nil {
+		return 0, err
+	}
+	var end int64
+	for _, sec := range file.Sections {
+		end = max(end, int64(sec.Offset)+int64(sec.Size))
+	}
+	return end, nil
+}
+
+// exeEndElf returns the end of the sections of an ELF binary.
+func exeEndElf(rda io.ReaderAt) (int64, error) {
+	file, err := elf.NewFile(rda)
+	if err != nil {
+		return 0, err
+	}
+	var end int64
+	for _, sect := range file.Sections {
+		if sect.Type == elf.SHT_NOBITS {
+			continue
+		}
+		// Size is the uncompressed size of compressed sections.
+		end = max(end, int64(sect.Offset+sect.FileSize))
+	}
+	return end, nil
+}
+
+// exeEndMacho returns the end of the segments of a Mach-O binary.
+func exeEndMacho(rda io.ReaderAt) (int64, error) {
+	file, err := macho.NewFile(rda)
+	if err != nil {
+		return 0, err
+	}
+	var end int64
+	for _, load := range file.Loads {
+		if seg, ok := load.(*macho.Segment); ok {
+			end = max(end, int64(seg.Offset+seg.Filesz))
+		}
+	}
+	return end, nil
+}
+
+// Untar extracts the uncompressed tar file r to the directory dst.
+// If fn is not nil, it is called with the path of each extracted file.
+// Files, links and link targets outside dst are rejected,
+// including paths that would leave dst through symlinks in the archive.
+func Untar(dst string, r io.Reader, fn func(path string)) error {
+	realDst, err := realPath(dst)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkPath(dst, header.Name); err != nil {
+			return err
+		}
+		target := filepath.Join(dst, header.Name)
+
+		// Resolve symlinks created earlier, so the entry cannot be written outside dst.
+		parent, err := realPath(filepath.Dir(target))
+		if err != nil {
+			return err
+		}
+		if !within(realDst, parent) {
+			return fmt.Errorf("s2sx: illegal file path: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeChar, tar.TypeBlock, tar.TypeFifo, tar.TypeGNUSparse:
+			if err := writeFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// A symlink target is resolved relative to the link's own
+			// directory (or used as-is when absolute); reject any that
+			// escape dst so the link cannot point outside the archive root.
+			// Symlinks in the target are resolved as well.
+			linkTarget := header.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(parent, linkTarget)
+			}
+			if realTarget, err := realPath(linkTarget); err != nil || !within(realDst, realTarget) {
+				return fmt.Errorf("s2sx: illegal link target: %s", header.Linkname)
+			}
+			if err := writeLink(target, header.Linkname, os.Symlink); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// Hardlink targets are relative to the archive root.
+			if err := checkPath(dst, header.Linkname); err != nil {
+				return err
+			}
+			if realTarget, err := realPath(filepath.Join(dst, header.Linkname)); err != nil || !within(realDst, realTarget) {
+				return fmt.Errorf("s2sx: illegal link target: %s", header.Linkname)
+			}
+			if err := writeLink(target, filepath.Join(dst, header.Linkname), os.Link); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		if fn != nil {
+			fn(target)
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Don't write through an existing symlink.
+	if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeLink(fpath, target string, link func(oldname, newname string) error) error {
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return fmt.Errorf("%s: making directory for file: %v", fpath, err)
+	}
+	if _, err := os.Lstat(fpath); err == nil {
+		if err := os.Remove(fpath); err != nil {
+			return fmt.Errorf("%s: failed to unlink: %v", fpath, err)
+		}
+	}
+	if err := link(target, fpath); err != nil {
+		return fmt.Errorf("%s: making link: %v", fpath, err)
+	}
+	return nil
+}
+
+// checkPath returns an error if filename is outside dst.
+func checkPath(dst, filename string) error {
+	if !within(dst, filepath.Join(dst, filename)) {
+		return fmt.Errorf("s2sx: illegal file path: %s", filename)
+	}
+	return nil
+}
+
+// realPath returns the absolute path of p with symlinks resolved.
+// Components of p that don't exist yet are appended to the resolved path
+// of the deepest existing directory.
+func realPath(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := p, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolved, rest), nil
+}
+
+// within reports whether target stays inside dst. A raw strings.HasPrefix
+// check is not separator-aware: dst "out" would wrongly accept the sibling
+// "out_evil". filepath.Rel collapses the path and a ".." result means escape.
+func within(dst, target string) bool {
+	rel, err := filepath.Rel(dst, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2021+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2sx
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+)
+
+func testData(n int) []byte {
+	rng := rand.New(rand.NewSource(int64(n)))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 'a' + uint8(rng.Intn(4))
+	}
+	return b
+}
+
+func testTar(t testing.TB) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	add := func(hdr *tar.Header, content []byte) {
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}, nil)
+	add(&tar.Header{Name: "dir/file.txt", Typeflag: tar.TypeReg, Mode: 0644}, testData(100000))
+	add(&tar.Header{Name: "dir/sub/other.txt", Typeflag: tar.TypeReg, Mode: 0600}, []byte("other"))
+	add(&tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "file.txt"}, nil)
+	add(&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/sub/other.txt"}, nil)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checkTar(t testing.TB, dir string) {
+	got, err := os.ReadFile(filepath.Join(dir, "dir", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, testData(100000)) {
+		t.Fatal("file.txt mismatch")
+	}
+	if runtime.GOOS == "windows" {
+		// Links may not be supported.
+		return
+	}
+	got, err = os.ReadFile(filepath.Join(dir, "dir", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, testData(100000)) {
+		t.Fatal("link mismatch")
+	}
+	got, err = os.ReadFile(filepath.Join(dir, "hard"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "other" {
+		t.Fatalf("hard link mismatch, got %q", got)
+	}
+}
+
+func TestCreateOpen(t *testing.T) {
+	// Use the test executable as unpacker.
+	me, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	unpacker, err := os.ReadFile(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Open(bytes.NewReader(unpacker), int64(len(unpacker))); err != ErrNoArchive {
+		t.Fatalf("want ErrNoArchive, got %v", err)
+	}
+	data := testData(1 << 20)
+	var compressed bytes.Buffer
+	enc := s2.NewWriter(&compressed)
+	enc.Write(data)
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string][]byte{"raw": data, "compressed": compressed.Bytes(), "empty": nil} {
+		t.Run(name, func(t *testing.T) {
+			for _, mode := range []Mode{ModeUnpack, ModeUntar} {
+				var buf bytes.Buffer
+				n, err := Create(&buf, unpacker, mode, bytes.NewReader(src))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != int64(len(src)) {
+					t.Fatalf("want %d bytes read, got %d", len(src), n)
+				}
+				gotMode, rd, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if gotMode != mode {
+					t.Fatalf("want mode %d, got %d", mode, gotMode)
+				}
+				got, err := io.ReadAll(s2.NewReader(rd))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := src
+				if name == "compressed" {
+					want = data
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatal("output mismatch")
+				}
+			}
+		})
+	}
+	if err := WriteHeader(io.Discard, unpacker, 0); err == nil {
+		t.Fatal("want error on invalid mode")
+	}
+}
+
+func TestUntar(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	if err := Untar(dir, bytes.NewReader(testTar(t)), func(path string) { names = append(names, path) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 5 {
+		t.Fatalf("want 5 entries, got %v", names)
+	}
+	checkTar(t, dir)
+
+	// Paths outside the destination must be rejected.
+	for _, hdr := range []tar.Header{
+		{Name: "../evil", Typeflag: tar.TypeReg},
+		{Name: "a/../../evil", Typeflag: tar.TypeReg},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../evil"},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		{Name: "link", Typeflag: tar.TypeLink, Linkname: "../evil"},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		dst := filepath.Join(t.TempDir(), "out")
+		err := Untar(dst, &buf, nil)
+		if err == nil || !strings.Contains(err.Error(), "illegal") {
+			t.Errorf("%s -> %s: want illegal path error, got %v", hdr.Name, hdr.Linkname, err)
+		}
+	}
+}
+
+func TestUntarSymlinkChain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("links may not be supported")
+	}
+	tests := map[string][]tar.Header{
+		"file": {
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c/evil.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"overwrite": {
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c", Typeflag: tar.TypeSymlink, Linkname: "../evil.txt"},
+		},
+		"hardlink": {
+			{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "a/b/c", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "hard", Typeflag: tar.TypeLink, Linkname: "a/b/c/evil.txt"},
+		},
+	}
+	for name, hdrs := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, hdr := range hdrs {
+				if err := tw.WriteHeader(&hdr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tw.Close()
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "out")
+			err := Untar(dst, &buf, nil)
+			if err == nil || !strings.Contains(err.Error(), "illegal") {
+				t.Errorf("want illegal path error, got %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "evil.txt")); err == nil {
+				t.Error("file written outside destination")
+			}
+		})
+	}
+
+	// Writing a file over a symlink replaces the link.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []tar.Header{
+		{Name: "target.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "target.txt"},
+		{Name: "link", Typeflag: tar.TypeReg, Mode: 0644, Size: 1},
+	} {
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte(hdr.Name[:1]))
+		}
+	}
+	tw.Close()
+	dst := t.TempDir()
+	if err := Untar(dst, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dst, "target.txt")); err != nil || string(got) != "t" {
+		t.Fatalf("target.txt: got %q, %v", got, err)
+	}
+}
+
+// TestExtract builds the unpacker and runs generated archives.
+func TestExtract(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("unpacker only tested on linux/amd64 and linux/arm64")
+	}
+	goBin, err := exec.LookPath(filepath.Join(runtime.GOROOT(), "bin", "go"))
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	unpackerPath := filepath.Join(dir, "unpack")
+	cmd := exec.Command(goBin, "build", "-o", unpackerPath, "../cmd/s2sx/_unpack/main.go")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building unpacker: %v\n%s", err, out)
+	}
+	unpacker, err := os.ReadFile(unpackerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(name string, mode Mode, src []byte) string {
+		var buf bytes.Buffer
+		if _, err := Create(&buf, unpacker, mode, bytes.NewReader(src), s2.WriterConcurrency(2)); err != nil {
+			t.Fatal(err)
+		}
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, buf.Bytes(), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return fn
+	}
+	run := func(exe string, args ...string) {
+		cmd := exec.Command(exe, args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("running %s: %v\n%s", exe, err, out)
+		}
+	}
+
+	data := testData(1 << 20)
+	exe := create("file.s2sx", ModeUnpack, data)
+	run(exe, "-q")
+	got, err := os.ReadFile(filepath.Join(dir, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("output mismatch")
+	}
+	run(exe, "-q", "other")
+	got, err = os.ReadFile(filepath.Join(dir, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("output mismatch")
+	}
+
+	exe = create("archive.s2sx", ModeUntar, testTar(t))
+	run(exe, "-q", "untarred")
+	checkTar(t, filepath.Join(dir, "untarred"))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026+ Klaus Post. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package s2
+
+import "sync/atomic"
+
+// Stats contains statistics of a compressed stream.
+type Stats struct {
+	Uncompressed       int64 // Uncompressed bytes in data blocks.
+	Compressed         int64 // Compressed bytes, including stream headers and skippable chunks.
+	Blocks             int64 // Number of data blocks.
+	UncompressedBlocks int64 // Number of data blocks stored uncompressed.
+	CRCFailures        int64 // Number of blocks with a CRC mismatch. Only set by the Reader.
+}
+
+// streamStats is updated atomically, so stats can be read
+// while the stream is being processed.
+type streamStats struct {
+	uncompressed       atomic.Int64
+	compressed         atomic.Int64
+	blocks             atomic.Int64
+	uncompressedBlocks atomic.Int64
+	crcFailures        atomic.Int64
+}
+
+func (s *streamStats) load() Stats {
+	return Stats{
+		Uncompressed:       s.uncompressed.Load(),
+		Compressed:         s.compressed.Load(),
+		Blocks:             s.blocks.Load(),
+		UncompressedBlocks: s.uncompressedBlocks.Load(),
+		CRCFailures:        s.crcFailures.Load(),
+	}
+}
+
+func (s *streamStats) reset() {
+	s.uncompressed.Store(0)
+	s.compressed.Store(0)
+	s.blocks.Store(0)
+	s.uncompressedBlocks.Store(0)
+	s.crcFailures.Store(0)
+}
+
+// addBlock adds a data block with n uncompressed bytes.
+func (s *streamStats) addBlock(n int, stored bool) {
+	s.uncompressed.Add(int64(n))
+	s.blocks.Add(1)
+	if stored {
+		s.uncompressedBlocks.Add(1)
+	}
+}
+
+// Stats returns statistics of the blocks written to the output so far.
+// Blocks that are still being compressed are not included.
+// Stats are reset by Reset.
+// It is safe to call Stats while the stream is being written.
+func (w *Writer) Stats() Stats {
+	return w.stats.load()
+}
+
+// WriterProgressCB will call fn with the stream statistics
+// after each data block has been written to the output.
+// Blocks are reported in stream order.
+// When compressing concurrently, fn is called from a separate goroutine,
+// but calls will not be done concurrently.
+// The callback should not call any methods on the Writer, except Stats.
+func WriterProgressCB(fn func(Stats)) WriterOption {
+	return func(w *Writer) error {
+		w.progressCB = fn
+		return nil
+	}
+}
+
+// blockWritten must be called after a data block has been written to the output.
+func (w *Writer) blockWritten(n int, stored bool) {
+	w.stats.addBlock(n, stored)
+	if w.progressCB != nil {
+		w.progressCB(w.stats.load())
+	}
+}
+
+// Stats returns statistics of the stream read so far.
+// Compressed is the number of bytes read from the input,
+// and Uncompressed is the size of the data blocks read,
+// including blocks that are skipped.
+// CRCFailures is only updated when CRC checks are enabled.
+// Stats are reset by Reset.
+func (r *Reader) Stats() Stats {
+	return r.stats.load()
+}
@@ -15,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/klauspost/compress/internal/race"
+	"github.com/klauspost/compress/internal/xxhash"
 )
 
 const (
@@ -44,6 +45,10 @@ func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
 			return &w2
 		}
 	}
+	if w2.dict != nil && w2.snappy {
+		w2.errState = errors.New("s2: dictionaries cannot be used with Snappy compatible output")
+		return &w2
+	}
 	w2.obufLen = obufHeaderLen + MaxEncodedLen(w2.blockSize)
 	w2.paramsOK = true
 	w2.ibuf = make([]byte, 0, w2.blockSize)
@@ -76,6 +81,12 @@ type Writer struct {
 	writerWg  sync.WaitGroup
 	index     Index
 	customEnc func(dst, src []byte) int
+	dict      *Dict
+	// streamHash is the hash of all content, if stream checksums are enabled.
+	streamHash *xxhash.Digest
+	adaptive   *adaptiveState
+	lineIndex  *LineIndex
+	stats      streamStats
 
 	// wroteStreamHeader is whether we have written the stream header.
 	wroteStreamHeader bool
@@ -84,6 +95,7 @@ type Writer struct {
 	flushOnWrite      bool
 	appendIndex       bool
 	bufferCB          func([]byte)
+	progressCB        func(Stats)
 	level             uint8
 }
 
@@ -93,6 +105,8 @@ type result struct {
 	ret []byte
 	// Uncompressed start offset
 	startOffset int64
+	// Uncompressed size, if b is a data block.
+	blockLen int
 }
 
 // err returns the previously set error.
@@ -126,7 +140,17 @@ func (w *Writer) Reset(writer io.Writer) {
 	w.written = 0
 	w.writer = writer
 	w.uncompWritten = 0
+	w.stats.reset()
 	w.index.reset(w.blockSize)
+	if w.streamHash != nil {
+		w.streamHash.Reset()
+	}
+	if w.adaptive != nil {
+		w.adaptive.reset()
+	}
+	if w.lineIndex != nil {
+		w.lineIndex.reset()
+	}
 
 	// If we didn't get a writer, stop here.
 	if writer == nil {
@@ -166,6 +190,10 @@ func (w *Writer) Reset(writer io.Writer) {
 					_ = w.err(err)
 					w.err(w.index.add(w.written, input.startOffset))
 					w.written += int64(n)
+					w.stats.compressed.Add(int64(n))
+					if input.blockLen > 0 && err == nil {
+						w.blockWritten(input.blockLen, in[0] == chunkTypeUncompressedData)
+					}
 				}
 			}
 			if cap(in) >= w.obufLen {
@@ -268,6 +296,7 @@ func (w *Writer) ReadFrom(r io.Reader) (n int64, err error) {
 
 // AddSkippableBlock will add a skippable block to the stream.
 // The ID must be 0x80-0xfe (inclusive).
+// If WriterStreamChecksum is used, ID 0x9a is reserved for the stream trailer.
 // Length of the skippable block must be <= 16777215 bytes.
 func (w *Writer) AddSkippableBlock(id uint8, data []byte) (err error) {
 	if err := w.err(nil); err != nil {
@@ -279,6 +308,9 @@ func (w *Writer) AddSkippableBlock(id uint8, data []byte) (err error) {
 	if id < 0x80 || id > chunkTypePadding {
 		return fmt.Errorf("invalid skippable block id %x", id)
 	}
+	if id == chunkTypeStreamTrailer && w.streamHash != nil {
+		return fmt.Errorf("skippable block id %x is reserved for the stream trailer", id)
+	}
 	if len(data) > maxChunkSize {
 		return fmt.Errorf("skippable block excessed maximum size")
 	}
@@ -298,18 +330,13 @@ func (w *Writer) AddSkippableBlock(id uint8, data []byte) (err error) {
 				return w.err(io.ErrShortWrite)
 			}
 			w.written += int64(n)
+			w.stats.compressed.Add(int64(n))
 			return w.err(nil)
 		}
 		if !w.wroteStreamHeader {
 			w.wroteStreamHeader = true
-			if w.snappy {
-				if err := write([]byte(magicChunkSnappy)); err != nil {
-					return err
-				}
-			} else {
-				if err := write([]byte(magicChunk)); err != nil {
-					return err
-				}
+			if err := write(w.streamHeader()); err != nil {
+				return err
 			}
 		}
 		if err := write(header[:]); err != nil {
@@ -323,11 +350,7 @@ func (w *Writer) AddSkippableBlock(id uint8, data []byte) (err error) {
 		w.wroteStreamHeader = true
 		hWriter := make(chan result)
 		w.output <- hWriter
-		if w.snappy {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkSnappyBytes}
-		} else {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkBytes}
-		}
+		hWriter <- result{startOffset: w.uncompWritten, b: w.streamHeader()}
 	}
 
 	// Copy input.
@@ -383,11 +406,7 @@ func (w *Writer) EncodeBuffer(buf []byte) (err error) {
 		w.wroteStreamHeader = true
 		hWriter := make(chan result)
 		w.output <- hWriter
-		if w.snappy {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkSnappyBytes}
-		} else {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkBytes}
-		}
+		hWriter <- result{startOffset: w.uncompWritten, b: w.streamHeader()}
 	}
 	orgBuf := buf
 	for len(buf) > 0 {
@@ -406,8 +425,10 @@ func (w *Writer) EncodeBuffer(buf []byte) (err error) {
 		w.output <- output
 		res := result{
 			startOffset: w.uncompWritten,
+			blockLen:    len(uncompressed),
 		}
 		w.uncompWritten += int64(len(uncompressed))
+		w.trackBlock(uncompressed)
 		if len(buf) == 0 && w.bufferCB != nil {
 			res.ret = orgBuf
 		}
@@ -452,14 +473,78 @@ func (w *Writer) EncodeBuffer(buf []byte) (err error) {
 	return nil
 }
 
+// streamHeader returns the stream identifier chunk.
+// If a dictionary is used, the dictionary chunk is appended.
+// If stream checksums are enabled, an empty trailer chunk is appended,
+// signaling that the stream will end with a trailer.
+func (w *Writer) streamHeader() []byte {
+	if w.streamHash != nil {
+		hdr := make([]byte, 0, len(magicChunk)+dictChunkLen+chunkHeaderSize)
+		if w.snappy {
+			hdr = append(hdr, magicChunkSnappy...)
+		} else {
+			hdr = append(hdr, magicChunk...)
+		}
+		if w.dict != nil {
+			hdr = appendDictChunk(hdr, w.dict.ID())
+		}
+		return append(hdr, chunkTypeStreamTrailer, 0, 0, 0)
+	}
+	if w.snappy {
+		return magicChunkSnappyBytes
+	}
+	if w.dict != nil {
+		return appendDictChunk(append(make([]byte, 0, len(magicChunk)+dictChunkLen), magicChunk...), w.dict.ID())
+	}
+	return magicChunkBytes
+}
+
+// trackBlock adds the uncompressed content of a block
+// to the stream checksum and line index, if enabled.
+// Must be called in stream order, after uncompWritten has been updated.
+func (w *Writer) trackBlock(uncompressed []byte) {
+	if w.streamHash != nil {
+		w.streamHash.Write(uncompressed)
+	}
+	if w.lineIndex != nil {
+		w.lineIndex.addBlock(w.uncompWritten-int64(len(uncompressed)), uncompressed)
+	}
+}
+
+// streamTrailer returns the stream trailer chunk.
+func (w *Writer) streamTrailer() []byte {
+	b := make([]byte, 0, chunkHeaderSize+streamTrailerSize)
+	b = append(b, chunkTypeStreamTrailer, streamTrailerSize, 0, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(w.uncompWritten))
+	return binary.LittleEndian.AppendUint64(b, w.streamHash.Sum64())
+}
+
 func (w *Writer) encodeBlock(obuf, uncompressed []byte) int {
 	if w.customEnc != nil {
 		if ret := w.customEnc(obuf, uncompressed); ret >= 0 {
 			return ret
 		}
 	}
+	level := w.level
+	if w.adaptive != nil {
+		level = w.adaptive.level(uncompressed)
+	}
+	if w.dict != nil {
+		if len(uncompressed) < minNonLiteralBlockSize {
+			return 0
+		}
+		switch level {
+		case levelFast:
+			return encodeBlockDictGo(obuf, uncompressed, w.dict)
+		case levelBetter:
+			return encodeBlockBetterDict(obuf, uncompressed, w.dict)
+		case levelBest:
+			return encodeBlockBest(obuf, uncompressed, w.dict)
+		}
+		return 0
+	}
 	if w.snappy {
-		switch w.level {
+		switch level {
 		case levelFast:
 			return encodeBlockSnappy(obuf, uncompressed)
 		case levelBetter:
@@ -469,7 +554,7 @@ func (w *Writer) encodeBlock(obuf, uncompressed []byte) int {
 		}
 		return 0
 	}
-	switch w.level {
+	switch level {
 	case levelFast:
 		return encodeBlock(obuf, uncompressed)
 	case levelBetter:
@@ -494,11 +579,7 @@ func (w *Writer) write(p []byte) (nRet int, errRet error) {
 			w.wroteStreamHeader = true
 			hWriter := make(chan result)
 			w.output <- hWriter
-			if w.snappy {
-				hWriter <- result{startOffset: w.uncompWritten, b: magicChunkSnappyBytes}
-			} else {
-				hWriter <- result{startOffset: w.uncompWritten, b: magicChunkBytes}
-			}
+			hWriter <- result{startOffset: w.uncompWritten, b: w.streamHeader()}
 		}
 
 		var uncompressed []byte
@@ -520,8 +601,10 @@ func (w *Writer) write(p []byte) (nRet int, errRet error) {
 		w.output <- output
 		res := result{
 			startOffset: w.uncompWritten,
+			blockLen:    len(uncompressed),
 		}
 		w.uncompWritten += int64(len(uncompressed))
+		w.trackBlock(uncompressed)
 
 		go func() {
 			checksum := crc(uncompressed)
@@ -588,11 +671,7 @@ func (w *Writer) writeFull(inbuf []byte) (errRet error) {
 		w.wroteStreamHeader = true
 		hWriter := make(chan result)
 		w.output <- hWriter
-		if w.snappy {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkSnappyBytes}
-		} else {
-			hWriter <- result{startOffset: w.uncompWritten, b: magicChunkBytes}
-		}
+		hWriter <- result{startOffset: w.uncompWritten, b: w.streamHeader()}
 	}
 
 	// Get an output buffer.
@@ -604,8 +683,10 @@ func (w *Writer) writeFull(inbuf []byte) (errRet error) {
 	w.output <- output
 	res := result{
 		startOffset: w.uncompWritten,
+		blockLen:    len(uncompressed),
 	}
 	w.uncompWritten += int64(len(uncompressed))
+	w.trackBlock(uncompressed)
 
 	go func() {
 		checksum := crc(uncompressed)
@@ -654,20 +735,16 @@ func (w *Writer) writeSync(p []byte) (nRet int, errRet error) {
 	}
 	if !w.wroteStreamHeader {
 		w.wroteStreamHeader = true
-		var n int
-		var err error
-		if w.snappy {
-			n, err = w.writer.Write(magicChunkSnappyBytes)
-		} else {
-			n, err = w.writer.Write(magicChunkBytes)
-		}
+		hdr := w.streamHeader()
+		n, err := w.writer.Write(hdr)
 		if err != nil {
 			return 0, w.err(err)
 		}
-		if n != len(magicChunk) {
+		if n != len(hdr) {
 			return 0, w.err(io.ErrShortWrite)
 		}
 		w.written += int64(n)
+		w.stats.compressed.Add(int64(n))
 	}
 
 	for len(p) > 0 {
@@ -716,7 +793,9 @@ func (w *Writer) writeSync(p []byte) (nRet int, errRet error) {
 		}
 		w.err(w.index.add(w.written, w.uncompWritten))
 		w.written += int64(n)
+		w.stats.compressed.Add(int64(n))
 		w.uncompWritten += int64(len(uncompressed))
+		w.trackBlock(uncompressed)
 
 		if chunkType == chunkTypeUncompressedData {
 			// Write uncompressed data.
@@ -728,7 +807,9 @@ func (w *Writer) writeSync(p []byte) (nRet int, errRet error) {
 				return 0, w.err(io.ErrShortWrite)
 			}
 			w.written += int64(n)
+			w.stats.compressed.Add(int64(n))
 		}
+		w.blockWritten(len(uncompressed), chunkType == chunkTypeUncompressedData)
 		w.buffers.Put(obuf)
 		// Queue final output.
 		nRet += len(uncompressed)
@@ -804,7 +885,29 @@ func (w *Writer) closeIndex(idx bool) ([]byte, error) {
 	}
 
 	var index []byte
-	if w.err(err) == nil && w.writer != nil {
+	if w.err(err) == nil && w.writer != nil && (w.streamHash != nil || w.lineIndex != nil) {
+		// Write the stream header, if nothing has been written,
+		// followed by the line index and the trailer.
+		var b []byte
+		if !w.wroteStreamHeader {
+			w.wroteStreamHeader = true
+			b = w.streamHeader()
+		}
+		if w.lineIndex != nil {
+			b = w.lineIndex.appendTo(b, w.uncompWritten)
+		}
+		if w.streamHash != nil {
+			b = append(b, w.streamTrailer()...)
+		}
+		n, err2 := w.writer.Write(b)
+		if err2 == nil && n != len(b) {
+			err2 = io.ErrShortWrite
+		}
+		w.written += int64(n)
+		w.stats.compressed.Add(int64(n))
+		_ = w.err(err2)
+	}
+	if w.err(nil) == nil && w.writer != nil {
 		// Create index.
 		if idx {
 			compSize := int64(-1)
@@ -834,6 +937,7 @@ func (w *Writer) closeIndex(idx bool) ([]byte, error) {
 			if err2 == nil && n != len(frame) {
 				err2 = io.ErrShortWrite
 			}
+			w.stats.compressed.Add(int64(n))
 			_ = w.err(err2)
 		}
 		if len(index) > 0 && w.appendIndex {
@@ -841,6 +945,7 @@ func (w *Writer) closeIndex(idx bool) ([]byte, error) {
 			if err2 == nil && n != len(index) {
 				err2 = io.ErrShortWrite
 			}
+			w.stats.compressed.Add(int64(n))
 			_ = w.err(err2)
 		}
 	}
@@ -1037,6 +1142,19 @@ func WriterSnappyCompat() WriterOption {
 	}
 }
 
+// WriterDict will use the supplied dictionary for all blocks in the stream.
+// The dictionary ID is written to the stream after the stream identifier,
+// so the stream can only be decoded by a Reader that has been
+// given the same dictionary using ReaderDicts.
+// Dictionaries cannot be combined with WriterSnappyCompat.
+// A nil dictionary will disable dictionary encoding.
+func WriterDict(dict *Dict) WriterOption {
+	return func(w *Writer) error {
+		w.dict = dict
+		return nil
+	}
+}
+
 // WriterFlushOnWrite will compress blocks on each call to the Write function.
 //
 // This is quite inefficient as blocks size will depend on the write size.
@@ -1050,6 +1168,24 @@ func WriterFlushOnWrite() WriterOption {
 	}
 }
 
+// WriterStreamChecksum will add a trailer to the stream containing
+// the uncompressed size and a 64 bit xxhash of all content.
+// When the stream is read by Reader, the trailer is verified,
+// and ErrCorrupt is returned on mismatch.
+// If the stream ends without a trailer, ErrTruncated is returned.
+// This will detect streams truncated at block boundaries,
+// which would otherwise decode without errors.
+//
+// The trailer is written when the Writer is closed, so streams that
+// are never closed will be reported as truncated.
+// Older decoders will ignore the trailer.
+func WriterStreamChecksum() WriterOption {
+	return func(w *Writer) error {
+		w.streamHash = xxhash.New()
+		return nil
+	}
+}
+
 // WriterCustomEncoder allows to override the encoder for blocks on the stream.
 // The function must compress 'src' into 'dst' and return the bytes used in dst as an integer.
 // Block size (initial varint) should not be added by the encoder.
@@ -6,6 +6,7 @@ package s2
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -711,3 +712,361 @@ func BenchmarkIndexFind(b *testing.B) {
 		})
 	}
 }
+
+func TestWriterStreamChecksum(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	src := make([]byte, 500<<10)
+	for i := range src {
+		src[i] = uint8(rng.Uint32()) & 3
+	}
+	opts := map[string][]WriterOption{
+		"default":  nil,
+		"c1":       {WriterConcurrency(1)},
+		"snappy":   {WriterSnappyCompat()},
+		"index":    {WriterAddIndex(), WriterPadding(1000)},
+		"flushall": {WriterFlushOnWrite()},
+	}
+	for name, opt := range opts {
+		t.Run(name, func(t *testing.T) {
+			for _, size := range []int{0, 1000, len(src)} {
+				var buf bytes.Buffer
+				w := NewWriter(&buf, append(opt, WriterStreamChecksum(), WriterBlockSize(64<<10))...)
+				if _, err := io.Copy(w, bytes.NewReader(src[:size])); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.EncodeBuffer(src[:size]); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				want := append(bytes.Clone(src[:size]), src[:size]...)
+				got, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatal("output mismatch")
+				}
+				var dst bytes.Buffer
+				if _, err := NewReader(bytes.NewReader(buf.Bytes())).DecodeConcurrent(&dst, 4); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(dst.Bytes(), want) {
+					t.Fatal("output mismatch")
+				}
+				// Skipping disables hash verification.
+				r := NewReader(bytes.NewReader(buf.Bytes()))
+				if err := r.Skip(int64(size)); err != nil {
+					t.Fatal(err)
+				}
+				got, err = io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want[size:]) {
+					t.Fatal("output mismatch")
+				}
+			}
+		})
+	}
+}
+
+func TestReaderStreamTruncated(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	src := make([]byte, 500<<10)
+	for i := range src {
+		src[i] = uint8(rng.Uint32()) & 3
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WriterStreamChecksum(), WriterBlockSize(64<<10))
+	w.Write(src)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	decode := func(b []byte) error {
+		_, err := io.ReadAll(NewReader(bytes.NewReader(b)))
+		_, err2 := NewReader(bytes.NewReader(b)).DecodeConcurrent(io.Discard, 2)
+		if err != err2 {
+			t.Fatalf("Read returned %v, DecodeConcurrent returned %v", err, err2)
+		}
+		return err
+	}
+
+	// Truncate at all chunk boundaries after the stream header.
+	trailer := len(stream) - chunkHeaderSize - streamTrailerSize
+	for off := len(magicChunk) + chunkHeaderSize; off < len(stream); {
+		if err := decode(stream[:off]); err != ErrTruncated {
+			t.Fatalf("truncated at %d: want ErrTruncated, got %v", off, err)
+		}
+		// Concatenated streams must also detect truncation.
+		if err := decode(append(bytes.Clone(stream[:off]), stream...)); err != ErrTruncated {
+			t.Fatalf("concatenated at %d: want ErrTruncated, got %v", off, err)
+		}
+		if off == trailer {
+			break
+		}
+		off += chunkHeaderSize + (int(stream[off+1]) | int(stream[off+2])<<8 | int(stream[off+3])<<16)
+	}
+	if err := decode(append(bytes.Clone(stream), stream...)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modified size and hash.
+	for _, i := range []int{trailer + chunkHeaderSize, len(stream) - 1} {
+		corrupt := bytes.Clone(stream)
+		corrupt[i]++
+		if err := decode(corrupt); err != ErrCorrupt {
+			t.Fatalf("want ErrCorrupt, got %v", err)
+		}
+	}
+	// Hash is not checked when ignoring CRC.
+	corrupt := bytes.Clone(stream)
+	corrupt[len(corrupt)-1]++
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(corrupt), ReaderIgnoreCRC())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterAdaptive(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	const blockSize = 64 << 10
+	// Alternate random and compressible blocks.
+	var src []byte
+	for i := 0; i < 8; i++ {
+		block := make([]byte, blockSize)
+		if i&1 == 0 {
+			rng.Read(block)
+		} else {
+			for j := range block {
+				block[j] = uint8(rng.Uint32()) & 3
+			}
+		}
+		src = append(src, block...)
+	}
+	for _, effort := range []float64{0, 0.5, 1} {
+		for _, c := range []int{1, 4} {
+			t.Run(fmt.Sprintf("effort-%v-c%d", effort, c), func(t *testing.T) {
+				var buf bytes.Buffer
+				w := NewWriter(&buf, WriterAdaptive(effort), WriterConcurrency(c), WriterBlockSize(blockSize))
+				if _, err := w.Write(src); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(NewReader(&buf))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, src) {
+					t.Fatal("output mismatch")
+				}
+				stats := w.AdaptiveStats()
+				t.Logf("%+v", stats)
+				if stats.Uncompressed != 4 || stats.UncompressedBytes != 4*blockSize {
+					t.Errorf("want 4 uncompressed blocks, got %d", stats.Uncompressed)
+				}
+				var want AdaptiveStats
+				switch effort {
+				case 0:
+					want = AdaptiveStats{Fast: 4, FastBytes: 4 * blockSize}
+				case 1:
+					want = AdaptiveStats{Best: 4, BestBytes: 4 * blockSize}
+				default:
+					want.Fast, want.Better, want.Best = stats.Fast, stats.Better, stats.Best
+					want.FastBytes, want.BetterBytes, want.BestBytes = stats.FastBytes, stats.BetterBytes, stats.BestBytes
+					if stats.Fast+stats.Better+stats.Best != 4 {
+						t.Errorf("want 4 compressed blocks, got %+v", stats)
+					}
+				}
+				want.Uncompressed, want.UncompressedBytes = stats.Uncompressed, stats.UncompressedBytes
+				if stats != want {
+					t.Errorf("want %+v, got %+v", want, stats)
+				}
+				w.Reset(io.Discard)
+				if stats := w.AdaptiveStats(); stats != (AdaptiveStats{}) {
+					t.Errorf("stats not reset: %+v", stats)
+				}
+			})
+		}
+	}
+	if err := NewWriter(io.Discard, WriterAdaptive(2)).Close(); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestWriterLineIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	// Lines of varying length, with offsets of line starts.
+	var src []byte
+	lineStarts := []int{0}
+	for len(src) < 3<<20 {
+		n := rng.Intn(200)
+		if rng.Intn(1000) == 0 {
+			n = rng.Intn(256 << 10)
+		}
+		for range n {
+			src = append(src, 'a'+uint8(rng.Intn(26)))
+		}
+		src = append(src, '\n')
+		lineStarts = append(lineStarts, len(src))
+	}
+	// No newline at the end.
+	src = append(src, "last"...)
+	for _, c := range []int{1, 4} {
+		t.Run(fmt.Sprint("c", c), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, WriterLineIndex(), WriterAddIndex(), WriterConcurrency(c), WriterBlockSize(64<<10))
+			if _, err := w.Write(src); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			rs, err := NewReader(bytes.NewReader(buf.Bytes())).ReadSeeker(true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			li, err := rs.LineIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if li.TotalLines != int64(len(lineStarts)-1) || li.TotalUncompressed != int64(len(src)) {
+				t.Fatalf("want %d lines, %d bytes, got %d, %d", len(lineStarts)-1, len(src), li.TotalLines, li.TotalUncompressed)
+			}
+			if len(li.info) < 3 {
+				t.Fatalf("want at least 3 index entries, got %d", len(li.info))
+			}
+			lines := []int{0, 1, len(lineStarts) - 2, len(lineStarts) - 1}
+			for range 50 {
+				lines = append(lines, rng.Intn(len(lineStarts)))
+			}
+			for _, line := range lines {
+				off, err := rs.SeekLine(int64(line))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if off != int64(lineStarts[line]) {
+					t.Fatalf("line %d: want offset %d, got %d", line, lineStarts[line], off)
+				}
+				got := make([]byte, min(100, len(src)-lineStarts[line]))
+				if _, err := io.ReadFull(rs, got); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, src[lineStarts[line]:][:len(got)]) {
+					t.Fatalf("line %d: output mismatch", line)
+				}
+			}
+			if _, err := rs.SeekLine(int64(len(lineStarts))); err != io.ErrUnexpectedEOF {
+				t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+			}
+		})
+	}
+
+	// Streams without a line index.
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WriterAddIndex())
+	if _, err := w.Write(src[:100000]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewReader(bytes.NewReader(buf.Bytes())).ReadSeeker(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rs.SeekLine(1); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("want ErrUnsupported, got %v", err)
+	}
+}
+
+func TestWriterStats(t *testing.T) {
+	rng := rand.New(rand.NewSource(0x1337))
+	const blockSize = 64 << 10
+	// Alternate random and compressible blocks, with a partial block at the end.
+	var src []byte
+	for i := 0; i < 8; i++ {
+		block := make([]byte, blockSize)
+		if i&1 == 0 {
+			rng.Read(block)
+		} else {
+			for j := range block {
+				block[j] = uint8(rng.Uint32()) & 3
+			}
+		}
+		src = append(src, block...)
+	}
+	src = append(src, make([]byte, 1000)...)
+	for _, c := range []int{1, 4} {
+		t.Run(fmt.Sprintf("c%d", c), func(t *testing.T) {
+			var buf bytes.Buffer
+			var progress []Stats
+			w := NewWriter(&buf, WriterConcurrency(c), WriterBlockSize(blockSize), WriterAddIndex(), WriterProgressCB(func(s Stats) {
+				progress = append(progress, s)
+			}))
+			if _, err := w.Write(src); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			stats := w.Stats()
+			want := Stats{Uncompressed: int64(len(src)), Compressed: int64(buf.Len()), Blocks: 9, UncompressedBlocks: 4}
+			if stats != want {
+				t.Errorf("want %+v, got %+v", want, stats)
+			}
+			if len(progress) != 9 {
+				t.Fatalf("want 9 progress callbacks, got %d", len(progress))
+			}
+			for i, s := range progress {
+				if s.Blocks != int64(i+1) || s.Uncompressed != min(int64(i+1)*blockSize, int64(len(src))) {
+					t.Errorf("progress %d: got %+v", i, s)
+				}
+			}
+
+			r := NewReader(bytes.NewReader(buf.Bytes()))
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, src) {
+				t.Fatal("output mismatch")
+			}
+			if stats := r.Stats(); stats != want {
+				t.Errorf("reader: want %+v, got %+v", want, stats)
+			}
+			r.Reset(bytes.NewReader(buf.Bytes()))
+			if _, err := r.DecodeConcurrent(io.Discard, c); err != nil {
+				t.Fatal(err)
+			}
+			if stats := r.Stats(); stats != want {
+				t.Errorf("concurrent reader: want %+v, got %+v", want, stats)
+			}
+
+			w.Reset(io.Discard)
+			if stats := w.Stats(); stats != (Stats{}) {
+				t.Errorf("stats not reset: %+v", stats)
+			}
+		})
+	}
+
+	// Corrupt the checksum of the first block.
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write(src)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	b[len(magicChunk)+chunkHeaderSize] ^= 0xff
+	r := NewReader(bytes.NewReader(b))
+	if _, err := io.ReadAll(r); err != ErrCRC {
+		t.Fatalf("want ErrCRC, got %v", err)
+	}
+	if stats := r.Stats(); stats.CRCFailures != 1 || stats.Blocks != 0 {
+		t.Errorf("want 1 CRC failure, got %+v", stats)
+	}
+}
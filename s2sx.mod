@@ -1,3 +0,0 @@
-module github.com/klauspost/compress
-
-go 1.22
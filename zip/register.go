@@ -73,12 +73,25 @@ func newFlateReader(r io.Reader) io.ReadCloser {
 	} else {
 		fr = flate.NewReader(r)
 	}
-	return &pooledFlateReader{fr: fr}
+	return &pooledFlateReader{fr: fr, pool: &flateReaderPool}
+}
+
+var flate64ReaderPool sync.Pool
+
+func newFlate64Reader(r io.Reader) io.ReadCloser {
+	fr, ok := flate64ReaderPool.Get().(io.ReadCloser)
+	if ok {
+		fr.(flate.Resetter).Reset(r, nil)
+	} else {
+		fr = flate.NewReader64(r)
+	}
+	return &pooledFlateReader{fr: fr, pool: &flate64ReaderPool}
 }
 
 type pooledFlateReader struct {
-	mu sync.Mutex // guards Close and Read
-	fr io.ReadCloser
+	mu   sync.Mutex // guards Close and Read
+	fr   io.ReadCloser
+	pool *sync.Pool // pool fr is returned to
 }
 
 func (r *pooledFlateReader) Read(p []byte) (n int, err error) {
@@ -96,7 +109,7 @@ func (r *pooledFlateReader) Close() error {
 	var err error
 	if r.fr != nil {
 		err = r.fr.Close()
-		flateReaderPool.Put(r.fr)
+		r.pool.Put(r.fr)
 		r.fr = nil
 	}
 	return err
@@ -113,10 +126,11 @@ func init() {
 
 	decompressors.Store(Store, Decompressor(io.NopCloser))
 	decompressors.Store(Deflate, Decompressor(newFlateReader))
+	decompressors.Store(Deflate64, Decompressor(newFlate64Reader))
 }
 
 // RegisterDecompressor allows custom decompressors for a specified method ID.
-// The common methods [Store] and [Deflate] are built in.
+// The common methods [Store], [Deflate] and [Deflate64] are built in.
 func RegisterDecompressor(method uint16, dcomp Decompressor) {
 	if _, dup := decompressors.LoadOrStore(method, dcomp); dup {
 		panic("decompressor already registered")
@@ -8,9 +8,11 @@ package zip
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
+	"hash/crc32"
 	"io"
 	"runtime"
 	"sort"
@@ -815,3 +817,49 @@ func (zeros) Read(p []byte) (int, error) {
 	}
 	return len(p), nil
 }
+
+func TestDeflate64(t *testing.T) {
+	// Fixed Huffman Deflate64 stream with a 65538 byte match (length code 285)
+	// and a match at distance 40005 (distance code 30).
+	stream, _ := hex.DecodeString("4b494dcb492c49353319fdff67c01f110700")
+	want := strings.Repeat("deflate64", 7284)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	fw, err := w.CreateRaw(&FileHeader{
+		Name:               "deflate64.txt",
+		Method:             Deflate64,
+		CRC32:              crc32.ChecksumIEEE([]byte(want)),
+		CompressedSize64:   uint64(len(stream)),
+		UncompressedSize64: uint64(len(want)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(stream); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read twice to exercise the reader pool.
+	for range 2 {
+		rc, err := r.File[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+		if string(got) != want {
+			t.Fatalf("got %d bytes, want %d", len(got), len(want))
+		}
+	}
+}
@@ -23,6 +23,10 @@ const (
 	DefaultCompression  = flate.DefaultCompression
 	ConstantCompression = flate.ConstantCompression // Deprecated: Use HuffmanOnly.
 	HuffmanOnly         = flate.HuffmanOnly
+
+	// BestCompressionZopfli is the best, but very slow, compression level.
+	// See flate.BestCompressionZopfli.
+	BestCompressionZopfli = flate.BestCompressionZopfli
 )
 
 // A Writer takes data written to it and writes the compressed
@@ -52,7 +56,7 @@ func NewWriter(w io.Writer) *Writer {
 // of assuming DefaultCompression.
 //
 // The compression level can be DefaultCompression, NoCompression, HuffmanOnly
-// or any integer value between BestSpeed and BestCompression inclusive.
+// or any integer value between BestSpeed and BestCompressionZopfli inclusive.
 // The error returned will be nil if the level is valid.
 func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
 	return NewWriterLevelDict(w, level, nil)
@@ -64,7 +68,7 @@ func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
 // The dictionary may be nil. If not, its contents should not be modified until
 // the Writer is closed.
 func NewWriterLevelDict(w io.Writer, level int, dict []byte) (*Writer, error) {
-	if level < HuffmanOnly || level > BestCompression {
+	if level < HuffmanOnly || level > BestCompressionZopfli {
 		return nil, fmt.Errorf("zlib: invalid compression level: %d", level)
 	}
 	return &Writer{
@@ -109,7 +113,7 @@ func (z *Writer) writeHeader() (err error) {
 		z.scratch[1] = 1 << 6
 	case 6, -1:
 		z.scratch[1] = 2 << 6
-	case 7, 8, 9:
+	case 7, 8, 9, 10, 11, 12:
 		z.scratch[1] = 3 << 6
 	default:
 		panic("unreachable")
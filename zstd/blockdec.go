@@ -12,7 +12,7 @@ import (
 	"sync"
 
 	"github.com/klauspost/compress/huff0"
-	"github.com/klauspost/compress/zstd/internal/xxhash"
+	"github.com/klauspost/compress/internal/xxhash"
 )
 
 type blockType uint8
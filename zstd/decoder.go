@@ -10,7 +10,8 @@ import (
 	"io"
 	"sync"
 
-	"github.com/klauspost/compress/zstd/internal/xxhash"
+	"github.com/klauspost/compress/internal/xxhash"
+	"github.com/klauspost/compress/readahead"
 )
 
 // Decoder provides decoding of zstandard streams.
@@ -41,6 +42,9 @@ type Decoder struct {
 
 	// streamWg is the waitgroup for all streams
 	streamWg sync.WaitGroup
+
+	// readAhead is the read-ahead of the current input, if any.
+	readAhead io.Closer
 }
 
 // decoderState is used for maintaining state when the decoder
@@ -169,6 +173,7 @@ func (d *Decoder) Reset(r io.Reader) error {
 	}
 
 	d.drainOutput()
+	d.closeReadAhead()
 
 	d.syncStream.br.r = nil
 	if r == nil {
@@ -220,6 +225,16 @@ func (d *Decoder) Reset(r io.Reader) error {
 		d.frame = newFrameDec(d.o)
 	}
 
+	if d.o.readAhead > 0 {
+		size := min(d.o.readAhead, 1<<20)
+		ra, err := readahead.NewReaderSize(r, max((d.o.readAhead+size-1)/size, 2), size)
+		if err != nil {
+			return err
+		}
+		d.readAhead = ra
+		r = ra
+	}
+
 	if d.o.concurrent == 1 {
 		return d.startSyncDecoder(r)
 	}
@@ -248,6 +263,16 @@ func (d *Decoder) ResetWithOptions(r io.Reader, opts ...DOption) error {
 	return d.Reset(r)
 }
 
+// closeReadAhead stops read-ahead on the current input, if any.
+func (d *Decoder) closeReadAhead() {
+	if d.readAhead != nil {
+		// Ensure the stream decoder no longer reads from the input.
+		d.streamWg.Wait()
+		d.readAhead.Close()
+		d.readAhead = nil
+	}
+}
+
 // drainOutput will drain the output until errEndOfStream is sent.
 func (d *Decoder) drainOutput() {
 	if d.current.cancel != nil {
@@ -588,6 +613,7 @@ func (d *Decoder) Close() {
 		d.streamWg.Wait()
 		d.current.cancel = nil
 	}
+	d.closeReadAhead()
 	if d.decoders != nil {
 		close(d.decoders)
 		for dec := range d.decoders {
@@ -24,6 +24,7 @@ type decoderOptions struct {
 	ignoreChecksum  bool
 	limitToCap      bool
 	decodeBufsBelow int
+	readAhead       int
 	resetOpt        bool
 }
 
@@ -188,6 +189,25 @@ func WithDecodeBuffersBelow(size int) DOption {
 	}
 }
 
+// WithDecoderReadAhead will read up to n bytes ahead from the input asynchronously
+// when decoding streams.
+// This can improve throughput when the input is slow, for example
+// files on slow disks or network streams.
+// The input is read in buffers of up to 1MB, and at least 2 buffers are used.
+// Inputs that are fully decoded, see WithDecodeBuffersBelow, are not affected.
+// The read-ahead is stopped when the decoder is Reset or Closed.
+// Default is 0, meaning no read-ahead.
+// Can be changed with ResetWithOptions.
+func WithDecoderReadAhead(n int) DOption {
+	return func(o *decoderOptions) error {
+		if n < 0 {
+			return errors.New("read-ahead must be >= 0")
+		}
+		o.readAhead = n
+		return nil
+	}
+}
+
 // IgnoreChecksum allows to forcibly ignore checksum checking.
 // Can be changed with ResetWithOptions.
 func IgnoreChecksum(b bool) DOption {
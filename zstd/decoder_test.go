@@ -22,12 +22,13 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	// "github.com/DataDog/zstd"
 	// zstd "github.com/valyala/gozstd"
 
-	"github.com/klauspost/compress/zstd/internal/xxhash"
+	"github.com/klauspost/compress/internal/xxhash"
 )
 
 func TestNewReaderMismatch(t *testing.T) {
@@ -2339,3 +2340,57 @@ func TestDecoderDictDeleteMultiple(t *testing.T) {
 		t.Error("dict 200 should still exist")
 	}
 }
+
+func TestDecoderReadAhead(t *testing.T) {
+	var want []byte
+	for i := 0; len(want) < 5<<20; i++ {
+		want = fmt.Appendf(want, "Item %019d\n", i)
+	}
+	enc, err := NewWriter(nil, WithEncoderLevel(SpeedFastest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := enc.EncodeAll(want, nil)
+	for _, c := range []int{1, 4} {
+		t.Run(fmt.Sprint("c", c), func(t *testing.T) {
+			dec, err := NewReader(nil, WithDecoderConcurrency(c), WithDecoderReadAhead(256<<10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dec.Close()
+			for i := 0; i < 3; i++ {
+				// Half reads, so input isn't fully decoded.
+				if err := dec.Reset(iotest.HalfReader(bytes.NewReader(compressed))); err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 {
+					// Abandon the stream.
+					if _, err := io.ReadFull(dec, make([]byte, 1000)); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				got, err := io.ReadAll(dec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatal("output mismatch")
+				}
+			}
+			// Input errors must be forwarded.
+			if err := dec.Reset(iotest.TimeoutReader(bytes.NewReader(compressed))); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.ReadAll(dec); !errors.Is(err, iotest.ErrTimeout) {
+				t.Fatalf("want %v, got %v", iotest.ErrTimeout, err)
+			}
+			if err := dec.ResetWithOptions(bytes.NewReader(compressed), WithDecoderReadAhead(0)); err != nil {
+				t.Fatal(err)
+			}
+			if dec.readAhead != nil {
+				t.Fatal("read-ahead not stopped")
+			}
+		})
+	}
+}
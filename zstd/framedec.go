@@ -10,7 +10,7 @@ import (
 	"errors"
 	"io"
 
-	"github.com/klauspost/compress/zstd/internal/xxhash"
+	"github.com/klauspost/compress/internal/xxhash"
 )
 
 type frameDec struct {